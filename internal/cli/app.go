@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"path/filepath"
 	"time"
 
@@ -22,13 +23,40 @@ type App struct {
 	Out   io.Writer
 	Err   io.Writer
 	Now   func() time.Time
+	Log   *slog.Logger // Debug output for --verbose, written to Err. Set by rootPreRun.
 
 	// Bound to --config in newRootCmd.
 	// Empty means the default location.
 	configOverride string
 
+	// Bound to --profile in newRootCmd.
+	// Empty falls back to TRANSIT_PROFILE, then to no profile.
+	profile string
+
+	// Bound to --location in newRootCmd.
+	// Empty means core.location.
+	locationOverride string
+
 	// Bound to --verbose in newRootCmd.
 	verbose bool
+
+	// Bound to --log-format in newRootCmd. Either text or json.
+	logFormat string
+
+	// Bound to --no-cache in newRootCmd.
+	noCache bool
+
+	// Bound to --record and --replay in newRootCmd.
+	// Empty means the run talks to the upstream APIs as usual.
+	recordDir string
+	replayDir string
+
+	// Set up by rootPreRun from recordDir or replayDir. At most one is non-nil.
+	recorder *provider.Recorder
+	replayer *provider.Replayer
+
+	// When recordPredictions last pruned old predictions. Zero until it has.
+	predictionsPruned time.Time
 }
 
 // run executes the command tree against args and returns a process exit code.
@@ -42,9 +70,70 @@ func (a *App) run(ctx context.Context, args []string) int {
 		a.errorf("%s", err)
 	}
 
+	// Saved even when the command failed, a failing run is the one worth reproducing.
+	if a.recorder != nil {
+		if saveErr := a.recorder.Cassette().Save(a.recordDir); saveErr != nil {
+			a.errorf("save cassette: %s", saveErr)
+		}
+	}
+
 	return exitCode(err)
 }
 
+// rootPreRun is the root's hook, run before every command's own.
+func (a *App) rootPreRun(_ *cobra.Command, _ []string) error {
+	if err := a.setupLogger(); err != nil {
+		return err
+	}
+
+	return a.setupCassette()
+}
+
+// setupLogger builds Log from --verbose and --log-format. Without --verbose
+// nothing is logged, so Log can always be used.
+func (a *App) setupLogger() error {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler
+	switch a.logFormat {
+	case "", "text":
+		handler = slog.NewTextHandler(a.Err, opts)
+	case "json":
+		handler = slog.NewJSONHandler(a.Err, opts)
+	default:
+		return fmt.Errorf("%w: --log-format must be text or json, not %q", errUsage, a.logFormat)
+	}
+
+	if !a.verbose {
+		handler = slog.DiscardHandler
+	}
+
+	a.Log = slog.New(handler)
+	return nil
+}
+
+// setupCassette handles --record, which captures the run's upstream traffic, and
+// --replay, which answers it from a previous recording with Now frozen at the
+// moment that recording was made.
+func (a *App) setupCassette() error {
+	switch {
+	case a.recordDir != "" && a.replayDir != "":
+		return fmt.Errorf("%w: --record and --replay can't be used together", errUsage)
+	case a.recordDir != "":
+		a.recorder = provider.NewRecorder(a.Now())
+	case a.replayDir != "":
+		cassette, err := provider.LoadCassette(a.replayDir)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+
+		a.replayer = provider.NewReplayer(cassette)
+		a.Now = func() time.Time { return cassette.Now }
+	}
+
+	return nil
+}
+
 // close releases anything a hook opened. Commands that never initialize
 // the store leave it nil, so it has to handle that.
 func (a *App) close() error {
@@ -57,7 +146,7 @@ func (a *App) close() error {
 
 // configSetupPreRun is the hook for commands that only read the config file.
 func (a *App) configSetupPreRun(_ *cobra.Command, _ []string) error {
-	cfg, err := config.Load(a.configOverride)
+	cfg, err := config.Load(a.configOverride, a.profile)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
@@ -72,7 +161,7 @@ func (a *App) dbSetupPreRun(ctx context.Context) error {
 		return fmt.Errorf("locate config: %w", err)
 	}
 
-	db, err := store.New(filepath.Join(path, "transit.db"))
+	db, err := store.New(filepath.Join(path, "transit.db"), store.WithLogger(a.Log))
 	if err != nil {
 		return fmt.Errorf("establish store: %w", err)
 	}
@@ -97,22 +186,83 @@ func (a *App) defaultPreRun(cmd *cobra.Command, args []string) error {
 	return a.dbSetupPreRun(cmd.Context())
 }
 
-// provider returns the configured location's provider.
-func (a *App) provider() (transit.Provider, error) {
-	switch transit.LocationSlug(a.Cfg.Core.Location) {
+// location returns the location this invocation runs against. --location wins
+// over core.location so a single run can target another initialized location.
+func (a *App) location() transit.LocationSlug {
+	if a.locationOverride != "" {
+		return transit.LocationSlug(a.locationOverride)
+	}
+
+	return transit.LocationSlug(a.Cfg.Core.Location)
+}
+
+// provider returns the provider for the location this invocation runs against.
+func (a *App) provider(ctx context.Context) (transit.Provider, error) {
+	return a.providerFor(ctx, a.location())
+}
+
+// providerFor returns the provider that serves a location. Resolving the API key
+// can read the keyring or run a command, which is why it takes a ctx.
+func (a *App) providerFor(ctx context.Context, location transit.LocationSlug) (transit.Provider, error) {
+	switch location {
 	case transit.DMVSlug:
-		client, err := provider.NewDMV(a.Cfg.DMV.APIKey, a.Now)
+		apiKey, err := a.apiKey(ctx, location)
+		if err != nil {
+			return nil, fmt.Errorf("dmv api key: %w", err)
+		}
+
+		client, err := provider.NewDMV(apiKey, a.Store, a.providerOptions()...)
 		if err != nil {
 			return nil, fmt.Errorf("dmv client: %w", err)
 		}
 		return client, nil
 	case transit.SFSlug:
-		client, err := provider.NewSF(a.Cfg.SF.APIKey, a.Store)
+		apiKey, err := a.apiKey(ctx, location)
+		if err != nil {
+			return nil, fmt.Errorf("sf api key: %w", err)
+		}
+
+		opts := append(a.providerOptions(), provider.WithConcurrency(a.Cfg.SF.Concurrency))
+
+		client, err := provider.NewSF(apiKey, a.Store, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("sf client: %w", err)
 		}
 		return client, nil
 	default:
-		return nil, fmt.Errorf("%w: unsupported location %q", config.ErrInvalid, a.Cfg.Core.Location)
+		return nil, fmt.Errorf("%w: unsupported location %q", config.ErrInvalid, location)
+	}
+}
+
+// apiKey resolves a location's API key. A replayed run never reaches the upstream,
+// so it works without one, which is what lets a cassette be replayed by someone else.
+func (a *App) apiKey(ctx context.Context, location transit.LocationSlug) (string, error) {
+	if a.replayer != nil && !a.Cfg.HasAPIKey(string(location)) {
+		return "replay", nil
+	}
+
+	return a.Cfg.APIKey(ctx, string(location))
+}
+
+// providerOptions puts the response cache in front of a provider, unless --no-cache
+// asked for fresh data. A config dir that can't be found just means no cache.
+// Recording and replaying skip the cache, so every exchange is on the cassette.
+func (a *App) providerOptions() []provider.Option {
+	opts := []provider.Option{provider.WithLogger(a.Log), provider.WithLanguage(a.Cfg.Core.Language), provider.WithClock(a.Now)}
+
+	switch {
+	case a.recorder != nil:
+		return append(opts, provider.WithTransport(a.recorder))
+	case a.replayer != nil:
+		return append(opts, provider.WithTransport(a.replayer))
+	case a.noCache:
+		return opts
 	}
+
+	path, err := config.GetConfigDir()
+	if err != nil {
+		return opts
+	}
+
+	return append(opts, provider.WithCache(filepath.Join(path, "cache")))
 }
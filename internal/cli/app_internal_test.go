@@ -2,10 +2,16 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/provider"
+	"github.com/ismailshak/transit/internal/store"
+	"github.com/ismailshak/transit/internal/transit"
 )
 
 type testApp struct {
@@ -22,7 +28,7 @@ func newTestApp(t *testing.T) *testApp {
 	out := &bytes.Buffer{}
 	errOut := &bytes.Buffer{}
 	app := &testApp{
-		App:  &App{Out: out, Err: errOut, Now: time.Now},
+		App:  &App{Out: out, Err: errOut, Now: time.Now, Cfg: &config.Config{}},
 		t:    t,
 		out:  out,
 		err:  errOut,
@@ -38,6 +44,22 @@ func newTestApp(t *testing.T) *testApp {
 	return app
 }
 
+// openStore gives the app a migrated store of its own, for tests that call a
+// command's internals instead of running it.
+func (a *testApp) openStore() {
+	a.t.Helper()
+
+	db, err := store.New(filepath.Join(a.t.TempDir(), "transit.db"))
+	if err != nil {
+		a.t.Fatalf("expected no error but got %v", err)
+	}
+
+	a.Store = db
+	if err := db.SyncMigrations(a.t.Context()); err != nil {
+		a.t.Fatalf("expected no error but got %v", err)
+	}
+}
+
 func (a *testApp) run(args ...string) int {
 	// Context from t so a command that hangs fails the run instead of stalling
 	return a.App.run(a.t.Context(), args)
@@ -61,6 +83,9 @@ func TestAppRun(t *testing.T) {
 	t.Run("only the config hook runs for a config-only command", func(t *testing.T) {
 		app := newTestApp(t)
 
+		// newTestApp starts with a config, drop it to see the hook load one.
+		app.Cfg = nil
+
 		code := app.run("config", "path")
 		if code != 0 {
 			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.out)
@@ -83,6 +108,9 @@ func TestAppRun(t *testing.T) {
 	t.Run("both hooks run when the command needs the store", func(t *testing.T) {
 		app := newTestApp(t)
 
+		// newTestApp starts with a config, drop it to see the hook load one.
+		app.Cfg = nil
+
 		code := app.run("config", "set", "core.location", "dmv", "--verbose")
 		if code != 0 {
 			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.out)
@@ -101,6 +129,83 @@ func TestAppRun(t *testing.T) {
 		}
 	})
 
+	t.Run("--location is bound to the override", func(t *testing.T) {
+		app := newTestApp(t)
+
+		if code := app.run("config", "path", "--location", "sf"); code != 0 {
+			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.out)
+		}
+
+		if app.location() != transit.SFSlug {
+			t.Errorf("expected %q but got %q, --location is not bound to the field", transit.SFSlug, app.location())
+		}
+	})
+
+	t.Run("--verbose --log-format json logs migrations as JSON on Err", func(t *testing.T) {
+		app := newTestApp(t)
+
+		if code := app.run("config", "set", "core.location", "dmv", "--verbose", "--log-format", "json"); code != 0 {
+			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.err)
+		}
+
+		line, _, _ := strings.Cut(app.err.String(), "\n")
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected a JSON log line on Err but got %q", line)
+		}
+
+		if !strings.Contains(app.err.String(), `"msg":"migration applied"`) {
+			t.Errorf("expected the migrations on Err but got %q", app.err)
+		}
+	})
+
+	t.Run("an unknown --log-format is a usage error", func(t *testing.T) {
+		app := newTestApp(t)
+
+		if code := app.run("config", "path", "--log-format", "xml"); code != 2 {
+			t.Errorf("expected exit code 2 but got %d", code)
+		}
+	})
+
+	t.Run("--replay freezes Now at the recording", func(t *testing.T) {
+		app := newTestApp(t)
+
+		dir := t.TempDir()
+		recorded := time.Date(2026, 8, 9, 17, 30, 0, 0, time.UTC)
+		if err := (&provider.Cassette{Now: recorded}).Save(dir); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if code := app.run("config", "path", "--replay", dir); code != 0 {
+			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.err)
+		}
+
+		if !app.Now().Equal(recorded) {
+			t.Errorf("expected Now to be %s but got %s", recorded, app.Now())
+		}
+	})
+
+	t.Run("--record saves a cassette once the command is done", func(t *testing.T) {
+		app := newTestApp(t)
+
+		dir := t.TempDir()
+		if code := app.run("config", "path", "--record", dir); code != 0 {
+			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.err)
+		}
+
+		if _, err := provider.LoadCassette(dir); err != nil {
+			t.Errorf("expected a cassette but got %v", err)
+		}
+	})
+
+	t.Run("--record and --replay are a usage error together", func(t *testing.T) {
+		app := newTestApp(t)
+
+		if code := app.run("config", "path", "--record", t.TempDir(), "--replay", t.TempDir()); code != 2 {
+			t.Errorf("expected exit code 2 but got %d", code)
+		}
+	})
+
 	t.Run("a failing command writes its diagnostic to Err, not Out", func(t *testing.T) {
 		app := newTestApp(t)
 
@@ -156,3 +261,45 @@ func TestAppClose(t *testing.T) {
 		}
 	})
 }
+
+func TestAppLocation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		configured string
+		override   string
+		want       transit.LocationSlug
+	}{
+		"the configured location by default": {
+			configured: "dmv",
+			want:       transit.DMVSlug,
+		},
+		"the flag wins over the config": {
+			configured: "dmv",
+			override:   "sf",
+			want:       transit.SFSlug,
+		},
+		"the flag without a configured location": {
+			override: "sf",
+			want:     transit.SFSlug,
+		},
+		"nothing configured": {
+			want: "",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			app := &App{
+				Cfg:              &config.Config{Core: config.CoreConfig{Location: tc.configured}},
+				locationOverride: tc.override,
+			}
+
+			if got := app.location(); got != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
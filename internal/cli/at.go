@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/ismailshak/transit/internal/config"
@@ -16,12 +18,22 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	// prunePredictionsEvery spaces out pruning, which reads the whole table, so a
+	// long --watch prunes now and then rather than on every refresh.
+	prunePredictionsEvery = time.Hour
+
+	// maxTargetFetches caps how many targets are fetched at once.
+	maxTargetFetches = 4
+)
+
 func (a *App) newAtCmd() *cobra.Command {
 	var watchFlag bool
+	var outputFlag string
 
 	atCmd := &cobra.Command{
 		Use:     "at <args>",
-		Example: "  transit at courth (matches \"Court House\")\n  transit at metro (matches \"Metro Center\")",
+		Example: "  transit at courth (matches \"Court House\")\n  transit at metro (matches \"Metro Center\")\n  transit at metro --output ics > trains.ics",
 		Short:   "Display upcoming train arrival information at chosen station(s)",
 		Long: `
 Display upcoming train information for one or more stations.
@@ -29,55 +41,65 @@ Display upcoming train information for one or more stations.
 Arguments are considered valid if it can be used to narrow
 the official station names to just 1. If something's too generic,
 try being more specific by adding more characters.
+
+Every initialized location is searched unless --location picks one.
+
+--output ics writes the departures as an iCalendar file instead, one event
+per train, to import into a calendar.
 	`,
 		Args:    usageArgs(cobra.MinimumNArgs(1)),
 		PreRunE: a.defaultPreRun,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p, err := a.provider()
-			if err != nil {
-				return err
+			ctx := cmd.Context()
+
+			if outputFlag != "text" && outputFlag != "ics" {
+				return fmt.Errorf("%w: --output must be text or ics, not %q", errUsage, outputFlag)
 			}
 
-			ctx := cmd.Context()
+			if watchFlag && outputFlag == "ics" {
+				return fmt.Errorf("%w: --watch only prints text", errUsage)
+			}
+
+			if outputFlag == "ics" {
+				return a.executeAtICS(ctx, args)
+			}
 
 			if watchFlag {
-				return a.watchAt(ctx, p, args)
+				return a.watchAt(ctx, args)
 			}
 
-			return a.executeAt(ctx, p, args)
+			return a.executeAt(ctx, args)
 		},
 	}
 
 	atCmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "live update arrival information")
+	atCmd.Flags().StringVarP(&outputFlag, "output", "o", "text", "output format, text or ics")
 
 	return atCmd
 }
 
-func (a *App) executeAt(ctx context.Context, p transit.Provider, args []string) error {
-	targets, err := a.resolveStops(ctx, p, args)
+func (a *App) executeAt(ctx context.Context, args []string) error {
+	targets, err := a.resolveStops(ctx, args)
 	if err != nil {
 		return err
 	}
 
-	return a.renderDepartures(ctx, p, targets)
+	return a.renderDepartures(ctx, targets)
 }
 
-func (a *App) watchAt(ctx context.Context, p transit.Provider, args []string) error {
+func (a *App) watchAt(ctx context.Context, args []string) error {
 	interval, err := watchInterval(a.Cfg.Core.WatchInterval)
 	if err != nil {
 		return err
 	}
 
-	targets, err := a.resolveStops(ctx, p, args)
+	targets, err := a.resolveStops(ctx, args)
 	if err != nil {
 		return err
 	}
 
 	message := tui.Bold(fmt.Sprintf("Refreshing station arrivals every %v. Press Ctrl+C to quit.", interval))
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
 	buffer := tui.NewBuffer()
 	buffer.StartAlternateBuffer()
 	defer buffer.StopAlternateBuffer()
@@ -86,7 +108,8 @@ func (a *App) watchAt(ctx context.Context, p transit.Provider, args []string) er
 		buffer.RefreshScreen()
 		_, _ = fmt.Fprintln(a.Out, message)
 
-		if err := a.renderDepartures(ctx, p, targets); err != nil {
+		wait := interval
+		if err := a.renderDepartures(ctx, targets); err != nil {
 			if endsWatch(err) {
 				return err
 			}
@@ -94,30 +117,59 @@ func (a *App) watchAt(ctx context.Context, p transit.Provider, args []string) er
 			if !errors.Is(err, transit.ErrNoDepartures) {
 				a.errorf("%s", err)
 			}
+
+			wait = nextWatch(err, interval)
 		}
 
+		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return ctx.Err()
-		case <-ticker.C:
+		case <-timer.C:
 		}
 	}
 }
 
-// target is one argument resolved to the stop codes a provider wants.
+// nextWatch is how long to wait before the next refresh. A rate limit pushes it
+// out until a request would be allowed again, rather than spending the next tick on
+// another refusal.
+func nextWatch(err error, interval time.Duration) time.Duration {
+	if rateErr, ok := errors.AsType[*provider.RateLimitError](err); ok {
+		return max(interval, rateErr.RetryAfter)
+	}
+
+	return interval
+}
+
+// target is one argument resolved to the stop codes a provider wants. An argument
+// that matches stops in several locations becomes one target per location.
 type target struct {
-	arg  string
-	refs []transit.StopRef
+	arg      string
+	location transit.LocationSlug
+	provider transit.Provider
+	refs     []transit.StopRef
+	stops    []string // IDs of the seeded stops the refs came from.
 }
 
-func (a *App) resolveStops(ctx context.Context, p transit.Provider, args []string) ([]target, error) {
-	slug := transit.LocationSlug(a.Cfg.Core.Location)
+func (a *App) resolveStops(ctx context.Context, args []string) ([]target, error) {
+	locations, err := a.searchLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make(map[transit.LocationSlug]transit.Provider, len(locations))
 
 	var targets []target
 	for _, arg := range args {
-		stops, err := a.Store.MatchStops(ctx, slug, arg)
-		if err != nil {
-			return nil, fmt.Errorf("resolve %q: %w", arg, err)
+		var stops []transit.Stop
+		for _, slug := range locations {
+			matched, err := a.Store.MatchStops(ctx, slug, arg)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %q: %w", arg, err)
+			}
+
+			stops = append(stops, matched...)
 		}
 
 		// TODO: report these to the caller so it can warn on nothing matching
@@ -129,12 +181,29 @@ func (a *App) resolveStops(ctx context.Context, p transit.Provider, args []strin
 			continue
 		}
 
-		var refs []transit.StopRef
-		for _, s := range stops {
-			refs = append(refs, p.StopRefs(s)...)
-		}
+		for _, slug := range locations {
+			var refs []transit.StopRef
+			var stopIDs []string
+			var p transit.Provider
+			for _, s := range stops {
+				if s.Location != slug {
+					continue
+				}
+
+				if p == nil {
+					if p, err = a.cachedProvider(ctx, providers, slug); err != nil {
+						return nil, err
+					}
+				}
+
+				refs = append(refs, p.StopRefs(s)...)
+				stopIDs = append(stopIDs, s.StopID)
+			}
 
-		targets = append(targets, target{arg: arg, refs: refs})
+			if len(refs) > 0 {
+				targets = append(targets, target{arg: arg, location: slug, provider: p, refs: refs, stops: stopIDs})
+			}
+		}
 	}
 
 	if len(targets) == 0 {
@@ -144,35 +213,234 @@ func (a *App) resolveStops(ctx context.Context, p transit.Provider, args []strin
 	return targets, nil
 }
 
-func (a *App) renderDepartures(ctx context.Context, p transit.Provider, targets []target) error {
-	var rendered int
+// searchLocations returns the locations `at` looks for stations in. --location
+// narrows it to one, otherwise it's every seeded location with the configured one first.
+func (a *App) searchLocations(ctx context.Context) ([]transit.LocationSlug, error) {
+	if a.locationOverride != "" {
+		return []transit.LocationSlug{a.location()}, nil
+	}
+
+	seeded, err := a.Store.SeededLocations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("look up seeded locations: %w", err)
+	}
+
+	preferred := a.location()
+	locations := make([]transit.LocationSlug, 0, len(seeded)+1)
+	if preferred != "" {
+		locations = append(locations, preferred)
+	}
+
+	for _, slug := range seeded {
+		if slug != preferred {
+			locations = append(locations, slug)
+		}
+	}
+
+	return locations, nil
+}
+
+// cachedProvider builds a location's provider once per resolve.
+func (a *App) cachedProvider(ctx context.Context, providers map[transit.LocationSlug]transit.Provider, location transit.LocationSlug) (transit.Provider, error) {
+	if p, ok := providers[location]; ok {
+		return p, nil
+	}
+
+	p, err := a.providerFor(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+
+	providers[location] = p
+	return p, nil
+}
+
+// renderDepartures prints a screen per target. Targets are fetched at the same time,
+// so the wait is as long as the slowest one, and a target that failed is reported in
+// its place without hiding the others. Alerts are only fetched from the providers
+// with a screen to put them under, and are kept in the history like `incidents`
+// keeps them. With nothing to show, the failures are returned instead of printed.
+func (a *App) renderDepartures(ctx context.Context, targets []target) error {
+	results := fetchTargets(ctx, targets)
+
+	var showing []transit.Provider
+	for n, r := range results {
+		if r.err == nil && len(r.set.Departures) > 0 && !slices.Contains(showing, targets[n].provider) {
+			showing = append(showing, targets[n].provider)
+		}
+	}
+
+	anyRendered := len(showing) > 0
+	providerAlerts := fetchAlerts(ctx, showing)
+	// Every target of a provider shares its alerts, so they're recorded once.
+	recorded := make(map[transit.Provider]bool, len(providerAlerts))
 	for _, t := range targets {
-		departureSet, err := p.Departures(ctx, t.refs)
-		// Let this error skip so other targets can attempt to fetch for data.
-		if errors.Is(err, transit.ErrNoDepartures) {
+		if alerts, ok := providerAlerts[t.provider]; ok && !recorded[t.provider] {
+			a.recordAlerts(ctx, t.location, alerts)
+			recorded[t.provider] = true
+		}
+	}
+
+	var errs []error
+	for n, t := range targets {
+		r := results[n]
+
+		// Let this error skip so other targets can show their data.
+		if errors.Is(r.err, transit.ErrNoDepartures) {
 			continue
 		}
 
-		if err != nil {
-			return fmt.Errorf("fetch departures for %q: %w", t.arg, err)
+		if r.err != nil {
+			err := fmt.Errorf("fetch departures for %q: %w", t.arg, r.err)
+			if anyRendered {
+				tui.PrintFailedScreen(a.Out, t.arg, err)
+			} else {
+				errs = append(errs, err)
+			}
+
+			continue
 		}
 
-		if len(departureSet.Departures) > 0 {
-			destinationLookup, sortedDestinations := groupByDestination(departureSet.Departures)
-			tui.PrintArrivalScreen(&destinationLookup, sortedDestinations, a.Now())
-			rendered++
+		a.recordPredictions(ctx, t, r.set.Departures)
+
+		if len(r.set.Departures) > 0 {
+			alerts := relevantAlerts(providerAlerts[t.provider], t, r.set.Departures, a.Now())
+			destinationLookup, sortedDestinations := groupByDestination(r.set.Departures)
+			tui.PrintArrivalScreen(a.Out, &destinationLookup, sortedDestinations, accessibilityOutages(alerts), r.set.CachedAge(a.Now()), a.Now())
+			tui.PrintAlerts(a.Out, alerts, false, a.Now())
 		}
 
-		for _, s := range departureSet.Degraded() {
+		for _, s := range r.set.Degraded() {
 			a.warnf("%v", s.Err)
 		}
 	}
 
-	if rendered == 0 {
-		return transit.ErrNoDepartures
+	if anyRendered {
+		return nil
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return transit.ErrNoDepartures
+}
+
+// recordPredictions logs departures for `transit stats` when stats.record_predictions
+// asks for it, and drops what was logged more than [config.KeepPredictionsFor] ago.
+// Failing to is logged, the screen is what was asked for.
+func (a *App) recordPredictions(ctx context.Context, t target, departures []transit.Departure) {
+	if !a.Cfg.Stats.RecordPredictions || len(departures) == 0 {
+		return
+	}
+
+	fetched := a.Now()
+	predictions := make([]transit.Prediction, 0, len(departures))
+	for _, d := range departures {
+		predictions = append(predictions, transit.Prediction{
+			Source:   d.Source,
+			StopID:   d.StopID,
+			StopName: d.StopName,
+			Line:     d.Line,
+			Headsign: d.Headsign,
+			Arrives:  d.Arrives,
+			Fetched:  fetched,
+		})
 	}
 
-	return nil
+	if err := a.Store.InsertPredictions(ctx, t.location, predictions); err != nil {
+		a.Log.DebugContext(ctx, "record predictions", "target", t.arg, "err", err)
+	}
+
+	if fetched.Sub(a.predictionsPruned) < prunePredictionsEvery {
+		return
+	}
+
+	a.predictionsPruned = fetched
+	if pruned, err := a.Store.PrunePredictions(ctx, fetched.Add(-config.KeepPredictionsFor)); err != nil {
+		a.Log.DebugContext(ctx, "prune predictions", "err", err)
+	} else if pruned > 0 {
+		a.Log.DebugContext(ctx, "prune predictions", "pruned", pruned)
+	}
+}
+
+// targetResult is what asking for one target's departures produced.
+type targetResult struct {
+	set transit.DepartureSet
+	err error
+}
+
+// fetchTargets asks every target's provider at once, at most maxTargetFetches of
+// them, so a long list of stations doesn't open a request per station on top of what
+// each provider fans out itself. Results are in the order of targets.
+func fetchTargets(ctx context.Context, targets []target) []targetResult {
+	results := make([]targetResult, len(targets))
+	sem := make(chan struct{}, maxTargetFetches)
+	var wg sync.WaitGroup
+
+	for n, t := range targets {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			set, err := t.provider.Departures(ctx, t.refs)
+			results[n] = targetResult{set: set, err: err}
+		})
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// fetchAlerts asks each of the providers for its alerts at once. A provider whose
+// alerts couldn't be fetched is left out, the departures are still worth showing
+// without them.
+func fetchAlerts(ctx context.Context, providers []transit.Provider) map[transit.Provider][]transit.Alert {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	alerts := make(map[transit.Provider][]transit.Alert, len(providers))
+	for _, p := range providers {
+		wg.Go(func() {
+			alertSet, err := p.Alerts(ctx)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			alerts[p] = alertSet.Alerts
+		})
+	}
+
+	wg.Wait()
+
+	return alerts
+}
+
+// relevantAlerts keeps the alerts in effect now that are about the target's stops or
+// the lines departing from them.
+func relevantAlerts(alerts []transit.Alert, t target, departures []transit.Departure, now time.Time) []transit.Alert {
+	filter := transit.AlertFilter{ActiveAt: now, Stops: slices.Clone(t.stops)}
+
+	for _, ref := range t.refs {
+		filter.Stops = append(filter.Stops, ref.StopID)
+	}
+
+	for _, d := range departures {
+		if d.Line != "" && !slices.Contains(filter.Lines, d.Line) {
+			filter.Lines = append(filter.Lines, d.Line)
+		}
+	}
+
+	return transit.AlertSet{Alerts: alerts}.Filter(filter).Alerts
+}
+
+// accessibilityOutages counts the elevator and escalator outages among a station's alerts.
+func accessibilityOutages(alerts []transit.Alert) int {
+	filter := transit.AlertFilter{Effects: []string{transit.EffectAccessibility}}
+	return len(transit.AlertSet{Alerts: alerts}.Filter(filter).Alerts)
 }
 
 // Groups departures by destination (assumes already sorted by minutes).
@@ -215,6 +483,11 @@ func endsWatch(err error) bool {
 		return true
 	}
 
+	// The wait is the upstream's call, nextWatch holds off until it's over.
+	if _, ok := errors.AsType[*provider.RateLimitError](err); ok {
+		return false
+	}
+
 	if httpErr, ok := errors.AsType[*provider.HTTPError](err); ok {
 		// 5xx and 429 are the upstream's problem and it could recover.
 		return httpErr.StatusCode < 500 && httpErr.StatusCode != http.StatusTooManyRequests
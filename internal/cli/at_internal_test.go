@@ -6,9 +6,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -90,6 +95,14 @@ func TestEndsWatch(t *testing.T) {
 			err:      fmt.Errorf("fetch departures for %q: %w", "courth", &provider.HTTPError{StatusCode: http.StatusInternalServerError}),
 			expected: false,
 		},
+		"a spent local budget keeps watching": {
+			err:      fetchErr(&provider.RateLimitError{Host: "api.511.org", RetryAfter: time.Minute}),
+			expected: false,
+		},
+		"an upstream rate limit keeps watching": {
+			err:      fetchErr(&provider.RateLimitError{Host: "api.511.org", Upstream: true}),
+			expected: false,
+		},
 		"404 ends the watch": {
 			err:      &provider.HTTPError{StatusCode: http.StatusNotFound},
 			expected: true,
@@ -176,6 +189,38 @@ func TestEndsWatch(t *testing.T) {
 	}
 }
 
+func TestNextWatch(t *testing.T) {
+	t.Parallel()
+
+	tt := map[string]struct {
+		err      error
+		expected time.Duration
+	}{
+		"an ordinary error waits the interval": {
+			err:      &provider.HTTPError{StatusCode: http.StatusServiceUnavailable},
+			expected: 10 * time.Second,
+		},
+		"a rate limit waits until it's over": {
+			err:      fetchErr(&provider.RateLimitError{Host: "api.511.org", RetryAfter: time.Minute}),
+			expected: time.Minute,
+		},
+		"a rate limit shorter than the interval waits the interval": {
+			err:      fetchErr(&provider.RateLimitError{Host: "api.511.org", RetryAfter: time.Second}),
+			expected: 10 * time.Second,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := nextWatch(tc.err, 10*time.Second); got != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
 func fetchErr(cause error) error {
 	urlErr := &url.Error{Op: "Get", URL: "https://api.wmata.com/StationPrediction.svc/json/GetPrediction", Err: cause}
 	return fmt.Errorf("fetch departures for %q: %w", "courth", urlErr)
@@ -186,3 +231,227 @@ func parseErr() error {
 	err := json.Unmarshal([]byte("{"), &struct{}{})
 	return fmt.Errorf("parse predictions response: %w", err)
 }
+
+// stubProvider answers Departures and Alerts with fixed results. When barrier is set every call
+// waits on it, so a test can prove calls overlap.
+type stubProvider struct {
+	set     transit.DepartureSet
+	err     error
+	barrier *sync.WaitGroup
+	alerts  transit.AlertSet
+
+	alertFetches atomic.Int32
+}
+
+func (p *stubProvider) Departures(ctx context.Context, _ []transit.StopRef) (transit.DepartureSet, error) {
+	if p.barrier != nil {
+		p.barrier.Done()
+
+		waited := make(chan struct{})
+		go func() {
+			p.barrier.Wait()
+			close(waited)
+		}()
+
+		select {
+		case <-waited:
+		case <-ctx.Done():
+			return transit.DepartureSet{}, ctx.Err()
+		}
+	}
+
+	return p.set, p.err
+}
+
+func (p *stubProvider) Alerts(context.Context) (transit.AlertSet, error) {
+	p.alertFetches.Add(1)
+	return p.alerts, nil
+}
+
+func (p *stubProvider) StopRefs(transit.Stop) []transit.StopRef { return nil }
+
+func TestRenderDepartures(t *testing.T) {
+	arriving := transit.DepartureSet{Departures: []transit.Departure{
+		{Line: "RD", Headsign: "Glenmont", Arrives: time.Now().Add(5 * time.Minute)},
+	}}
+	upstream := &provider.HTTPError{StatusCode: http.StatusBadGateway}
+
+	t.Run("one failed target doesn't hide the others", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		targets := []target{
+			{arg: "gallery", provider: &stubProvider{set: arriving}},
+			{arg: "courth", provider: &stubProvider{err: upstream}},
+			{arg: "metro", provider: &stubProvider{err: transit.ErrNoDepartures}},
+		}
+
+		if err := app.renderDepartures(t.Context(), targets); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		out := app.out.String()
+		arrived, failed := strings.Index(out, "Glenmont"), strings.Index(out, `fetch departures for "courth"`)
+		if failed == -1 {
+			t.Errorf("expected the failed target's screen on Out but got %q", out)
+		} else if arrived == -1 || arrived > failed {
+			t.Errorf("expected the failed screen after gallery's departures but got %q", out)
+		}
+
+		if app.err.Len() != 0 {
+			t.Errorf("expected nothing on Err but got %q, the failure has its own screen", app.err)
+		}
+	})
+
+	t.Run("alerts are fetched once per provider with a screen", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		shown := &stubProvider{set: arriving}
+		failed := &stubProvider{err: upstream}
+		empty := &stubProvider{err: transit.ErrNoDepartures}
+
+		targets := []target{
+			{arg: "gallery", provider: shown},
+			{arg: "metro", provider: shown},
+			{arg: "courth", provider: failed},
+			{arg: "union", provider: empty},
+		}
+
+		if err := app.renderDepartures(t.Context(), targets); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if got := shown.alertFetches.Load(); got != 1 {
+			t.Errorf("expected alerts fetched once for the shown provider but got %d", got)
+		}
+
+		if got := failed.alertFetches.Load() + empty.alertFetches.Load(); got != 0 {
+			t.Errorf("expected no alerts fetched without a screen but got %d", got)
+		}
+	})
+
+	t.Run("alerts shown are kept in the history", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		delay := transit.Alert{ID: "42", Source: "wmata", Effect: "Delay", Description: "Red Line delays"}
+		shown := &stubProvider{set: arriving, alerts: transit.AlertSet{Alerts: []transit.Alert{delay}}}
+
+		targets := []target{
+			{arg: "gallery", location: transit.DMVSlug, provider: shown},
+			{arg: "metro", location: transit.DMVSlug, provider: shown},
+		}
+
+		if err := app.renderDepartures(t.Context(), targets); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		records, err := app.Store.AlertHistory(t.Context(), transit.DMVSlug, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if len(records) != 1 || records[0].ID != "42" {
+			t.Errorf("expected alert 42 in the history but got %v", records)
+		}
+	})
+
+	t.Run("every target failing returns the failures", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		targets := []target{
+			{arg: "gallery", provider: &stubProvider{err: transit.ErrNoDepartures}},
+			{arg: "courth", provider: &stubProvider{err: upstream}},
+		}
+
+		err := app.renderDepartures(t.Context(), targets)
+		if exitCode(err) != 3 {
+			t.Errorf("expected exit code 3 but got %d (%v)", exitCode(err), err)
+		}
+
+		if app.err.Len() != 0 {
+			t.Errorf("expected nothing on Err but got %q, the caller prints the error", app.err)
+		}
+	})
+
+	t.Run("nothing arriving anywhere", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		targets := []target{{arg: "gallery", provider: &stubProvider{err: transit.ErrNoDepartures}}}
+
+		if err := app.renderDepartures(t.Context(), targets); !errors.Is(err, transit.ErrNoDepartures) {
+			t.Errorf("expected %v but got %v", transit.ErrNoDepartures, err)
+		}
+	})
+
+	t.Run("targets are fetched at the same time", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		var barrier sync.WaitGroup
+		barrier.Add(2)
+
+		targets := []target{
+			{arg: "gallery", provider: &stubProvider{set: arriving, barrier: &barrier}},
+			{arg: "courth", provider: &stubProvider{set: arriving, barrier: &barrier}},
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := app.renderDepartures(ctx, targets); err != nil {
+			t.Errorf("expected no error but got %v, the second fetch waited on the first", err)
+		}
+	})
+
+	t.Run("fetches are capped across targets", func(t *testing.T) {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		var running, peak atomic.Int32
+		targets := make([]target, 3*maxTargetFetches)
+		for n := range targets {
+			targets[n] = target{arg: strconv.Itoa(n), provider: &slowProvider{stubProvider: stubProvider{set: arriving}, running: &running, peak: &peak}}
+		}
+
+		if err := app.renderDepartures(t.Context(), targets); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if got := peak.Load(); got > maxTargetFetches {
+			t.Errorf("expected at most %d fetches at once but got %d", maxTargetFetches, got)
+		}
+	})
+}
+
+// slowProvider takes a moment to answer and keeps the most fetches it saw at once.
+type slowProvider struct {
+	stubProvider
+	running, peak *atomic.Int32
+}
+
+func (p *slowProvider) Departures(ctx context.Context, refs []transit.StopRef) (transit.DepartureSet, error) {
+	now := p.running.Add(1)
+	defer p.running.Add(-1)
+
+	for {
+		seen := p.peak.Load()
+		if now <= seen || p.peak.CompareAndSwap(seen, now) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	return p.stubProvider.Departures(ctx, refs)
+}
@@ -3,7 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"text/tabwriter"
 
 	"github.com/ismailshak/transit/internal/config"
 	"github.com/ismailshak/transit/internal/transit"
@@ -17,7 +17,15 @@ func (a *App) newConfigCmd() *cobra.Command {
 		Long: `
 Get and set configuration options.
 
-For nested config options, use a dot (.) as a delimiter.`,
+For nested config options, use a dot (.) as a delimiter.
+
+API keys can also come from the environment (e.g. TRANSIT_DMV_API_KEY), from
+a command (e.g. dmv.api_key_cmd set to "pass show wmata") or from the OS
+keyring (dmv.api_key set to "keyring:dmv.api_key", which init --keyring writes).
+
+Named profiles live under profiles.<name> (e.g. profiles.kiosk.core.location)
+and are layered over the base config with --profile or TRANSIT_PROFILE. While
+a profile is active, set and unset change that profile.`,
 		DisableFlagsInUseLine: true,
 	}
 
@@ -26,6 +34,9 @@ For nested config options, use a dot (.) as a delimiter.`,
 		a.newConfigGetCmd(),
 		a.newConfigSetCmd(),
 		a.newConfigPathCmd(),
+		a.newConfigListCmd(),
+		a.newConfigUnsetCmd(),
+		a.newConfigValidateCmd(),
 	)
 
 	return configCmd
@@ -35,7 +46,7 @@ func (a *App) newConfigGetCmd() *cobra.Command {
 	configGetCmd := &cobra.Command{
 		Use:                   "get <key>",
 		Short:                 "Get a key from the config file",
-		Long:                  "Get a key from the configuration file\nFor all values, check the docs https://transitcli.com/docs/config-reference",
+		Long:                  "Get a key from the configuration file\nAPI keys are masked. For all values, check the docs https://transitcli.com/docs/config-reference",
 		Example:               "  transit config get core.location",
 		Args:                  usageArgs(cobra.ExactArgs(1)),
 		DisableFlagsInUseLine: true,
@@ -69,7 +80,7 @@ func (a *App) newConfigSetCmd() *cobra.Command {
 				return err
 			}
 
-			_, err = fmt.Fprintf(a.Out, "'%s' has been set to '%s'\n", args[0], args[1])
+			_, err = fmt.Fprintf(a.Out, "'%s' has been set to '%s'\n", args[0], displayValue(args[0], args[1]))
 			return err
 		},
 	}
@@ -93,7 +104,63 @@ func (a *App) newConfigPathCmd() *cobra.Command {
 	return configPathCmd
 }
 
+func (a *App) newConfigListCmd() *cobra.Command {
+	configListCmd := &cobra.Command{
+		Use:                   "list",
+		Aliases:               []string{"ls"},
+		Short:                 "List every config key with its effective value",
+		Long:                  "List every known config key, its effective value and where that value came from (file, env or default)",
+		DisableFlagsInUseLine: true,
+		Args:                  usageArgs(cobra.NoArgs),
+		PreRunE:               a.configSetupPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.executeList()
+		},
+	}
+
+	return configListCmd
+}
+
+func (a *App) newConfigUnsetCmd() *cobra.Command {
+	configUnsetCmd := &cobra.Command{
+		Use:                   "unset <key>",
+		Short:                 "Remove a key from the config file",
+		Long:                  "Remove a key from the configuration file. Unknown keys can be removed too",
+		Example:               "  transit config unset core.watch_interval",
+		DisableFlagsInUseLine: true,
+		Args:                  usageArgs(cobra.ExactArgs(1)),
+		PreRunE:               a.configSetupPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.Cfg.Unset(args[0]); err != nil {
+				return err
+			}
+
+			_, err := fmt.Fprintf(a.Out, "'%s' has been unset\n", args[0])
+			return err
+		},
+	}
+
+	return configUnsetCmd
+}
+
+func (a *App) newConfigValidateCmd() *cobra.Command {
+	configValidateCmd := &cobra.Command{
+		Use:                   "validate",
+		Short:                 "Check the config file for unknown keys and bad values",
+		Long:                  "Check the configuration file for unknown keys and bad values. Exits with code 2 when a problem is found",
+		DisableFlagsInUseLine: true,
+		Args:                  usageArgs(cobra.NoArgs),
+		PreRunE:               a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.executeValidate(cmd.Context())
+		},
+	}
+
+	return configValidateCmd
+}
+
 // executeGet backs `config get`. Returns an empty string if the key isn't set.
+// Secrets come back masked.
 func (a *App) executeGet(key string) string {
 	result := a.Cfg.Get(key)
 
@@ -101,7 +168,16 @@ func (a *App) executeGet(key string) string {
 		return ""
 	}
 
-	return fmt.Sprint(result)
+	return displayValue(key, fmt.Sprint(result))
+}
+
+// displayValue masks value when key holds a secret.
+func displayValue(key, value string) string {
+	if config.IsSecret(key) {
+		return config.Mask(value)
+	}
+
+	return value
 }
 
 // executeSet backs `config set`. Validates the value before writing it.
@@ -120,38 +196,87 @@ func (a *App) executePath() error {
 	return err
 }
 
-func (a *App) validateKey(ctx context.Context, key, value string) error {
-	switch key {
-	case "core.location":
-		return a.validateLocation(ctx, value)
-	case "core.watch_interval":
-		return a.validateWatchInterval(value)
+// executeList backs `config list`.
+func (a *App) executeList() error {
+	settings, err := a.Cfg.Settings()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	w := tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE\tORIGIN\tDESCRIPTION")
+
+	for _, s := range settings {
+		var value string
+		if s.Value != nil {
+			value = displayValue(s.Key, fmt.Sprint(s.Value))
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Key, value, s.Origin, s.Description)
+	}
+
+	return w.Flush()
 }
 
-func (a *App) validateLocation(ctx context.Context, location string) error {
-	l, err := a.Store.Location(ctx, transit.LocationSlug(location))
+// executeValidate backs `config validate`. Every problem is printed before the
+// error that sets the exit code.
+func (a *App) executeValidate(ctx context.Context) error {
+	problems, err := a.Cfg.Validate()
 	if err != nil {
-		return fmt.Errorf("get location data: %w", err)
+		return err
 	}
 
-	if l == nil {
-		return fmt.Errorf("%w: %q is not a valid location", config.ErrInvalid, location)
+	// Only the store knows which locations exist.
+	if location := a.Cfg.Core.Location; location != "" {
+		l, err := a.Store.Location(ctx, transit.LocationSlug(location))
+		if err != nil {
+			return fmt.Errorf("get location data: %w", err)
+		}
+
+		if l == nil {
+			problems = append(problems, config.Problem{Key: "core.location", Message: fmt.Sprintf("%q is not a valid location", location)})
+		}
+	}
+
+	if len(problems) == 0 {
+		_, err := fmt.Fprintln(a.Out, a.Cfg.FileUsed()+" is valid")
+		return err
+	}
+
+	for _, p := range problems {
+		_, _ = fmt.Fprintln(a.Out, p)
+	}
+
+	return fmt.Errorf("%w: %d problem(s) in %s", config.ErrInvalid, len(problems), a.Cfg.FileUsed())
+}
+
+// validateKey rejects keys [config.Schema] doesn't know and values it doesn't accept.
+// A location is also checked against the store.
+func (a *App) validateKey(ctx context.Context, key, value string) error {
+	field, ok := config.Lookup(key)
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", config.ErrInvalid, key)
+	}
+
+	if _, err := field.Parse(value); err != nil {
+		return err
+	}
+
+	if field.Key == "core.location" {
+		return a.validateLocation(ctx, value)
 	}
 
 	return nil
 }
 
-func (a *App) validateWatchInterval(interval string) error {
-	i, err := strconv.ParseInt(interval, 10, 0)
+func (a *App) validateLocation(ctx context.Context, location string) error {
+	l, err := a.Store.Location(ctx, transit.LocationSlug(location))
 	if err != nil {
-		return fmt.Errorf("%w: watch_interval must be an integer", config.ErrInvalid)
+		return fmt.Errorf("get location data: %w", err)
 	}
 
-	if i <= 0 {
-		return fmt.Errorf("%w: watch_interval must be greater than 0", config.ErrInvalid)
+	if l == nil {
+		return fmt.Errorf("%w: %q is not a valid location", config.ErrInvalid, location)
 	}
 
 	return nil
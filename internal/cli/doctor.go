@@ -0,0 +1,359 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/provider"
+	"github.com/ismailshak/transit/internal/store"
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/ismailshak/transit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// staleStopsAfter is when seeded stops are old enough that a refresh is worth suggesting.
+	staleStopsAfter = 90 * 24 * time.Hour
+
+	// pingTimeout bounds each upstream check, so one dead host doesn't hold up the report.
+	pingTimeout = 10 * time.Second
+)
+
+// checkStatus is the outcome of one doctor check.
+type checkStatus string
+
+const (
+	checkPass checkStatus = "pass"
+	checkWarn checkStatus = "warn" // Works, but something is worth a look.
+	checkFail checkStatus = "fail" // Commands that need this won't work.
+)
+
+// check is one line of the doctor's report.
+type check struct {
+	Name   string      `json:"name"`
+	Status checkStatus `json:"status"`
+	Detail string      `json:"detail"`
+}
+
+// doctorReport is the machine-readable form of the report.
+type doctorReport struct {
+	OK     bool    `json:"ok"` // No check failed.
+	Checks []check `json:"checks"`
+}
+
+func (a *App) newDoctorCmd() *cobra.Command {
+	var outputFlag string
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the config, the database and the upstream APIs",
+		Long: `
+Check that transit is set up to work: the config file parses, the
+configured location exists, every location in use has stops and an
+API key, and each upstream accepts that key.
+
+Every check prints pass, warn or fail. Use --output json for a report
+that can be attached to a bug report. The exit code is 1 if any check failed.
+	`,
+		Args: usageArgs(cobra.NoArgs),
+		// No PreRunE, a config or database that fails to load is something to report.
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFlag != "text" && outputFlag != "json" {
+				return fmt.Errorf("%w: --output must be text or json, not %q", errUsage, outputFlag)
+			}
+
+			return a.executeDoctor(cmd.Context(), outputFlag)
+		},
+	}
+
+	doctorCmd.Flags().StringVarP(&outputFlag, "output", "o", "text", "report format, text or json")
+
+	return doctorCmd
+}
+
+func (a *App) executeDoctor(ctx context.Context, format string) error {
+	checks := a.diagnose(ctx)
+
+	report := doctorReport{OK: true, Checks: checks}
+	var failed int
+	for _, c := range checks {
+		if c.Status == checkFail {
+			report.OK = false
+			failed++
+		}
+	}
+
+	var err error
+	if format == "json" {
+		err = a.printReportJSON(report)
+	} else {
+		err = a.printReport(report)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+// diagnose runs every check it can. A check that later ones depend on stops the
+// run when it fails, rather than failing everything after it for the same reason.
+func (a *App) diagnose(ctx context.Context) []check {
+	checks := []check{a.checkConfig()}
+	if a.Cfg != nil {
+		checks = append(checks, a.checkConfigValues()...)
+	}
+
+	db, current := a.checkDatabase(ctx)
+	checks = append(checks, db)
+	if db.Status == checkFail || a.Cfg == nil {
+		return checks
+	}
+
+	loc := a.checkLocation(ctx)
+	checks = append(checks, loc)
+	if loc.Status == checkFail && a.location() == "" {
+		return checks
+	}
+
+	locations, err := a.searchLocations(ctx)
+	if err != nil {
+		return append(checks, check{Name: "locations", Status: checkFail, Detail: err.Error()})
+	}
+
+	for _, slug := range locations {
+		if l, err := a.Store.Location(ctx, slug); err != nil || l == nil {
+			continue // Already reported by checkLocation.
+		}
+
+		// Stops are described by tables a pending migration may not have made yet.
+		if current {
+			checks = append(checks, a.checkStops(ctx, slug))
+		}
+
+		key := a.checkAPIKey(slug)
+		checks = append(checks, key)
+		if key.Status == checkFail {
+			continue
+		}
+
+		checks = append(checks, a.checkUpstream(ctx, slug))
+	}
+
+	return checks
+}
+
+func (a *App) checkConfig() check {
+	cfg, err := config.Load(a.configOverride, a.profile)
+	if err != nil {
+		return check{Name: "config", Status: checkFail, Detail: err.Error()}
+	}
+
+	a.Cfg = cfg
+
+	detail := "loaded " + cfg.FileUsed()
+	if cfg.Profile() != "" {
+		detail += " with profile " + cfg.Profile()
+	}
+
+	return check{Name: "config", Status: checkPass, Detail: detail}
+}
+
+// checkConfigValues reports what `config validate` would, one line per problem.
+func (a *App) checkConfigValues() []check {
+	problems, err := a.Cfg.Validate()
+	if err != nil {
+		return []check{{Name: "config values", Status: checkFail, Detail: err.Error()}}
+	}
+
+	checks := make([]check, 0, len(problems))
+	for _, p := range problems {
+		checks = append(checks, check{Name: "config values", Status: checkWarn, Detail: p.String()})
+	}
+
+	return checks
+}
+
+// checkDatabase opens the store and reports its migrations. It never migrates, a
+// diagnostic leaves the database as it found it, so it also says whether the
+// tables are current enough for the checks that read them.
+func (a *App) checkDatabase(ctx context.Context) (check, bool) {
+	path, err := config.GetConfigDir()
+	if err != nil {
+		return check{Name: "database", Status: checkFail, Detail: err.Error()}, false
+	}
+
+	path = filepath.Join(path, "transit.db")
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return check{Name: "database", Status: checkFail, Detail: fmt.Sprintf("no database at %s, run transit init", path)}, false
+	}
+
+	db, err := store.New(path, store.WithLogger(a.Log))
+	if err != nil {
+		return check{Name: "database", Status: checkFail, Detail: err.Error()}, false
+	}
+
+	a.Store = db
+
+	applied, known, err := db.MigrationState(ctx)
+	if err != nil {
+		return check{Name: "database", Status: checkFail, Detail: err.Error()}, false
+	}
+
+	switch {
+	case applied > known:
+		return check{Name: "database", Status: checkFail, Detail: fmt.Sprintf("%s has %d migrations but this build knows %d, upgrade transit", path, applied, known)}, false
+	case applied < known:
+		return check{Name: "database", Status: checkWarn, Detail: fmt.Sprintf("%s is %d migrations behind, the next transit command applies them", path, known-applied)}, false
+	default:
+		return check{Name: "database", Status: checkPass, Detail: fmt.Sprintf("%s, %d migrations applied", path, applied)}, true
+	}
+}
+
+func (a *App) checkLocation(ctx context.Context) check {
+	slug := a.location()
+	if slug == "" {
+		return check{Name: "location", Status: checkFail, Detail: "core.location is not set, run transit init"}
+	}
+
+	l, err := a.Store.Location(ctx, slug)
+	if err != nil {
+		return check{Name: "location", Status: checkFail, Detail: err.Error()}
+	}
+
+	if l == nil {
+		return check{Name: "location", Status: checkFail, Detail: fmt.Sprintf("%q is not a supported location", slug)}
+	}
+
+	return check{Name: "location", Status: checkPass, Detail: fmt.Sprintf("%s (%s)", l.Name, slug)}
+}
+
+func (a *App) checkStops(ctx context.Context, slug transit.LocationSlug) check {
+	name := "stops " + string(slug)
+
+	count, err := a.Store.CountStopsByLocation(ctx, slug)
+	if err != nil {
+		return check{Name: name, Status: checkFail, Detail: err.Error()}
+	}
+
+	if count == 0 {
+		return check{Name: name, Status: checkFail, Detail: fmt.Sprintf("no stops, run transit init --location %s", slug)}
+	}
+
+	seeded, err := a.Store.SeededAt(ctx, slug)
+	if err != nil {
+		return check{Name: name, Status: checkFail, Detail: err.Error()}
+	}
+
+	refresh := fmt.Sprintf("run transit init --refresh --location %s", slug)
+	if seeded.IsZero() {
+		return check{Name: name, Status: checkWarn, Detail: fmt.Sprintf("%d stops from an older version of transit, %s to store everything this one uses", count, refresh)}
+	}
+
+	age := a.Now().Sub(seeded)
+	detail := fmt.Sprintf("%d stops, downloaded %s ago", count, age.Round(time.Hour))
+	if age > staleStopsAfter {
+		return check{Name: name, Status: checkWarn, Detail: detail + ", " + refresh}
+	}
+
+	return check{Name: name, Status: checkPass, Detail: detail}
+}
+
+func (a *App) checkAPIKey(slug transit.LocationSlug) check {
+	name := "api key " + string(slug)
+	key := string(slug) + ".api_key"
+
+	if !a.Cfg.HasAPIKey(string(slug)) {
+		return check{Name: name, Status: checkFail, Detail: fmt.Sprintf("not set, run transit config set %s or set %s", key, config.EnvVar(key))}
+	}
+
+	return check{Name: name, Status: checkPass, Detail: "set"}
+}
+
+// checkUpstream makes one authenticated request, which proves the host is reachable
+// and the key is accepted at the same time.
+func (a *App) checkUpstream(ctx context.Context, slug transit.LocationSlug) check {
+	name := "upstream " + string(slug)
+
+	p, err := a.providerFor(ctx, slug)
+	if err != nil {
+		return check{Name: name, Status: checkFail, Detail: err.Error()}
+	}
+
+	pinger, ok := p.(transit.Pinger)
+	if !ok {
+		return check{Name: name, Status: checkWarn, Detail: "this provider can't be checked"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	status, detail := classifyPing(pinger.Ping(ctx))
+	return check{Name: name, Status: status, Detail: detail}
+}
+
+// classifyPing tells a rejected key apart from an upstream that's down or unreachable.
+func classifyPing(err error) (checkStatus, string) {
+	if err == nil {
+		return checkPass, "reachable, API key accepted"
+	}
+
+	if rateErr, ok := errors.AsType[*provider.RateLimitError](err); ok {
+		return checkWarn, fmt.Sprintf("reachable, but rate limited for %v", rateErr.RetryAfter)
+	}
+
+	if httpErr, ok := errors.AsType[*provider.HTTPError](err); ok {
+		if httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden {
+			return checkFail, fmt.Sprintf("the API key was rejected (%d)", httpErr.StatusCode)
+		}
+
+		return checkWarn, fmt.Sprintf("reachable, but answered %d", httpErr.StatusCode)
+	}
+
+	return checkFail, "unreachable: " + err.Error()
+}
+
+var warnStyle = lipgloss.NewStyle().Foreground(tui.Orange).Render
+
+func (a *App) printReport(report doctorReport) error {
+	w := tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0)
+
+	for _, c := range report.Checks {
+		var label string
+		switch c.Status {
+		case checkPass:
+			label = tui.OpSuccessStyle("PASS")
+		case checkWarn:
+			label = warnStyle("WARN")
+		default:
+			label = tui.OpFailedStyle("FAIL")
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", label, c.Name, c.Detail)
+	}
+
+	return w.Flush()
+}
+
+func (a *App) printReportJSON(report doctorReport) error {
+	enc := json.NewEncoder(a.Out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(report)
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/provider"
+)
+
+func TestClassifyPing(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err  error
+		want checkStatus
+	}{
+		"accepted": {
+			err:  nil,
+			want: checkPass,
+		},
+		"rejected key": {
+			err:  &provider.HTTPError{StatusCode: http.StatusUnauthorized},
+			want: checkFail,
+		},
+		"upstream having a bad day": {
+			err:  &provider.HTTPError{StatusCode: http.StatusServiceUnavailable},
+			want: checkWarn,
+		},
+		"rate limited": {
+			err:  &provider.RateLimitError{Host: "api.511.org", RetryAfter: time.Minute},
+			want: checkWarn,
+		},
+		"unreachable": {
+			err:  errors.New("dial tcp: lookup api.wmata.com: no such host"),
+			want: checkFail,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, _ := classifyPing(tc.err); got != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	statuses := func(t *testing.T, app *testApp) map[string]checkStatus {
+		t.Helper()
+
+		var report doctorReport
+		if err := json.Unmarshal(app.out.Bytes(), &report); err != nil {
+			t.Fatalf("expected a JSON report but got %q", app.out)
+		}
+
+		got := make(map[string]checkStatus, len(report.Checks))
+		for _, c := range report.Checks {
+			got[c.Name] = c.Status
+		}
+
+		return got
+	}
+
+	t.Run("a fresh install fails on the missing database without creating one", func(t *testing.T) {
+		app := newTestApp(t)
+
+		if code := app.run("doctor", "--output", "json"); code != 1 {
+			t.Fatalf("expected exit code 1 but got %d (output %q)", code, app.err)
+		}
+
+		got := statuses(t, app)
+		if got["config"] != checkPass || got["database"] != checkFail {
+			t.Errorf("expected config to pass and database to fail but got %v", got)
+		}
+
+		dir, err := config.GetConfigDir()
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "transit.db")); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected doctor to leave no database behind but got %v", err)
+		}
+	})
+
+	t.Run("an uninitialized location without a key", func(t *testing.T) {
+		app := newTestApp(t)
+		t.Setenv(config.EnvVar("dmv.api_key"), "")
+
+		if code := app.run("config", "set", "core.location", "dmv"); code != 0 {
+			t.Fatalf("expected exit code 0 but got %d (output %q)", code, app.err)
+		}
+
+		app.out.Reset()
+		if code := app.run("doctor", "-o", "json"); code != 1 {
+			t.Fatalf("expected exit code 1 but got %d (output %q)", code, app.err)
+		}
+
+		got := statuses(t, app)
+		for name, want := range map[string]checkStatus{"location": checkPass, "stops dmv": checkFail, "api key dmv": checkFail} {
+			if got[name] != want {
+				t.Errorf("expected %s to %s but got %v", name, want, got)
+			}
+		}
+
+		if _, ok := got["upstream dmv"]; ok {
+			t.Error("expected no upstream check without a key")
+		}
+	})
+
+	t.Run("an unknown --output is a usage error", func(t *testing.T) {
+		app := newTestApp(t)
+
+		if code := app.run("doctor", "--output", "yaml"); code != 2 {
+			t.Errorf("expected exit code 2 but got %d", code)
+		}
+	})
+}
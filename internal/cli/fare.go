@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/ismailshak/transit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func (a *App) newFareCmd() *cobra.Command {
+	fareCmd := &cobra.Command{
+		Use:   "fare <from> <to>",
+		Short: "Look up the fare and rail time between two stations",
+		Long: `
+Show what a ride between two stations costs at peak and off-peak times, the
+reduced fare for seniors and riders with disabilities, how long the ride is
+scheduled to take and how far it is.
+
+Stations are matched like transit at. Only locations whose agency publishes
+fares support this, currently dmv.
+	`,
+		Example: "  transit fare \"metro center\" wiehle",
+		Args:    usageArgs(cobra.ExactArgs(2)),
+		PreRunE: a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.executeFare(cmd.Context(), args[0], args[1])
+		},
+	}
+
+	return fareCmd
+}
+
+func (a *App) executeFare(ctx context.Context, fromArg, toArg string) error {
+	location, from, to, err := a.resolveTrip(ctx, fromArg, toArg)
+	if err != nil {
+		return err
+	}
+
+	p, err := a.providerFor(ctx, location)
+	if err != nil {
+		return fmt.Errorf("%s: %w", location, err)
+	}
+
+	quoter, ok := p.(transit.FareQuoter)
+	if !ok {
+		return fmt.Errorf("%w: fares aren't available for %s", errUsage, location)
+	}
+
+	fare, err := quoter.Fare(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("look up fare: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(a.Out, "%s\n\n", tui.Bold(from.Name+" to "+to.Name))
+
+	w := tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Peak\t$%.2f\n", fare.Peak)
+	_, _ = fmt.Fprintf(w, "Off-peak\t$%.2f\n", fare.OffPeak)
+	_, _ = fmt.Fprintf(w, "Senior/disabled\t$%.2f\n", fare.SeniorDisabled)
+	_, _ = fmt.Fprintf(w, "Rail time\t%s\n", formatObserved(fare.RailTime))
+	_, _ = fmt.Fprintf(w, "Distance\t%.2f mi\n", fare.Miles)
+
+	return w.Flush()
+}
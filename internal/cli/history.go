@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/ismailshak/transit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// noLine groups alerts that didn't name a line, e.g. a single elevator.
+	noLine = "-"
+
+	// keepAlertHistoryFor is how long an alert stays in the history after it was last
+	// seen. A year covers the seasonal comparisons history is useful for.
+	keepAlertHistoryFor = 365 * 24 * time.Hour
+
+	// maxHistoryDescription keeps each alert on one line of the listing.
+	maxHistoryDescription = 60
+)
+
+// lineHistory is how one line fared over the period asked about.
+type lineHistory struct {
+	Line     string        `json:"line"`
+	Alerts   int           `json:"alerts"`
+	Observed time.Duration `json:"observed_ns"` // Summed over its alerts.
+	Longest  time.Duration `json:"longest_ns"`
+}
+
+// historyJSON is how `incidents history --output json` writes its report.
+type historyJSON struct {
+	Since  time.Time          `json:"since"`
+	Lines  []lineHistory      `json:"lines"`
+	Alerts []alertHistoryJSON `json:"alerts"`
+}
+
+type alertHistoryJSON struct {
+	alertJSON
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	Observed  time.Duration `json:"observed_ns"`
+}
+
+func (a *App) newIncidentsHistoryCmd() *cobra.Command {
+	var sinceFlag, outputFlag string
+	var linesFlag []string
+	var accessibilityFlag bool
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Summarize the disruptions seen in the past",
+		Long: `
+Summarize the alerts transit has seen for the configured location: how
+many each line had and how long they stayed up, followed by the alerts
+themselves.
+
+Alerts are remembered whenever incidents (or notify) fetches them, so the
+history only covers the times transit was asked. Durations are how long an
+alert was seen for, which makes them a lower bound. An alert is forgotten a
+year after it was last seen.
+
+Accessibility outages are left out like they are from incidents,
+--accessibility shows only those instead.
+
+--since takes a number of days (30d), a duration (12h) or a date (2026-10-01).
+	`,
+		Example: "  transit incidents history --line RD --since 30d",
+		Args:    usageArgs(cobra.NoArgs),
+		PreRunE: a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFlag != "text" && outputFlag != "json" {
+				return fmt.Errorf("%w: --output must be text or json, not %q", errUsage, outputFlag)
+			}
+
+			since, err := parseSince(sinceFlag, a.Now())
+			if err != nil {
+				return err
+			}
+
+			return a.executeHistory(cmd.Context(), since, linesFlag, accessibilityFlag, outputFlag)
+		},
+	}
+
+	historyCmd.Flags().StringVar(&sinceFlag, "since", "30d", "how far back to look")
+	historyCmd.Flags().StringSliceVar(&linesFlag, "line", nil, "only alerts affecting this line, e.g. RD")
+	historyCmd.Flags().BoolVar(&accessibilityFlag, "accessibility", false, "only elevator and escalator outages")
+	historyCmd.Flags().StringVarP(&outputFlag, "output", "o", "text", "output format, text or json")
+
+	return historyCmd
+}
+
+func (a *App) executeHistory(ctx context.Context, since time.Time, lines []string, accessibility bool, format string) error {
+	records, err := a.Store.AlertHistory(ctx, a.location(), since)
+	if err != nil {
+		return fmt.Errorf("read alert history: %w", err)
+	}
+
+	filter := accessibilityFilter(transit.AlertFilter{Lines: lines}, accessibility)
+	records = slices.DeleteFunc(records, func(r transit.AlertRecord) bool { return !filter.Match(r.Alert) })
+
+	summary := summarizeHistory(records, lines)
+
+	if format == "json" {
+		return a.printHistoryJSON(since, summary, records)
+	}
+
+	return a.printHistory(since, summary, records)
+}
+
+// recordAlerts adds alerts just fetched to the location's history, and drops what
+// was last seen more than keepAlertHistoryFor ago. History is a side effect, failing
+// to keep it is logged rather than costing the alerts.
+func (a *App) recordAlerts(ctx context.Context, location transit.LocationSlug, alerts []transit.Alert) {
+	if err := a.Store.RecordAlerts(ctx, location, alerts, a.Now()); err != nil {
+		a.Log.DebugContext(ctx, "record alert history", "err", err)
+	}
+
+	if pruned, err := a.Store.PruneAlertHistory(ctx, location, a.Now().Add(-keepAlertHistoryFor)); err != nil {
+		a.Log.DebugContext(ctx, "prune alert history", "err", err)
+	} else if pruned > 0 {
+		a.Log.DebugContext(ctx, "prune alert history", "pruned", pruned)
+	}
+}
+
+// summarizeHistory counts the alerts and time seen per line, busiest first. When
+// lines narrows the history down, only those lines are summarized, not every line
+// an alert happened to mention alongside them.
+func summarizeHistory(records []transit.AlertRecord, lines []string) []lineHistory {
+	byLine := make(map[string]*lineHistory)
+
+	add := func(line string, r transit.AlertRecord) {
+		h, ok := byLine[line]
+		if !ok {
+			h = &lineHistory{Line: line}
+			byLine[line] = h
+		}
+
+		h.Alerts++
+		h.Observed += r.Observed()
+		h.Longest = max(h.Longest, r.Observed())
+	}
+
+	for _, r := range records {
+		var routes []string
+		for _, ref := range r.Affected {
+			if ref.Kind == transit.RefRoute && !slices.Contains(routes, ref.ID) {
+				routes = append(routes, ref.ID)
+			}
+		}
+
+		if len(routes) == 0 && len(lines) == 0 {
+			add(noLine, r)
+		}
+
+		for _, route := range routes {
+			if len(lines) == 0 || slices.ContainsFunc(lines, func(l string) bool { return strings.EqualFold(l, route) }) {
+				add(route, r)
+			}
+		}
+	}
+
+	summary := make([]lineHistory, 0, len(byLine))
+	for _, h := range byLine {
+		summary = append(summary, *h)
+	}
+
+	slices.SortFunc(summary, func(x, y lineHistory) int {
+		if x.Alerts != y.Alerts {
+			return y.Alerts - x.Alerts
+		}
+
+		return strings.Compare(x.Line, y.Line)
+	})
+
+	return summary
+}
+
+func (a *App) printHistory(since time.Time, summary []lineHistory, records []transit.AlertRecord) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintf(a.Out, "No incidents seen since %s\n", since.Format(tui.DateFormat))
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.Out, "%d incidents seen since %s\n\n", len(records), since.Format(tui.DateFormat))
+
+	w := tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "LINE\tALERTS\tSEEN FOR\tLONGEST")
+	for _, h := range summary {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", h.Line, h.Alerts, formatObserved(h.Observed), formatObserved(h.Longest))
+	}
+
+	_, _ = fmt.Fprintln(w, "\nFIRST SEEN\tSEEN FOR\tEFFECT\tLINES\tDESCRIPTION")
+	for _, r := range records {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			r.FirstSeen.In(time.Local).Format(tui.DateFormat),
+			formatObserved(r.Observed()),
+			r.Effect,
+			strings.Join(routeLabels(r.Alert), ","),
+			truncate(r.Description, maxHistoryDescription),
+		)
+	}
+
+	return w.Flush()
+}
+
+func (a *App) printHistoryJSON(since time.Time, summary []lineHistory, records []transit.AlertRecord) error {
+	out := historyJSON{Since: since, Lines: summary, Alerts: make([]alertHistoryJSON, 0, len(records))}
+	for _, r := range records {
+		out.Alerts = append(out.Alerts, alertHistoryJSON{
+			alertJSON: a.toAlertJSON(r.Alert),
+			FirstSeen: r.FirstSeen,
+			LastSeen:  r.LastSeen,
+			Observed:  r.Observed(),
+		})
+	}
+
+	enc := json.NewEncoder(a.Out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
+
+// parseSince reads --since as days ("30d"), a duration ("12h") or a local date.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: --since must be like 30d, 12h or 2026-10-01, not %q", errUsage, value)
+}
+
+// formatObserved rounds to the minute, polling can't tell seconds apart anyway.
+func formatObserved(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+func routeLabels(alert transit.Alert) []string {
+	var labels []string
+	for _, ref := range alert.Affected {
+		if ref.Kind == transit.RefRoute {
+			labels = append(labels, ref.Label())
+		}
+	}
+
+	return labels
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+
+	tt := map[string]struct {
+		value    string
+		expected time.Time
+		err      error
+	}{
+		"days": {
+			value:    "30d",
+			expected: time.Date(2026, 9, 19, 12, 0, 0, 0, time.Local),
+		},
+		"a duration": {
+			value:    "12h",
+			expected: time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local),
+		},
+		"a date": {
+			value:    "2026-10-01",
+			expected: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local),
+		},
+		"something else": {
+			value: "last month",
+			err:   errUsage,
+		},
+		"negative days": {
+			value: "-3d",
+			err:   errUsage,
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSince(tc.value, now)
+
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error wrapping %v but got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if !got.Equal(tc.expected) {
+				t.Errorf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	record := func(observed time.Duration, lines ...string) transit.AlertRecord {
+		var affected []transit.AlertRef
+		for _, l := range lines {
+			affected = append(affected, transit.AlertRef{Kind: transit.RefRoute, ID: l})
+		}
+
+		return transit.AlertRecord{
+			Alert:     transit.Alert{Affected: affected},
+			FirstSeen: start,
+			LastSeen:  start.Add(observed),
+		}
+	}
+
+	records := []transit.AlertRecord{
+		record(30*time.Minute, "RD"),
+		record(2*time.Hour, "RD", "OR"),
+		record(10 * time.Minute),
+	}
+
+	tt := map[string]struct {
+		lines    []string
+		expected []lineHistory
+	}{
+		"every line": {
+			expected: []lineHistory{
+				{Line: "RD", Alerts: 2, Observed: 150 * time.Minute, Longest: 2 * time.Hour},
+				{Line: noLine, Alerts: 1, Observed: 10 * time.Minute, Longest: 10 * time.Minute},
+				{Line: "OR", Alerts: 1, Observed: 2 * time.Hour, Longest: 2 * time.Hour},
+			},
+		},
+		"only the lines asked about": {
+			lines: []string{"or"},
+			expected: []lineHistory{
+				{Line: "OR", Alerts: 1, Observed: 2 * time.Hour, Longest: 2 * time.Hour},
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := summarizeHistory(records, tc.lines)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v but got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestExecuteHistoryAccessibility(t *testing.T) {
+	delay := transit.Alert{ID: "1", Source: "wmata", Effect: "Delay", Description: "Red Line delays"}
+	elevator := transit.Alert{ID: "2", Source: "wmata", Effect: transit.EffectAccessibility, Description: "Elevator out at Metro Center"}
+
+	tests := map[string]struct {
+		accessibility bool
+		expected      string
+		unexpected    string
+	}{
+		"outages are left out by default":    {expected: delay.Description, unexpected: elevator.Description},
+		"--accessibility keeps only outages": {accessibility: true, expected: elevator.Description, unexpected: delay.Description},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			app := newTestApp(t)
+			app.locationOverride = string(transit.DMVSlug)
+			app.openStore()
+
+			if err := app.Store.RecordAlerts(t.Context(), transit.DMVSlug, []transit.Alert{delay, elevator}, time.Now()); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if err := app.executeHistory(t.Context(), time.Now().Add(-time.Hour), nil, tc.accessibility, "text"); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if !strings.Contains(app.out.String(), tc.expected) || strings.Contains(app.out.String(), tc.unexpected) {
+				t.Errorf("expected %q and not %q in %q", tc.expected, tc.unexpected, app.out)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ismailshak/transit/internal/ics"
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+const (
+	// icsProdID identifies transit as the maker of the calendars it writes.
+	icsProdID = "-//transit//at//EN"
+
+	// icsEventLength is how long a departure's event lasts. A departure is a moment,
+	// this is just enough for a calendar to draw it.
+	icsEventLength = 5 * time.Minute
+)
+
+func (a *App) executeAtICS(ctx context.Context, args []string) error {
+	targets, err := a.resolveStops(ctx, args)
+	if err != nil {
+		return err
+	}
+
+	return a.writeDeparturesICS(ctx, targets)
+}
+
+// writeDeparturesICS writes the targets' departures as an iCalendar file. Failures
+// are handled like the text screen: a target that failed is reported on Err as long
+// as another one has departures to write.
+func (a *App) writeDeparturesICS(ctx context.Context, targets []target) error {
+	results := fetchTargets(ctx, targets)
+
+	var events []ics.Event
+	var errs []error
+	for n, t := range targets {
+		r := results[n]
+
+		if errors.Is(r.err, transit.ErrNoDepartures) {
+			continue
+		}
+
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("fetch departures for %q: %w", t.arg, r.err))
+			continue
+		}
+
+		a.recordPredictions(ctx, t, r.set.Departures)
+
+		zones, err := a.agencyZones(ctx, t.location)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range r.set.Departures {
+			events = append(events, departureEvent(d, zones[d.AgencyID], a.Now()))
+		}
+
+		for _, s := range r.set.Degraded() {
+			a.warnf("%v", s.Err)
+		}
+	}
+
+	if len(events) == 0 {
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+
+		return transit.ErrNoDepartures
+	}
+
+	for _, err := range errs {
+		a.errorf("%s", err)
+	}
+
+	return ics.Write(a.Out, ics.Calendar{ProdID: icsProdID, Name: "transit", Events: events})
+}
+
+// agencyZones maps a location's agencies to their time zones. An agency whose
+// zone doesn't load is left out, and its times are written in UTC, or shown in the
+// local zone by trip.
+func (a *App) agencyZones(ctx context.Context, location transit.LocationSlug) (map[string]*time.Location, error) {
+	agencies, err := a.Store.Agencies(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("look up agencies: %w", err)
+	}
+
+	zones := make(map[string]*time.Location, len(agencies))
+	for _, agency := range agencies {
+		zone, err := time.LoadLocation(agency.Timezone)
+		if err != nil {
+			a.Log.DebugContext(ctx, "load agency timezone", "agency", agency.AgencyID, "timezone", agency.Timezone, "err", err)
+			continue
+		}
+
+		zones[agency.AgencyID] = zone
+	}
+
+	return zones, nil
+}
+
+// departureEvent is a departure as a calendar event. The UID is derived from the
+// departure, so importing the same departure twice doesn't duplicate it. A train
+// whose prediction moved is a different departure as far as the UID goes.
+func departureEvent(d transit.Departure, zone *time.Location, now time.Time) ics.Event {
+	if zone == nil {
+		zone = time.UTC
+	}
+
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%s|%s|%d", d.Source, d.StopID, d.Line, d.Headsign, d.Arrives.Unix()))
+
+	summary := "To " + d.Headsign
+	if d.Line != "" {
+		summary = d.Line + " to " + d.Headsign
+	}
+
+	return ics.Event{
+		UID:         hex.EncodeToString(sum[:12]) + "@transit",
+		Stamp:       now,
+		Start:       d.Arrives.In(zone),
+		End:         d.Arrives.Add(icsEventLength).In(zone),
+		Summary:     summary,
+		Location:    d.StopName,
+		Description: fmt.Sprintf("%s from %s", summary, d.StopName),
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/provider"
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+func TestWriteDeparturesICS(t *testing.T) {
+	arrives := time.Date(2026, 10, 19, 12, 15, 0, 0, time.UTC)
+	arriving := transit.DepartureSet{Departures: []transit.Departure{
+		{Source: "wmata", StopID: "A01", StopName: "Metro Center", AgencyID: "WMATA", Line: "RD", Headsign: "Glenmont", Arrives: arrives},
+		{Source: "wmata", StopID: "A01", StopName: "Metro Center", AgencyID: "elsewhere", Line: "RD", Headsign: "Shady Grove", Arrives: arrives},
+	}}
+
+	newApp := func(t *testing.T) *testApp {
+		app := newTestApp(t)
+		app.Log = slog.New(slog.DiscardHandler)
+		app.openStore()
+
+		agency := transit.Agency{AgencyID: "WMATA", Name: "WMATA", Location: transit.DMVSlug, Timezone: "America/New_York"}
+		if err := app.Store.InsertAgencies(t.Context(), []transit.Agency{agency}); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		return app
+	}
+
+	t.Run("departures are written in their agency's zone", func(t *testing.T) {
+		app := newApp(t)
+
+		targets := []target{
+			{arg: "metro", location: transit.DMVSlug, provider: &stubProvider{set: arriving}},
+			{arg: "courth", location: transit.DMVSlug, provider: &stubProvider{err: &provider.HTTPError{StatusCode: http.StatusBadGateway}}},
+		}
+
+		if err := app.writeDeparturesICS(t.Context(), targets); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		out := app.out.String()
+		for _, want := range []string{
+			"SUMMARY:RD to Glenmont\r\n",
+			"LOCATION:Metro Center\r\n",
+			"TZID:America/New_York\r\n",
+			"DTSTART;TZID=America/New_York:20261019T081500\r\n",
+			"DTEND;TZID=America/New_York:20261019T082000\r\n",
+			"DTSTART:20261019T121500Z\r\n", // The other agency has no zone on record.
+			"DTEND:20261019T122000Z\r\n",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q in\n%s", want, out)
+			}
+		}
+
+		if strings.Count(out, "BEGIN:VEVENT") != 2 {
+			t.Errorf("expected 2 events in\n%s", out)
+		}
+
+		if !strings.Contains(app.err.String(), `fetch departures for "courth"`) {
+			t.Errorf("expected the failed target on Err but got %q", app.err)
+		}
+	})
+
+	t.Run("nothing arriving writes no calendar", func(t *testing.T) {
+		app := newApp(t)
+
+		targets := []target{{arg: "metro", location: transit.DMVSlug, provider: &stubProvider{err: transit.ErrNoDepartures}}}
+
+		if err := app.writeDeparturesICS(t.Context(), targets); exitCode(err) != 4 {
+			t.Errorf("expected exit code 4 but got %d (%v)", exitCode(err), err)
+		}
+
+		if app.out.Len() != 0 {
+			t.Errorf("expected nothing on Out but got %q", app.out)
+		}
+	})
+}
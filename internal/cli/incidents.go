@@ -2,57 +2,374 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/ismailshak/transit/internal/transit"
 	"github.com/ismailshak/transit/internal/tui"
 	"github.com/spf13/cobra"
 )
 
+// incidentsFlags narrow down the alerts `incidents` shows.
+type incidentsFlags struct {
+	lines         []string
+	stops         []string
+	agencies      []string
+	active        bool
+	accessibility bool
+	output        string
+	watch         bool
+}
+
+// alertJSON is how `incidents --output json` writes an alert. It keeps every
+// translation, where the text output only shows the preferred one.
+type alertJSON struct {
+	ID           string              `json:"id,omitempty"`
+	Source       string              `json:"source"`
+	Agency       string              `json:"agency"`
+	Status       transit.AlertStatus `json:"status"`
+	Description  string              `json:"description"`
+	Translations []translationJSON   `json:"translations,omitempty"`
+	Effect       string              `json:"effect,omitempty"`
+	Cause        string              `json:"cause,omitempty"`
+	Affected     []affectedJSON      `json:"affected,omitempty"`
+	Periods      []periodJSON        `json:"periods,omitempty"`
+	Updated      *time.Time          `json:"updated,omitempty"`
+}
+
+type translationJSON struct {
+	Language string `json:"language"`
+	Text     string `json:"text"`
+}
+
+type affectedJSON struct {
+	Kind transit.RefKind `json:"kind"`
+	ID   string          `json:"id"`
+	Name string          `json:"name,omitempty"`
+}
+
+type periodJSON struct {
+	Starts *time.Time `json:"starts,omitempty"`
+	Ends   *time.Time `json:"ends,omitempty"`
+}
+
 func (a *App) newIncidentsCmd() *cobra.Command {
+	var flags incidentsFlags
+
 	incidentsCmd := &cobra.Command{
 		Use:     "incidents",
 		Aliases: []string{"inc"},
 		Short:   "Display reported disruptions or delays",
+		Long: `
+Display the disruptions and delays reported for the configured location.
+
+Alerts that have already ended are left out, transit incidents history
+keeps those.
+
+--line and --stop keep alerts about any of the lines or stations given,
+--agency keeps alerts from the agencies given, and --active drops alerts
+that aren't in effect right now. Flags can be repeated or comma separated.
+
+Elevator, escalator and other step-free access outages are left out, a
+busy system has dozens at once. --accessibility shows only those instead.
+
+Alerts are shown in core.language when the agency publishes that translation.
+--output json includes every translation.
+
+--watch polls every core.watch_interval seconds and prints only what changed:
+alerts newly posted, updated, or resolved since the last poll.
+
+Every alert seen is kept locally, see transit incidents history.
+	`,
+		Example: "  transit incidents --line RD --active\n  transit incidents --stop \"metro center\" --agency BA\n  transit incidents --accessibility",
 		Args:    usageArgs(cobra.NoArgs),
 		PreRunE: a.defaultPreRun,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p, err := a.provider()
+			ctx := cmd.Context()
+
+			if flags.output != "text" && flags.output != "json" {
+				return fmt.Errorf("%w: --output must be text or json, not %q", errUsage, flags.output)
+			}
+
+			if flags.watch && flags.output == "json" {
+				return fmt.Errorf("%w: --watch only prints text", errUsage)
+			}
+
+			p, err := a.provider(ctx)
 			if err != nil {
 				return err
 			}
 
-			return a.executeIncidents(cmd.Context(), p)
+			filter, err := a.alertFilter(ctx, p, flags)
+			if err != nil {
+				return err
+			}
+
+			// Not in alertFilter, notify holds on to its filter for the whole session.
+			filter.CurrentAt = a.Now()
+
+			if flags.watch {
+				return a.watchIncidents(ctx, p, filter)
+			}
+
+			return a.executeIncidents(ctx, p, filter, flags.output)
 		},
 	}
 
+	incidentsCmd.Flags().StringSliceVar(&flags.lines, "line", nil, "only alerts affecting this line, e.g. RD")
+	incidentsCmd.Flags().StringSliceVar(&flags.stops, "stop", nil, "only alerts affecting this station, matched like `at` does")
+	incidentsCmd.Flags().StringSliceVar(&flags.agencies, "agency", nil, "only alerts from this agency, e.g. BA")
+	incidentsCmd.Flags().BoolVar(&flags.active, "active", false, "only alerts in effect right now")
+	incidentsCmd.Flags().BoolVar(&flags.accessibility, "accessibility", false, "only elevator and escalator outages")
+	incidentsCmd.Flags().StringVarP(&flags.output, "output", "o", "text", "output format, text or json")
+	incidentsCmd.Flags().BoolVarP(&flags.watch, "watch", "w", false, "keep polling and print what changed")
+
+	incidentsCmd.AddCommand(a.newIncidentsHistoryCmd())
+
 	return incidentsCmd
 }
 
-func (a *App) executeIncidents(ctx context.Context, p transit.Provider) error {
+// alertFilter turns the flags into a filter. Stations are matched against the store
+// and every ID a provider knows them by is kept, since alerts can use either.
+// Accessibility outages are dropped unless the flags ask for only them: there are
+// enough to drown out everything else, and notify would send one for each.
+func (a *App) alertFilter(ctx context.Context, p transit.Provider, flags incidentsFlags) (transit.AlertFilter, error) {
+	filter := transit.AlertFilter{Lines: flags.lines, Agencies: flags.agencies}
+
+	if flags.active {
+		filter.ActiveAt = a.Now()
+	}
+
+	filter = accessibilityFilter(filter, flags.accessibility)
+
+	for _, query := range flags.stops {
+		stops, err := a.Store.MatchStops(ctx, a.location(), query)
+		if err != nil {
+			return transit.AlertFilter{}, fmt.Errorf("resolve %q: %w", query, err)
+		}
+
+		if len(stops) == 0 {
+			return transit.AlertFilter{}, fmt.Errorf("%w: no station matched %q", errUsage, query)
+		}
+
+		for _, s := range stops {
+			filter.Stops = append(filter.Stops, s.StopID)
+			for _, ref := range p.StopRefs(s) {
+				filter.Stops = append(filter.Stops, ref.StopID)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// accessibilityFilter has filter keep only accessibility outages, or drop them.
+func accessibilityFilter(filter transit.AlertFilter, only bool) transit.AlertFilter {
+	if only {
+		filter.Effects = []string{transit.EffectAccessibility}
+	} else {
+		filter.ExcludeEffects = []string{transit.EffectAccessibility}
+	}
+
+	return filter
+}
+
+func (a *App) executeIncidents(ctx context.Context, p transit.Provider, filter transit.AlertFilter, format string) error {
+	alertSet, err := a.fetchIncidents(ctx, p, filter)
+	if err != nil {
+		return err
+	}
+
+	showAgency, err := a.showAgency(ctx)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		if err := a.printAlertsJSON(alertSet.Alerts); err != nil {
+			return err
+		}
+	} else {
+		tui.PrintIncidents(a.Out, alertSet, showAgency, a.Now())
+	}
+
+	for _, s := range alertSet.Degraded() {
+		a.warnf("%v", s.Err)
+	}
+
+	return nil
+}
+
+// watchIncidents prints the alerts once, then only the difference each poll makes.
+// A poll that fails is skipped without touching what was last seen, so an outage
+// doesn't read as every alert being resolved.
+func (a *App) watchIncidents(ctx context.Context, p transit.Provider, filter transit.AlertFilter) error {
+	interval, err := watchInterval(a.Cfg.Core.WatchInterval)
+	if err != nil {
+		return err
+	}
+
+	showAgency, err := a.showAgency(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(a.Out, tui.Bold(fmt.Sprintf("Checking for incidents every %v. Press Ctrl+C to quit.", interval)))
+
+	var seen []transit.Alert
+	first := true
+
+	for {
+		filter.CurrentAt = a.Now()
+		if !filter.ActiveAt.IsZero() {
+			filter.ActiveAt = a.Now()
+		}
+
+		wait := interval
+		alertSet, err := a.fetchIncidents(ctx, p, filter)
+		switch {
+		case err != nil:
+			if endsWatch(err) {
+				return err
+			}
+
+			a.errorf("%s", err)
+			wait = nextWatch(err, interval)
+		case first:
+			tui.PrintIncidents(a.Out, alertSet, showAgency, a.Now())
+			seen, first = alertSet.Alerts, false
+		default:
+			current := carryOver(seen, alertSet)
+			if diff := transit.DiffAlerts(seen, current); !diff.Empty() {
+				tui.PrintAlertDiff(a.Out, diff, showAgency, a.Now())
+			}
+
+			seen = current
+		}
+
+		if err == nil {
+			for _, s := range alertSet.Degraded() {
+				a.warnf("%v", s.Err)
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// carryOver adds the alerts seen last time that went missing from a source that
+// is now failing. Nothing says they were resolved, only that they couldn't be fetched.
+func carryOver(seen []transit.Alert, alertSet transit.AlertSet) []transit.Alert {
+	degraded := alertSet.Degraded()
+	if len(degraded) == 0 {
+		return alertSet.Alerts
+	}
+
+	present := make(map[string]struct{}, len(alertSet.Alerts))
+	for _, alert := range alertSet.Alerts {
+		present[alert.Key()] = struct{}{}
+	}
+
+	current := slices.Clone(alertSet.Alerts)
+	for _, alert := range seen {
+		if _, ok := present[alert.Key()]; ok {
+			continue
+		}
+
+		if slices.ContainsFunc(degraded, func(s transit.SourceStatus) bool { return s.Source == alert.Source }) {
+			current = append(current, alert)
+		}
+	}
+
+	return current
+}
+
+// fetchIncidents polls the provider and keeps the alerts filter matches. It only
+// fails when every source did, a partial answer comes back with its failures.
+// Every alert fetched goes into the local history, whether it matches or not, and
+// what the history has kept for longer than keepAlertHistoryFor is dropped.
+func (a *App) fetchIncidents(ctx context.Context, p transit.Provider, filter transit.AlertFilter) (transit.AlertSet, error) {
 	alertSet, err := p.Alerts(ctx)
 	if err != nil {
-		return fmt.Errorf("fetch incidents: %w", err)
+		return transit.AlertSet{}, fmt.Errorf("fetch incidents: %w", err)
 	}
 
 	degraded := alertSet.Degraded()
 	if len(alertSet.Alerts) == 0 && len(degraded) > 0 {
-		return fmt.Errorf("fetch incidents: %w", errors.Join(errsOf(degraded)...))
+		return transit.AlertSet{}, fmt.Errorf("fetch incidents: %w", errors.Join(errsOf(degraded)...))
 	}
 
-	agencies, err := a.Store.Agencies(ctx, transit.LocationSlug(a.Cfg.Core.Location))
+	a.recordAlerts(ctx, a.location(), alertSet.Alerts)
+
+	return alertSet.Filter(filter), nil
+}
+
+// showAgency reports whether the location has more than one agency, in which case
+// each alert says which one it's from.
+func (a *App) showAgency(ctx context.Context) (bool, error) {
+	agencies, err := a.Store.Agencies(ctx, a.location())
 	if err != nil {
-		return fmt.Errorf("look up agencies: %w", err)
+		return false, fmt.Errorf("look up agencies: %w", err)
 	}
 
-	tui.PrintIncidents(alertSet, len(agencies) > 1)
+	return len(agencies) > 1, nil
+}
 
-	for _, s := range degraded {
-		a.warnf("%v", s.Err)
+func (a *App) printAlertsJSON(alerts []transit.Alert) error {
+	out := make([]alertJSON, 0, len(alerts))
+	for _, alert := range alerts {
+		out = append(out, a.toAlertJSON(alert))
 	}
 
-	return nil
+	enc := json.NewEncoder(a.Out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
+
+func (a *App) toAlertJSON(alert transit.Alert) alertJSON {
+	out := alertJSON{
+		ID:          alert.ID,
+		Source:      alert.Source,
+		Agency:      alert.AgencyID,
+		Status:      alert.Status(a.Now()),
+		Description: alert.Description,
+		Effect:      alert.Effect,
+		Cause:       alert.Cause,
+		Updated:     optionalTime(alert.Updated),
+	}
+
+	for _, t := range alert.Translations {
+		out.Translations = append(out.Translations, translationJSON{Language: t.Language, Text: t.Text})
+	}
+
+	for _, ref := range alert.Affected {
+		out.Affected = append(out.Affected, affectedJSON{Kind: ref.Kind, ID: ref.ID, Name: ref.Name})
+	}
+
+	for _, p := range alert.Periods {
+		out.Periods = append(out.Periods, periodJSON{Starts: optionalTime(p.Starts), Ends: optionalTime(p.Ends)})
+	}
+
+	return out
+}
+
+// optionalTime leaves a zero time out of the JSON rather than writing year 1.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
 }
 
 func errsOf(sources []transit.SourceStatus) []error {
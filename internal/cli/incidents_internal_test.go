@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+func TestCarryOver(t *testing.T) {
+	t.Parallel()
+
+	bart := transit.Alert{ID: "1", Source: "bayarea511", AgencyID: "BA", Description: "Delays"}
+	caltrain := transit.Alert{ID: "2", Source: "bayarea511", AgencyID: "CT", Description: "Single tracking"}
+	wmata := transit.Alert{ID: "3", Source: "wmata", Description: "Red Line delays"}
+
+	tt := map[string]struct {
+		seen     []transit.Alert
+		alertSet transit.AlertSet
+		expected []transit.Alert
+	}{
+		"a healthy poll is taken as it is": {
+			seen: []transit.Alert{bart, caltrain, wmata},
+			alertSet: transit.AlertSet{
+				Alerts:  []transit.Alert{bart},
+				Sources: []transit.SourceStatus{{Source: "bayarea511"}, {Source: "wmata"}},
+			},
+			expected: []transit.Alert{bart},
+		},
+		"alerts of a failing source are kept": {
+			seen: []transit.Alert{bart, caltrain, wmata},
+			alertSet: transit.AlertSet{
+				Alerts:  []transit.Alert{bart},
+				Sources: []transit.SourceStatus{{Source: "bayarea511", Err: errors.New("503")}, {Source: "wmata"}},
+			},
+			expected: []transit.Alert{bart, caltrain},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := carryOver(tc.seen, tc.alertSet)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v but got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAlertFilterAccessibility(t *testing.T) {
+	t.Parallel()
+
+	delay := transit.Alert{ID: "1", Effect: "Delay"}
+	elevator := transit.Alert{ID: "2", Effect: transit.EffectAccessibility}
+
+	tests := map[string]struct {
+		flags    incidentsFlags
+		expected []transit.Alert
+	}{
+		"outages are left out by default": {
+			expected: []transit.Alert{delay},
+		},
+		"--accessibility keeps only outages": {
+			flags:    incidentsFlags{accessibility: true},
+			expected: []transit.Alert{elevator},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Not newTestApp, whose home directory can't be set in parallel. Without
+			// --stop the filter doesn't need the store.
+			app := &App{Now: time.Now}
+
+			filter, err := app.alertFilter(t.Context(), &stubProvider{}, tc.flags)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			got := transit.AlertSet{Alerts: []transit.Alert{delay, elevator}}.Filter(filter).Alerts
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v but got %+v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -12,20 +12,30 @@ import (
 )
 
 func (a *App) newInitCmd() *cobra.Command {
+	var keyringFlag, refreshFlag bool
+
 	initCmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize transit",
 		Long: `
-Adds missing config properties and downloads static data for the chosen location`,
+Adds missing config properties and downloads static data for the chosen location.
+
+Run it again with --location to initialize another location alongside the
+configured one. The first location initialized becomes core.location.
+
+Static data is only downloaded once per location. Use --refresh to download it
+again, e.g. after the agency changes its schedule or to pick up data that a
+newer version of transit stores.`,
+		Example: "  transit init\n  transit init --location sf\n  transit init --refresh",
 		Args:    usageArgs(cobra.NoArgs),
 		PreRunE: a.defaultPreRun,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			if err := a.executeInitConfig(ctx); err != nil {
+			if err := a.executeInitConfig(ctx, keyringFlag); err != nil {
 				return fmt.Errorf("collect information: %w", err)
 			}
 
-			p, err := a.provider()
+			p, err := a.provider(ctx)
 			if err != nil {
 				return err
 			}
@@ -35,7 +45,7 @@ Adds missing config properties and downloads static data for the chosen location
 				return nil
 			}
 
-			if err := a.executeInitData(ctx, seeder, transit.LocationSlug(a.Cfg.Core.Location)); err != nil {
+			if err := a.executeInitData(ctx, seeder, a.location(), refreshFlag); err != nil {
 				return fmt.Errorf("initialize data: %w", err)
 			}
 
@@ -43,6 +53,9 @@ Adds missing config properties and downloads static data for the chosen location
 		},
 	}
 
+	initCmd.Flags().BoolVar(&keyringFlag, "keyring", false, "store the API key in the OS keyring instead of the config file")
+	initCmd.Flags().BoolVar(&refreshFlag, "refresh", false, "download static data again even if the location already has it")
+
 	return initCmd
 }
 
@@ -56,6 +69,10 @@ func toChoices(locations []transit.Location) []ui.Choice {
 }
 
 func (a *App) getConfiguredLocation(ctx context.Context) (string, error) {
+	if a.locationOverride != "" {
+		return a.overrideLocation(ctx)
+	}
+
 	location := a.Cfg.Core.Location
 	if location != "" {
 		return location, nil
@@ -92,10 +109,28 @@ func (a *App) getConfiguredLocation(ctx context.Context) (string, error) {
 	return selection, nil
 }
 
-func (a *App) confirmConfiguredKey(ctx context.Context, location string) error {
-	keyPath := fmt.Sprintf("%s.api_key", location)
-	apiKey := a.executeGet(keyPath)
-	if apiKey != "" {
+// overrideLocation initializes the location passed to --location. It only becomes
+// core.location when nothing was configured yet, otherwise the default is left alone.
+func (a *App) overrideLocation(ctx context.Context) (string, error) {
+	location := a.locationOverride
+
+	if a.Cfg.Core.Location != "" {
+		if err := a.validateLocation(ctx, location); err != nil {
+			return "", err
+		}
+
+		return location, nil
+	}
+
+	if err := a.executeSet(ctx, "core.location", location); err != nil {
+		return "", fmt.Errorf("set location: %w", err)
+	}
+
+	return location, nil
+}
+
+func (a *App) confirmConfiguredKey(ctx context.Context, location string, useKeyring bool) error {
+	if a.Cfg.HasAPIKey(location) {
 		return nil
 	}
 
@@ -115,7 +150,14 @@ func (a *App) confirmConfiguredKey(ctx context.Context, location string) error {
 		return err
 	}
 
-	err = a.executeSet(ctx, keyPath, key)
+	keyPath := fmt.Sprintf("%s.api_key", location)
+
+	if useKeyring {
+		err = a.Cfg.SetSecret(keyPath, key)
+	} else {
+		err = a.executeSet(ctx, keyPath, key)
+	}
+
 	if err != nil {
 		return fmt.Errorf("set api key: %w", err)
 	}
@@ -123,7 +165,7 @@ func (a *App) confirmConfiguredKey(ctx context.Context, location string) error {
 	return nil
 }
 
-func (a *App) executeInitConfig(ctx context.Context) error {
+func (a *App) executeInitConfig(ctx context.Context, useKeyring bool) error {
 	location, err := a.getConfiguredLocation(ctx)
 	if err != nil {
 		return err
@@ -131,7 +173,7 @@ func (a *App) executeInitConfig(ctx context.Context) error {
 
 	tui.OperationSuccessful("Location set to " + location)
 
-	err = a.confirmConfiguredKey(ctx, location)
+	err = a.confirmConfiguredKey(ctx, location, useKeyring)
 	if err != nil {
 		return err
 	}
@@ -140,14 +182,14 @@ func (a *App) executeInitConfig(ctx context.Context) error {
 	return nil
 }
 
-func (a *App) executeInitData(ctx context.Context, seeder transit.Seeder, location transit.LocationSlug) error {
+func (a *App) executeInitData(ctx context.Context, seeder transit.Seeder, location transit.LocationSlug, refresh bool) error {
 	count, err := a.Store.CountStopsByLocation(ctx, location)
 	if err != nil {
 		return fmt.Errorf("count stops: %w", err)
 	}
 
-	if count > 0 {
-		tui.OperationSuccessful("Data initialized")
+	if count > 0 && !refresh {
+		tui.OperationSuccessful("Data initialized, use --refresh to download it again")
 		return nil
 	}
 
@@ -177,15 +219,7 @@ func (a *App) executeInitData(ctx context.Context, seeder transit.Seeder, locati
 		ErrorMessage:   "Failed to save data",
 		SuccessMessage: "Data saved",
 		CallbackFn: func(ctx context.Context) error {
-			if insertErr := a.Store.InsertAgencies(ctx, d.Agencies); insertErr != nil {
-				return insertErr
-			}
-
-			if insertErr := a.Store.InsertStops(ctx, d.Stops); insertErr != nil {
-				return insertErr
-			}
-
-			return nil
+			return a.saveStatic(ctx, location, d)
 		},
 	})
 
@@ -201,3 +235,41 @@ func (a *App) executeInitData(ctx context.Context, seeder transit.Seeder, locati
 	_, err = fmt.Fprintln(a.Out, "\nSuccessfully initialized. Use transit --help for commands and examples")
 	return err
 }
+
+// saveStatic writes freshly seeded static data for a location. Everything is
+// upserted, so it's also how a seeded location is refreshed: stops and trips the
+// feed no longer lists are pruned, and a schedule that failed to download keeps the
+// trips and patterns from the last one.
+func (a *App) saveStatic(ctx context.Context, location transit.LocationSlug, d *transit.Static) error {
+	if err := a.Store.InsertAgencies(ctx, d.Agencies); err != nil {
+		return err
+	}
+
+	if err := a.Store.InsertStops(ctx, d.Stops); err != nil {
+		return err
+	}
+
+	if len(d.Stops) > 0 {
+		if _, err := a.Store.PruneStops(ctx, location, d.Stops); err != nil {
+			return err
+		}
+	}
+
+	if err := a.Store.InsertTrips(ctx, location, d.Trips); err != nil {
+		return err
+	}
+
+	if len(d.Trips) > 0 {
+		if _, err := a.Store.PruneTrips(ctx, location, d.Trips); err != nil {
+			return err
+		}
+	}
+
+	if len(d.Patterns) > 0 {
+		if err := a.Store.ReplacePatterns(ctx, location, d.Patterns); err != nil {
+			return err
+		}
+	}
+
+	return a.Store.MarkSeeded(ctx, location, a.Now())
+}
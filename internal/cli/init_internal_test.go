@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"log/slog"
+	"maps"
 	"slices"
 	"testing"
 
@@ -40,3 +42,68 @@ func TestToChoices(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveStaticRefreshesASeededLocation(t *testing.T) {
+	app := newTestApp(t)
+	app.Log = slog.New(slog.DiscardHandler)
+	app.openStore()
+
+	agency := transit.Agency{AgencyID: "BA", Name: "Bay Area Rapid Transit", Location: transit.SFSlug}
+	stop := func(id, name string) transit.Stop {
+		return transit.Stop{StopID: id, AgencyID: "BA", Name: name, Location: transit.SFSlug}
+	}
+
+	// Seeded by a version that didn't store trips or patterns.
+	old := &transit.Static{Agencies: []transit.Agency{agency}, Stops: []transit.Stop{stop("EMBR", "Embarcadero"), stop("GONE", "Closed")}}
+	if err := app.saveStatic(t.Context(), transit.SFSlug, old); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	fresh := &transit.Static{
+		Agencies: []transit.Agency{agency},
+		Stops:    []transit.Stop{stop("EMBR", "Embarcadero"), stop("MONT", "Montgomery St")},
+		Trips:    []transit.Trip{{TripID: "1234", RouteID: "Yellow-N"}},
+		Patterns: []transit.Pattern{{PatternID: "p1", RouteID: "Yellow-N", Stops: []transit.PatternStop{{StopID: "EMBR"}, {StopID: "MONT"}}}},
+	}
+
+	if err := app.saveStatic(t.Context(), transit.SFSlug, fresh); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	routes, err := app.Store.TripRoutes(t.Context(), transit.SFSlug, []string{"1234"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if routes["1234"] != "Yellow-N" {
+		t.Errorf("expected trip 1234 to resolve to Yellow-N but got %v", routes)
+	}
+
+	patterns, err := app.Store.Patterns(t.Context(), transit.SFSlug)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(patterns) != 1 {
+		t.Errorf("expected the refreshed pattern but got %v", patterns)
+	}
+
+	names, err := app.Store.StopNames(t.Context(), transit.SFSlug, []string{"EMBR", "MONT", "GONE"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	want := map[string]string{"EMBR": "Embarcadero", "MONT": "Montgomery St"}
+	if !maps.Equal(names, want) {
+		t.Errorf("expected %v but got %v", want, names)
+	}
+
+	seeded, err := app.Store.SeededAt(t.Context(), transit.SFSlug)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if seeded.IsZero() {
+		t.Error("expected the refresh to be recorded")
+	}
+}
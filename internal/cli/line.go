@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/ismailshak/transit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func (a *App) newLineCmd() *cobra.Command {
+	lineCmd := &cobra.Command{
+		Use:   "line <line>",
+		Short: "Show where the trains on a line are right now",
+		Long: `
+Draw a line as a vertical strip of its stations with a marker for every train
+running on it. Trains stopped at a station sit beside it, trains between two
+stations sit in the gap. Arrows point the way each train is headed.
+
+Lines are given by their code, e.g. RD, BL or SV. Only locations whose agency
+publishes live train positions support this, currently dmv.
+	`,
+		Example: "  transit line RD",
+		Args:    usageArgs(cobra.ExactArgs(1)),
+		PreRunE: a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.executeLine(cmd.Context(), args[0])
+		},
+	}
+
+	return lineCmd
+}
+
+func (a *App) executeLine(ctx context.Context, line string) error {
+	p, err := a.provider(ctx)
+	if err != nil {
+		return err
+	}
+
+	locator, ok := p.(transit.VehicleLocator)
+	if !ok {
+		return fmt.Errorf("%w: live train positions aren't available for %s", errUsage, a.location())
+	}
+
+	positions, err := locator.Positions(ctx, strings.ToUpper(line))
+	if errors.Is(err, transit.ErrUnknownLine) {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("train positions: %w", err)
+	}
+
+	tui.PrintLineMap(a.Out, positions)
+
+	return nil
+}
@@ -0,0 +1,406 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/notify"
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// forgetNotifiedAfter is how long a sent notification is remembered. Alerts are
+	// long gone by then, and the table shouldn't grow for as long as the daemon runs.
+	forgetNotifiedAfter = 30 * 24 * time.Hour
+
+	// noServiceAfterPolls is how many polls in a row have to find a station without
+	// departures before that's sent. A single empty board is often a feed hiccup, or
+	// a gap between trains late at night.
+	noServiceAfterPolls = 3
+
+	// maxEmptyPollGap is how long after the last empty poll the next one still counts
+	// as in a row. It's generous enough for --once runs from cron.
+	maxEmptyPollGap = time.Hour
+
+	// defaultNotifyInterval is the time between checks when notify.interval isn't set.
+	defaultNotifyInterval = time.Minute
+
+	// sfNotifyInterval is the default in sf. 511 allows 60 requests an hour, and every
+	// check spends one on alerts and one per watched station.
+	sfNotifyInterval = 5 * time.Minute
+)
+
+// notifyFlags override the notify section of the config for one run.
+type notifyFlags struct {
+	lines []string
+	stops []string
+	sink  string
+	once  bool
+}
+
+func (a *App) newNotifyCmd() *cobra.Command {
+	var flags notifyFlags
+
+	notifyCmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Send a notification when a watched line or station is disrupted",
+		Long: fmt.Sprintf(`
+Keep checking the configured location and send a notification when an
+alert is posted for a watched line or station, or when a watched station
+stops having departures (and again when they come back). Elevator and
+escalator outages aren't sent, transit incidents --accessibility lists them.
+
+Lines and stations come from notify.lines and notify.stops, or from --line
+and --stop. Notifications go to the sink in notify.sink or --sink:
+
+  stdout   one JSON object per line
+  bell     ring the terminal bell and print the notification
+  command  run notify.command with the title and body appended
+  webhook  POST the notification as JSON to notify.webhook_url
+
+A station is only reported without departures once it has had none for
+%d checks in a row. Checks run every notify.interval seconds, or every %d
+seconds when it isn't set (%d in sf, to stay within 511's limit of 60
+requests an hour).
+
+What was sent is remembered, so restarting doesn't send it again.
+	`, noServiceAfterPolls, int(defaultNotifyInterval.Seconds()), int(sfNotifyInterval.Seconds())),
+		Example: "  transit notify --line OR --sink command\n  transit notify --stop \"metro center\" --once",
+		Args:    usageArgs(cobra.NoArgs),
+		PreRunE: a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			n, err := a.newNotifier(ctx, flags)
+			if err != nil {
+				return err
+			}
+
+			// Here rather than in the watch loop, so runs from cron prune too.
+			if pruned, err := a.Store.PruneNotified(ctx, a.Now().Add(-forgetNotifiedAfter)); err != nil {
+				a.Log.DebugContext(ctx, "prune notified", "err", err)
+			} else if pruned > 0 {
+				a.Log.DebugContext(ctx, "prune notified", "pruned", pruned)
+			}
+
+			if flags.once {
+				return n.poll(ctx)
+			}
+
+			return a.watchNotify(ctx, n)
+		},
+	}
+
+	notifyCmd.Flags().StringSliceVar(&flags.lines, "line", nil, "watch this line instead of notify.lines, e.g. OR")
+	notifyCmd.Flags().StringSliceVar(&flags.stops, "stop", nil, "watch this station instead of notify.stops")
+	notifyCmd.Flags().StringVar(&flags.sink, "sink", "", "send notifications here instead of notify.sink")
+	notifyCmd.Flags().BoolVar(&flags.once, "once", false, "check once and exit, e.g. from cron")
+
+	return notifyCmd
+}
+
+// notifier is what one `transit notify` session watches and where it reports.
+type notifier struct {
+	app      *App
+	location transit.LocationSlug
+	provider transit.Provider
+	filter   transit.AlertFilter
+	lines    []string
+	targets  []target
+	sink     notify.Sink
+}
+
+func (a *App) newNotifier(ctx context.Context, flags notifyFlags) (*notifier, error) {
+	lines := flags.lines
+	if len(lines) == 0 {
+		lines = splitList(a.Cfg.Notify.Lines)
+	}
+
+	stops := flags.stops
+	if len(stops) == 0 {
+		stops = splitList(a.Cfg.Notify.Stops)
+	}
+
+	if len(lines) == 0 && len(stops) == 0 {
+		return nil, fmt.Errorf("%w: nothing to watch, pass --line or --stop or set notify.lines or notify.stops", errUsage)
+	}
+
+	sinkName := flags.sink
+	if sinkName == "" {
+		sinkName = a.Cfg.Notify.Sink
+	}
+
+	sink, err := notify.New(sinkName, notify.Options{
+		Out:        a.Out,
+		Command:    a.Cfg.Notify.Command,
+		WebhookURL: a.Cfg.Notify.WebhookURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	p, err := a.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := a.alertFilter(ctx, p, incidentsFlags{lines: lines, stops: stops})
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []target
+	if len(stops) > 0 {
+		if targets, err = a.resolveStops(ctx, stops); err != nil {
+			return nil, err
+		}
+	}
+
+	return &notifier{
+		app:      a,
+		location: a.location(),
+		provider: p,
+		filter:   filter,
+		lines:    lines,
+		targets:  targets,
+		sink:     sink,
+	}, nil
+}
+
+func (a *App) watchNotify(ctx context.Context, n *notifier) error {
+	interval, err := notifyInterval(a.Cfg.Notify.Interval, n.location)
+	if err != nil {
+		return err
+	}
+
+	// Err, so stdout only carries notifications when that's the sink.
+	_, _ = fmt.Fprintf(a.Err, "Checking every %v. Press Ctrl+C to quit.\n", interval)
+
+	for {
+		wait := interval
+		if err := n.poll(ctx); err != nil {
+			if endsWatch(err) {
+				return err
+			}
+
+			a.errorf("%s", err)
+			wait = nextWatch(err, interval)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// notifyInterval is the time between checks, notify.interval or the location's
+// default when it isn't set.
+func notifyInterval(seconds int, location transit.LocationSlug) (time.Duration, error) {
+	switch {
+	case seconds < 0:
+		return 0, fmt.Errorf("%w: notify.interval must be greater than 0", config.ErrInvalid)
+	case seconds > 0:
+		return time.Duration(seconds) * time.Second, nil
+	case location == transit.SFSlug:
+		return sfNotifyInterval, nil
+	default:
+		return defaultNotifyInterval, nil
+	}
+}
+
+// poll checks everything once and sends what's new. It returns what couldn't be
+// fetched. A notification that couldn't be sent is reported and retried next poll.
+func (n *notifier) poll(ctx context.Context) error {
+	var errs []error
+
+	if err := n.checkAlerts(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, t := range n.targets {
+		if err := n.checkService(ctx, t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkAlerts sends every alert on a watched line or stop that isn't over yet.
+func (n *notifier) checkAlerts(ctx context.Context) error {
+	alertSet, err := n.app.fetchIncidents(ctx, n.provider, n.filter)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range alertSet.Degraded() {
+		n.app.warnf("%v", s.Err)
+	}
+
+	now := n.app.Now()
+	for _, alert := range alertSet.Alerts {
+		if alert.Status(now) == transit.AlertEnded {
+			continue
+		}
+
+		n.send(ctx, notify.Notification{
+			Key:   "alert:" + alert.Key(),
+			Kind:  notify.KindAlert,
+			Title: alertTitle(alert),
+			Body:  alert.Description,
+			Time:  now,
+		})
+	}
+
+	return nil
+}
+
+// checkService notices a watched station losing, or getting back, its departures
+// on the watched lines. Losing them takes noServiceAfterPolls empty polls in a row.
+// The count and whether the loss was sent are kept in the store rather than in
+// memory, so a restart or a --once run from cron still pairs every outage with its
+// recovery.
+func (n *notifier) checkService(ctx context.Context, t target) error {
+	set, err := t.provider.Departures(ctx, t.refs)
+	if err != nil && !errors.Is(err, transit.ErrNoDepartures) {
+		return fmt.Errorf("fetch departures for %q: %w", t.arg, err)
+	}
+
+	running := false
+	for _, d := range set.Departures {
+		if len(n.lines) == 0 || slices.ContainsFunc(n.lines, func(l string) bool { return strings.EqualFold(l, d.Line) }) {
+			running = true
+			break
+		}
+	}
+
+	key := fmt.Sprintf("no_service:%s:%s", t.location, strings.ToLower(t.arg))
+	name := targetName(t)
+	now := n.app.Now()
+
+	if !running {
+		polls, err := n.app.Store.CountEmptyPoll(ctx, n.location, key, now, maxEmptyPollGap)
+		if err != nil {
+			n.app.errorf("%s", err)
+			return nil
+		}
+
+		if polls < noServiceAfterPolls {
+			return nil
+		}
+
+		n.send(ctx, notify.Notification{
+			Key:   key,
+			Kind:  notify.KindNoService,
+			Title: "No departures at " + name,
+			Body:  fmt.Sprintf("Nothing%s is predicted to depart from %s.", onLines(n.lines), name),
+			Time:  now,
+		})
+
+		return nil
+	}
+
+	if err := n.app.Store.ResetEmptyPolls(ctx, n.location, key); err != nil {
+		n.app.errorf("%s", err)
+	}
+
+	forgot, err := n.app.Store.ForgetNotified(ctx, n.location, key)
+	if err != nil {
+		n.app.errorf("%s", err)
+	}
+
+	if forgot {
+		n.deliver(ctx, notify.Notification{
+			Key:   key,
+			Kind:  notify.KindResumed,
+			Title: "Departures are back at " + name,
+			Body:  fmt.Sprintf("Departures%s are predicted at %s again.", onLines(n.lines), name),
+			Time:  now,
+		})
+	}
+
+	return nil
+}
+
+// send delivers a notification unless one with its key was already sent. When
+// delivery fails the key is forgotten, so the next poll tries again.
+func (n *notifier) send(ctx context.Context, msg notify.Notification) {
+	fresh, err := n.app.Store.MarkNotified(ctx, n.location, msg.Key)
+	if err != nil {
+		n.app.errorf("%s", err)
+		return
+	}
+
+	if !fresh {
+		return
+	}
+
+	if !n.deliver(ctx, msg) {
+		if _, err := n.app.Store.ForgetNotified(ctx, n.location, msg.Key); err != nil {
+			n.app.errorf("%s", err)
+		}
+	}
+}
+
+// deliver hands msg to the sink, reporting whether it was accepted.
+func (n *notifier) deliver(ctx context.Context, msg notify.Notification) bool {
+	if err := n.sink.Notify(ctx, msg); err != nil {
+		n.app.errorf("notify %q: %s", msg.Key, err)
+		return false
+	}
+
+	return true
+}
+
+// alertTitle says what an alert is and which lines it's on, e.g. "Delay on OR, SV".
+func alertTitle(alert transit.Alert) string {
+	title := alert.Effect
+	if title == "" {
+		title = "Alert"
+	}
+
+	if lines := routeLabels(alert); len(lines) > 0 {
+		title += " on " + strings.Join(lines, ", ")
+	}
+
+	return title
+}
+
+// targetName is the station's name, when the provider gave one, or what was typed.
+func targetName(t target) string {
+	if len(t.refs) > 0 && t.refs[0].Name != "" {
+		return t.refs[0].Name
+	}
+
+	return t.arg
+}
+
+func onLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return " on " + strings.Join(lines, ", ")
+}
+
+// splitList reads a comma separated config value. Blank entries are dropped.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
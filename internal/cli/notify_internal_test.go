@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/notify"
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+// recordingSink keeps what it was asked to send, or refuses when err is set.
+type recordingSink struct {
+	sent []notify.Notification
+	err  error
+}
+
+func (s *recordingSink) Notify(_ context.Context, n notify.Notification) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.sent = append(s.sent, n)
+	return nil
+}
+
+func (s *recordingSink) keys() []string {
+	keys := make([]string, 0, len(s.sent))
+	for _, n := range s.sent {
+		keys = append(keys, n.Key)
+	}
+
+	return keys
+}
+
+func newTestNotifier(t *testing.T, p *stubProvider, sink notify.Sink) *notifier {
+	t.Helper()
+
+	app := newTestApp(t)
+	app.Log = slog.New(slog.DiscardHandler)
+	app.locationOverride = string(transit.DMVSlug)
+	app.openStore()
+
+	return &notifier{
+		app:      app.App,
+		location: transit.DMVSlug,
+		provider: p,
+		lines:    []string{"OR"},
+		targets:  []target{{arg: "vienna", location: transit.DMVSlug, provider: p, refs: []transit.StopRef{{StopID: "K08", Name: "Vienna"}}}},
+		sink:     sink,
+	}
+}
+
+func TestNotifierPoll(t *testing.T) {
+	delay := transit.Alert{ID: "42", Source: "wmata", Effect: "Delay", Description: "Orange Line delays",
+		Affected: []transit.AlertRef{{Kind: transit.RefRoute, ID: "OR"}}}
+	ended := transit.Alert{ID: "7", Source: "wmata", Effect: "Delay", Description: "Over",
+		Periods: []transit.ActivePeriod{{Ends: time.Now().Add(-time.Hour)}}}
+	orange := transit.DepartureSet{Departures: []transit.Departure{{Line: "OR", Arrives: time.Now().Add(4 * time.Minute)}}}
+	silver := transit.DepartureSet{Departures: []transit.Departure{{Line: "SV", Arrives: time.Now().Add(4 * time.Minute)}}}
+
+	t.Run("an alert is sent once", func(t *testing.T) {
+		p := &stubProvider{set: orange, alerts: transit.AlertSet{Alerts: []transit.Alert{delay, ended}}}
+		sink := &recordingSink{}
+		n := newTestNotifier(t, p, sink)
+
+		for range 2 {
+			if err := n.poll(t.Context()); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+		}
+
+		want := []string{"alert:wmata/42"}
+		if !reflect.DeepEqual(sink.keys(), want) {
+			t.Fatalf("expected %v but got %v", want, sink.keys())
+		}
+
+		if sink.sent[0].Title != "Delay on OR" {
+			t.Errorf("expected the title to name the line but got %q", sink.sent[0].Title)
+		}
+	})
+
+	t.Run("a refused notification is retried", func(t *testing.T) {
+		p := &stubProvider{set: orange, alerts: transit.AlertSet{Alerts: []transit.Alert{delay}}}
+		sink := &recordingSink{err: errors.New("no display")}
+		n := newTestNotifier(t, p, sink)
+
+		if err := n.poll(t.Context()); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		sink.err = nil
+		if err := n.poll(t.Context()); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		if len(sink.sent) != 1 {
+			t.Errorf("expected the alert on the second poll but got %v", sink.keys())
+		}
+	})
+
+	t.Run("losing and regaining departures on the watched lines", func(t *testing.T) {
+		p := &stubProvider{set: orange}
+		sink := &recordingSink{}
+		n := newTestNotifier(t, p, sink)
+
+		// Only Silver Line trains left, which isn't what's watched. Two empty polls are
+		// a gap between trains, it takes noServiceAfterPolls in a row to be an outage.
+		for _, set := range []transit.DepartureSet{orange, silver, silver, orange, silver, silver, silver, silver, orange} {
+			p.set = set
+			if err := n.poll(t.Context()); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+		}
+
+		var kinds []notify.Kind
+		for _, msg := range sink.sent {
+			kinds = append(kinds, msg.Kind)
+		}
+
+		want := []notify.Kind{notify.KindNoService, notify.KindResumed}
+		if !reflect.DeepEqual(kinds, want) {
+			t.Errorf("expected %v but got %v", want, kinds)
+		}
+	})
+
+	t.Run("an outage and its recovery survive a restart", func(t *testing.T) {
+		p := &stubProvider{set: orange}
+		sink := &recordingSink{}
+		n := newTestNotifier(t, p, sink)
+
+		// Each poll is a fresh notifier on the same store, the way --once runs from cron.
+		for _, set := range []transit.DepartureSet{silver, silver, silver, orange, orange, silver, silver, silver} {
+			p.set = set
+			restarted := &notifier{app: n.app, location: n.location, provider: p, lines: n.lines, targets: n.targets, sink: sink}
+			if err := restarted.poll(t.Context()); err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+		}
+
+		var kinds []notify.Kind
+		for _, msg := range sink.sent {
+			kinds = append(kinds, msg.Kind)
+		}
+
+		want := []notify.Kind{notify.KindNoService, notify.KindResumed, notify.KindNoService}
+		if !reflect.DeepEqual(kinds, want) {
+			t.Errorf("expected %v but got %v", want, kinds)
+		}
+	})
+}
+
+func TestNotifyInterval(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		seconds  int
+		location transit.LocationSlug
+		expected time.Duration
+		wantErr  bool
+	}{
+		"set":                   {seconds: 30, location: transit.SFSlug, expected: 30 * time.Second},
+		"unset":                 {location: transit.DMVSlug, expected: defaultNotifyInterval},
+		"unset in sf":           {location: transit.SFSlug, expected: sfNotifyInterval},
+		"negative is a mistake": {seconds: -1, location: transit.DMVSlug, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := notifyInterval(tc.seconds, tc.location)
+			if tc.wantErr {
+				if !errors.Is(err, config.ErrInvalid) {
+					t.Errorf("expected %v but got %v", config.ErrInvalid, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if got != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	t.Parallel()
+
+	got := splitList(" OR, SV,,")
+	want := []string{"OR", "SV"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v but got %v", want, got)
+	}
+}
@@ -27,7 +27,8 @@ func (a *App) newRootCmd() *cobra.Command {
 		// Root takes no positional args, so anything here is an unrecognised
 		// command. Cobra would say the same thing implicitly via legacyArgs;
 		// stating it lets the failure carry errUsage.
-		Args: usageArgs(cobra.NoArgs),
+		Args:              usageArgs(cobra.NoArgs),
+		PersistentPreRunE: a.rootPreRun,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if versionFlag {
 				a.executeVersion()
@@ -49,7 +50,13 @@ func (a *App) newRootCmd() *cobra.Command {
 
 	// Global, persistent flags
 	rootCmd.PersistentFlags().StringVarP(&a.configOverride, "config", "c", "", "config file (defaults to $HOME/.config/transit/config.yml)")
+	rootCmd.PersistentFlags().StringVarP(&a.profile, "profile", "p", "", "config profile layered over the base config (defaults to $TRANSIT_PROFILE)")
+	rootCmd.PersistentFlags().StringVarP(&a.locationOverride, "location", "l", "", "location to use for this run (defaults to core.location)")
 	rootCmd.PersistentFlags().BoolVarP(&a.verbose, "verbose", "v", false, "turn on verbose logging")
+	rootCmd.PersistentFlags().StringVar(&a.logFormat, "log-format", "text", "format of --verbose logs, text or json")
+	rootCmd.PersistentFlags().BoolVar(&a.noCache, "no-cache", false, "skip the response cache and always ask upstream")
+	rootCmd.PersistentFlags().StringVar(&a.recordDir, "record", "", "save every upstream exchange to a cassette in this directory, secrets scrubbed")
+	rootCmd.PersistentFlags().StringVar(&a.replayDir, "replay", "", "answer upstream requests from the cassette in this directory instead of the network")
 
 	// Local to root flags
 	rootCmd.Flags().BoolVarP(&versionFlag, "version", "V", false, "print installed version number")
@@ -58,8 +65,14 @@ func (a *App) newRootCmd() *cobra.Command {
 	rootCmd.AddCommand(
 		a.newAtCmd(),
 		a.newConfigCmd(),
+		a.newDoctorCmd(),
 		a.newIncidentsCmd(),
 		a.newInitCmd(),
+		a.newNotifyCmd(),
+		a.newStatsCmd(),
+		a.newTripCmd(),
+		a.newFareCmd(),
+		a.newLineCmd(),
 	)
 
 	return rootCmd
@@ -90,6 +103,7 @@ func cancelled(err error) bool {
 // exitCode maps a caught error to one of the documented exit codes.
 func exitCode(err error) int {
 	var httpErr *provider.HTTPError
+	var rateErr *provider.RateLimitError
 
 	switch {
 	case err == nil:
@@ -102,7 +116,7 @@ func exitCode(err error) int {
 		errors.Is(err, ui.ErrNoInput),
 		errors.Is(err, config.ErrInvalid):
 		return 2 // Usage or configuration error
-	case errors.As(err, &httpErr):
+	case errors.As(err, &httpErr), errors.As(err, &rateErr):
 		return 3 // Network or upstream error
 	case errors.Is(err, transit.ErrNoDepartures):
 		return 4 // Request was successful but there's nothing to show
@@ -80,6 +80,10 @@ func TestExitCode(t *testing.T) {
 			err:  fmt.Errorf("fetch incidents: %w", &provider.HTTPError{StatusCode: http.StatusServiceUnavailable}),
 			want: 3,
 		},
+		"rate limited": {
+			err:  fmt.Errorf("fetch incidents: %w", &provider.RateLimitError{Host: "api.511.org", Upstream: true}),
+			want: 3,
+		},
 		"no departures anywhere": {
 			err:  fmt.Errorf("at: %w", transit.ErrNoDepartures),
 			want: 4,
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/stats"
+	"github.com/ismailshak/transit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+func (a *App) newStatsCmd() *cobra.Command {
+	var sinceFlag string
+
+	statsCmd := &cobra.Command{
+		Use:   "stats <station>",
+		Short: "Show how reliable departure predictions have been at a station",
+		Long: fmt.Sprintf(`
+Summarize the departures logged at a station: the time between trains,
+how far predictions drifted as trains approached, and how often a train
+vanished from the board before arriving (a ghost train).
+
+Nothing is logged unless stats.record_predictions is on, then every
+departure transit at fetches is kept. Leaving transit at --watch running
+gives the best picture, trains are followed from one refresh to the next.
+Departures are kept for %d days.
+
+--since takes a number of days (7d), a duration (12h) or a date (2026-10-01).
+	`, config.KeepPredictionsFor/(24*time.Hour)),
+		Example: "  transit config set stats.record_predictions true\n  transit stats \"metro center\" --since 7d",
+		Args:    usageArgs(cobra.ExactArgs(1)),
+		PreRunE: a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, err := parseSince(sinceFlag, a.Now())
+			if err != nil {
+				return err
+			}
+
+			return a.executeStats(cmd.Context(), args[0], since)
+		},
+	}
+
+	statsCmd.Flags().StringVar(&sinceFlag, "since", "7d", "how far back to look")
+
+	return statsCmd
+}
+
+func (a *App) executeStats(ctx context.Context, arg string, since time.Time) error {
+	targets, err := a.resolveStops(ctx, []string{arg})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		stopIDs := make([]string, 0, len(t.refs))
+		for _, ref := range t.refs {
+			stopIDs = append(stopIDs, ref.StopID)
+		}
+
+		predictions, err := a.Store.Predictions(ctx, t.location, stopIDs, since)
+		if err != nil {
+			return fmt.Errorf("read predictions: %w", err)
+		}
+
+		if err := a.printStats(targetName(t), stats.Analyze(predictions), since); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *App) printStats(name string, report stats.Report, since time.Time) error {
+	if report.Fetches == 0 {
+		hint := ""
+		if !a.Cfg.Stats.RecordPredictions {
+			hint = ", turn on stats.record_predictions to start logging"
+		}
+
+		_, err := fmt.Fprintf(a.Out, "No departures logged at %s since %s%s\n", name, since.Format(tui.DateFormat), hint)
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.Out, "%s\n%d fetches from %s to %s\n\n", tui.Bold(name), report.Fetches,
+		report.From.In(time.Local).Format(tui.DateFormat), report.To.In(time.Local).Format(tui.DateFormat))
+
+	w := tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "LINE\tTOWARD\tARRIVED\tMEDIAN HEADWAY\tMAX HEADWAY\tGHOSTS")
+	for _, l := range report.Lines {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%d (%.0f%%)\n", l.Line, l.Headsign, l.Arrived,
+			formatHeadway(l.MedianHeadway), formatHeadway(l.MaxHeadway), l.Ghosts, 100*l.GhostRate())
+	}
+
+	header := []string{"LINE", "TOWARD"}
+	for _, b := range stats.Buckets {
+		header = append(header, strings.ToUpper(b.Label))
+	}
+
+	_, _ = fmt.Fprintln(w, "\nPREDICTION DRIFT, AVERAGE MISS (LATE +, EARLY -)")
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, l := range report.Lines {
+		row := []string{l.Line, l.Headsign}
+		for _, d := range l.Drift {
+			row = append(row, formatDrift(d))
+		}
+
+		_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(a.Out)
+	return err
+}
+
+func formatHeadway(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+
+	return formatObserved(d)
+}
+
+// formatDrift shows the size of the average miss and which way it leaned, e.g. "1m30s (+1m)".
+func formatDrift(d stats.Drift) string {
+	if d.Samples == 0 {
+		return "-"
+	}
+
+	sign := "+"
+	if d.MeanBias < 0 {
+		sign = "-"
+	}
+
+	return fmt.Sprintf("%s (%s%s)", d.MeanAbs.Round(time.Second), sign, d.MeanBias.Abs().Round(time.Second))
+}
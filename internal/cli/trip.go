@@ -0,0 +1,416 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/ismailshak/transit/internal/plan"
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/ismailshak/transit/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// maxTripOptions is how many ways to make a trip are shown.
+	maxTripOptions = 3
+
+	// transferTime is how long changing trains is allowed to take. A connection that
+	// leaves sooner than this after the first train gets in is assumed missed.
+	transferTime = 2 * time.Minute
+
+	// maxStopCandidates is how many stations an ambiguous match lists.
+	maxStopCandidates = 5
+
+	tripTimeFormat = "3:04pm"
+)
+
+func (a *App) newTripCmd() *cobra.Command {
+	tripCmd := &cobra.Command{
+		Use:   "trip <from> <to>",
+		Short: "Plan a ride between two stations",
+		Long: `
+Find the ways to ride from one station to another, directly or with one
+change of trains, and when the next trains would get you there.
+
+Routes come from the schedule stored by transit init. A location seeded
+before schedules were stored has none, run transit init --refresh to
+download it. Times come from the live departure boards of
+the stations you'd board at. A leg with no train predicted yet shows only
+how long its ride is scheduled to take.
+
+Stations are matched like transit at, both have to be in the same location.
+	`,
+		Example: "  transit trip vienna \"union station\"\n  transit trip courth metro",
+		Args:    usageArgs(cobra.ExactArgs(2)),
+		PreRunE: a.defaultPreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.executeTrip(cmd.Context(), args[0], args[1])
+		},
+	}
+
+	return tripCmd
+}
+
+// tripStations is what a trip is planned over: where every stop is, and the
+// stations themselves.
+type tripStations struct {
+	parents  map[string]string // Stop ID to the station it's in. A station is its own.
+	stations map[string]transit.Stop
+}
+
+func (s tripStations) station(stopID string) string {
+	if parent, ok := s.parents[stopID]; ok {
+		return parent
+	}
+
+	return stopID
+}
+
+func (s tripStations) name(station string) string {
+	if stop, ok := s.stations[station]; ok {
+		return stop.Name
+	}
+
+	return station
+}
+
+func (a *App) executeTrip(ctx context.Context, fromArg, toArg string) error {
+	location, from, to, err := a.resolveTrip(ctx, fromArg, toArg)
+	if err != nil {
+		return err
+	}
+
+	if from.StopID == to.StopID {
+		return fmt.Errorf("%w: %q and %q are both %s", errUsage, fromArg, toArg, from.Name)
+	}
+
+	patterns, err := a.Store.Patterns(ctx, location)
+	if err != nil {
+		return fmt.Errorf("read schedule: %w", err)
+	}
+
+	if len(patterns) == 0 {
+		return fmt.Errorf("no schedule stored for %s, run transit init --refresh --location %s to download it", location, location)
+	}
+
+	stations, err := a.tripStations(ctx, location)
+	if err != nil {
+		return err
+	}
+
+	options := plan.Find(patterns, stations.station, from.StopID, to.StopID)
+	if len(options) == 0 {
+		return fmt.Errorf("no ride from %s to %s with at most one change", from.Name, to.Name)
+	}
+
+	options = options[:min(len(options), maxTripOptions)]
+
+	boards, err := a.tripBoards(ctx, location, stations, options)
+	if err != nil {
+		return err
+	}
+
+	zones, err := a.agencyZones(ctx, location)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.Out, "%s\n\n", tui.Bold(from.Name+" to "+to.Name))
+
+	for n, option := range options {
+		legs := timeOption(option, boards, stations, a.Now())
+		if err := a.printTripOption(n+1, option, legs, stations, zones); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveTrip finds the two stations in the first location, in search order, that
+// has a match for both. A station that matches more than one is a usage error
+// naming the candidates, rather than a guess at which was meant.
+func (a *App) resolveTrip(ctx context.Context, fromArg, toArg string) (transit.LocationSlug, transit.Stop, transit.Stop, error) {
+	locations, err := a.searchLocations(ctx)
+	if err != nil {
+		return "", transit.Stop{}, transit.Stop{}, err
+	}
+
+	for _, slug := range locations {
+		from, err := a.Store.MatchStops(ctx, slug, fromArg)
+		if err != nil {
+			return "", transit.Stop{}, transit.Stop{}, fmt.Errorf("resolve %q: %w", fromArg, err)
+		}
+
+		to, err := a.Store.MatchStops(ctx, slug, toArg)
+		if err != nil {
+			return "", transit.Stop{}, transit.Stop{}, fmt.Errorf("resolve %q: %w", toArg, err)
+		}
+
+		if len(from) == 0 || len(to) == 0 {
+			continue
+		}
+
+		fromStop, err := uniqueStop(fromArg, from)
+		if err != nil {
+			return "", transit.Stop{}, transit.Stop{}, err
+		}
+
+		toStop, err := uniqueStop(toArg, to)
+		if err != nil {
+			return "", transit.Stop{}, transit.Stop{}, err
+		}
+
+		return slug, fromStop, toStop, nil
+	}
+
+	return "", transit.Stop{}, transit.Stop{}, fmt.Errorf("%w: no location has stations matching both %q and %q", errUsage, fromArg, toArg)
+}
+
+// uniqueStop picks the station arg names out of its matches. A match whose name is
+// arg wins over the rest, otherwise there has to be only one.
+func uniqueStop(arg string, matches []transit.Stop) (transit.Stop, error) {
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+
+	var exact []transit.Stop
+	for _, m := range matches {
+		if strings.EqualFold(m.Name, arg) {
+			exact = append(exact, m)
+		}
+	}
+
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	if len(exact) > 1 {
+		matches = exact
+	}
+
+	candidates := make([]string, 0, min(len(matches), maxStopCandidates))
+	for _, m := range matches[:min(len(matches), maxStopCandidates)] {
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", m.Name, m.StopID))
+	}
+
+	if len(matches) > maxStopCandidates {
+		candidates = append(candidates, fmt.Sprintf("and %d more", len(matches)-maxStopCandidates))
+	}
+
+	return transit.Stop{}, fmt.Errorf("%w: %q matches more than one station: %s", errUsage, arg, strings.Join(candidates, ", "))
+}
+
+func (a *App) tripStations(ctx context.Context, location transit.LocationSlug) (tripStations, error) {
+	stops, err := a.Store.StopsByLocation(ctx, location, false)
+	if err != nil {
+		return tripStations{}, fmt.Errorf("look up stations: %w", err)
+	}
+
+	s := tripStations{
+		parents:  make(map[string]string, len(stops)),
+		stations: make(map[string]transit.Stop),
+	}
+
+	for _, stop := range stops {
+		if stop.ParentID == "" || stop.ParentID == stop.StopID {
+			s.stations[stop.StopID] = stop
+			continue
+		}
+
+		s.parents[stop.StopID] = stop.ParentID
+	}
+
+	return s, nil
+}
+
+// tripBoards fetches the departures of every station an option boards at, keyed by
+// station. A board that couldn't be fetched is warned about and left out, its legs
+// fall back to the schedule.
+func (a *App) tripBoards(ctx context.Context, location transit.LocationSlug, stations tripStations, options []plan.Option) (map[string][]transit.Departure, error) {
+	p, err := a.providerFor(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+
+	var boarding []string
+	var targets []target
+	for _, option := range options {
+		for _, leg := range option.Legs {
+			stop, ok := stations.stations[leg.From]
+			if !ok || slices.Contains(boarding, leg.From) {
+				continue
+			}
+
+			boarding = append(boarding, leg.From)
+			targets = append(targets, target{arg: stop.Name, location: location, provider: p, refs: p.StopRefs(stop)})
+		}
+	}
+
+	boards := make(map[string][]transit.Departure, len(targets))
+	for n, r := range fetchTargets(ctx, targets) {
+		if errors.Is(r.err, transit.ErrNoDepartures) {
+			continue
+		}
+
+		if r.err != nil {
+			if cancelled(r.err) {
+				return nil, r.err
+			}
+
+			a.warnf("fetch departures for %s: %v", targets[n].arg, r.err)
+			continue
+		}
+
+		a.recordPredictions(ctx, targets[n], r.set.Departures)
+		boards[boarding[n]] = r.set.Departures
+	}
+
+	return boards, nil
+}
+
+// timedLeg is a leg of an option with the train that makes it, if one's predicted.
+type timedLeg struct {
+	plan.Leg
+	train   *transit.Departure
+	leaves  time.Time // Zero without a train.
+	arrives time.Time
+}
+
+// timeOption puts the first train that can be taken on each leg of an option, in
+// order. Once a leg has no train, the ones after it have none either: there's no
+// knowing when they'd be boarded.
+func timeOption(option plan.Option, boards map[string][]transit.Departure, stations tripStations, now time.Time) []timedLeg {
+	legs := make([]timedLeg, len(option.Legs))
+	earliest := now
+	for n, leg := range option.Legs {
+		legs[n].Leg = leg
+		if earliest.IsZero() {
+			continue
+		}
+
+		var train *transit.Departure
+		for i, d := range boards[leg.From] {
+			if d.Arrives.Before(earliest) || !takes(d, leg, stations) {
+				continue
+			}
+
+			if train == nil || d.Arrives.Before(train.Arrives) {
+				train = &boards[leg.From][i]
+			}
+		}
+
+		if train == nil {
+			earliest = time.Time{}
+			continue
+		}
+
+		legs[n].train = train
+		legs[n].leaves = train.Arrives
+		legs[n].arrives = train.Arrives.Add(leg.Ride)
+		earliest = legs[n].arrives.Add(transferTime)
+	}
+
+	return legs
+}
+
+// takes reports whether a departure runs the leg: it's on the leg's line and headed
+// for one of the places the leg's trains end up.
+func takes(d transit.Departure, leg plan.Leg, stations tripStations) bool {
+	if d.Line != "" && !strings.EqualFold(d.Line, leg.Line) && !strings.EqualFold(d.Line, leg.RouteID) {
+		return false
+	}
+
+	headsign := squash(d.Headsign)
+	if headsign == "" {
+		return false
+	}
+
+	for _, h := range leg.Headsigns {
+		if squash(h) == headsign {
+			return true
+		}
+	}
+
+	// Boards often shorten a terminal's name, "Largo" for "Downtown Largo".
+	for _, terminal := range leg.Terminals {
+		name := squash(stations.name(terminal))
+		if name != "" && (strings.Contains(name, headsign) || strings.Contains(headsign, name)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// squash lower cases s and drops everything but letters and digits, so names written
+// slightly differently compare equal.
+func squash(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+
+		return -1
+	}, s)
+}
+
+func (a *App) printTripOption(n int, option plan.Option, legs []timedLeg, stations tripStations, zones map[string]*time.Location) error {
+	lines := make([]string, len(option.Legs))
+	for i, leg := range option.Legs {
+		lines[i] = leg.Line
+	}
+
+	heading := fmt.Sprintf("%d. %s, %s riding", n, strings.Join(lines, " then "), formatObserved(option.Ride()))
+	if via := option.Transfer(); via != "" {
+		heading += ", change at " + stations.name(via)
+	}
+
+	_, _ = fmt.Fprintln(a.Out, heading)
+
+	w := tabwriter.NewWriter(a.Out, 0, 0, 2, ' ', 0)
+	for _, leg := range legs {
+		toward := leg.towardName(stations)
+		if leg.train == nil {
+			_, _ = fmt.Fprintf(w, "   leave %s\t-\t%s toward %s, no train predicted yet\n", stations.name(leg.From), leg.Line, toward)
+			_, _ = fmt.Fprintf(w, "   arrive %s\t-\t%s later\n", stations.name(leg.To), formatObserved(leg.Ride))
+			continue
+		}
+
+		zone := zones[leg.train.AgencyID]
+		if zone == nil {
+			zone = time.Local
+		}
+
+		_, _ = fmt.Fprintf(w, "   leave %s\t%s\t%s toward %s\n", stations.name(leg.From), leg.leaves.In(zone).Format(tripTimeFormat), leg.Line, toward)
+		_, _ = fmt.Fprintf(w, "   arrive %s\t%s\t\n", stations.name(leg.To), leg.arrives.In(zone).Format(tripTimeFormat))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(a.Out)
+	return err
+}
+
+// towardName is where the leg's train is headed, as its board shows it, or as the
+// schedule has it without one.
+func (l timedLeg) towardName(stations tripStations) string {
+	switch {
+	case l.train != nil && l.train.Headsign != "":
+		return l.train.Headsign
+	case len(l.Headsigns) > 0:
+		return l.Headsigns[0]
+	case len(l.Terminals) > 0:
+		return stations.name(l.Terminals[0])
+	default:
+		return stations.name(l.To)
+	}
+}
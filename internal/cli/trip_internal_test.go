@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/plan"
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+var tripNetwork = tripStations{
+	parents: map[string]string{"PF_A01": "STN_A01", "PF_B01": "STN_B01", "PF_C01": "STN_C01"},
+	stations: map[string]transit.Stop{
+		"STN_A01": {StopID: "STN_A01", Name: "Metro Center"},
+		"STN_B01": {StopID: "STN_B01", Name: "Gallery Place"},
+		"STN_C01": {StopID: "STN_C01", Name: "Downtown Largo"},
+		"STN_D01": {StopID: "STN_D01", Name: "Union Station"},
+	},
+}
+
+func TestUniqueStop(t *testing.T) {
+	t.Parallel()
+
+	metro := transit.Stop{StopID: "A01", Name: "Metro Center"}
+	gallery := transit.Stop{StopID: "B01", Name: "Gallery Place"}
+	union := transit.Stop{StopID: "B03", Name: "Union Station"}
+
+	tests := map[string]struct {
+		arg     string
+		matches []transit.Stop
+		want    transit.Stop
+		wantErr string
+	}{
+		"one match":                 {arg: "metro", matches: []transit.Stop{metro}, want: metro},
+		"the exact name wins":       {arg: "metro center", matches: []transit.Stop{gallery, metro}, want: metro},
+		"several matches are named": {arg: "a", matches: []transit.Stop{metro, gallery, union}, wantErr: `"a" matches more than one station: Metro Center (A01), Gallery Place (B01), Union Station (B03)`},
+		"a long list is cut short": {
+			arg:     "a",
+			matches: []transit.Stop{metro, gallery, union, metro, gallery, union, metro},
+			wantErr: "Gallery Place (B01), and 2 more",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := uniqueStop(tc.arg, tc.matches)
+			if tc.wantErr != "" {
+				if !errors.Is(err, errUsage) || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("expected a usage error containing %q but got %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTakes(t *testing.T) {
+	t.Parallel()
+
+	leg := plan.Leg{RouteID: "BLUE", Line: "BL", Terminals: []string{"STN_C01"}, Headsigns: []string{"Largo Town Center"}}
+
+	tests := map[string]struct {
+		departure transit.Departure
+		want      bool
+	}{
+		"the scheduled headsign":            {departure: transit.Departure{Line: "BL", Headsign: "Largo Town Center"}, want: true},
+		"a shortened terminal name":         {departure: transit.Departure{Line: "BL", Headsign: "Largo"}, want: true},
+		"the route ID as the line":          {departure: transit.Departure{Line: "blue", Headsign: "Largo"}, want: true},
+		"another line to the same terminal": {departure: transit.Departure{Line: "SV", Headsign: "Largo"}},
+		"the other way":                     {departure: transit.Departure{Line: "BL", Headsign: "Franconia-Springfield"}},
+		"no headsign":                       {departure: transit.Departure{Line: "BL"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := takes(tc.departure, leg, tripNetwork); got != tc.want {
+				t.Errorf("expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTimeOption(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	red := plan.Leg{Line: "RD", From: "STN_A01", To: "STN_B01", Ride: 2 * time.Minute, Terminals: []string{"STN_D01"}}
+	blue := plan.Leg{Line: "BL", From: "STN_B01", To: "STN_C01", Ride: 20 * time.Minute, Terminals: []string{"STN_C01"}}
+	option := plan.Option{Legs: []plan.Leg{red, blue}}
+
+	t.Run("each leg takes the first train it can make", func(t *testing.T) {
+		t.Parallel()
+
+		boards := map[string][]transit.Departure{
+			"STN_A01": {
+				{Line: "RD", Headsign: "Union Station", Arrives: now.Add(6 * time.Minute)},
+				{Line: "RD", Headsign: "Union Station", Arrives: now.Add(3 * time.Minute)},
+				{Line: "RD", Headsign: "Shady Grove", Arrives: now.Add(time.Minute)},
+			},
+			"STN_B01": {
+				{Line: "BL", Headsign: "Largo", Arrives: now.Add(6 * time.Minute)}, // Too soon after the red gets in.
+				{Line: "BL", Headsign: "Largo", Arrives: now.Add(8 * time.Minute)},
+			},
+		}
+
+		legs := timeOption(option, boards, tripNetwork, now)
+
+		if want := now.Add(3 * time.Minute); !legs[0].leaves.Equal(want) {
+			t.Errorf("expected the red leg to leave at %v but got %v", want, legs[0].leaves)
+		}
+
+		if want := now.Add(8 * time.Minute); !legs[1].leaves.Equal(want) {
+			t.Errorf("expected the blue leg to leave at %v but got %v", want, legs[1].leaves)
+		}
+
+		if want := now.Add(28 * time.Minute); !legs[1].arrives.Equal(want) {
+			t.Errorf("expected to arrive at %v but got %v", want, legs[1].arrives)
+		}
+	})
+
+	t.Run("no train on the first leg leaves the rest untimed", func(t *testing.T) {
+		t.Parallel()
+
+		boards := map[string][]transit.Departure{
+			"STN_B01": {{Line: "BL", Headsign: "Largo", Arrives: now.Add(8 * time.Minute)}},
+		}
+
+		for n, leg := range timeOption(option, boards, tripNetwork, now) {
+			if leg.train != nil {
+				t.Errorf("expected leg %d to have no train but got %+v", n, leg.train)
+			}
+		}
+	})
+}
@@ -9,44 +9,91 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // DmvConfig holds options for the `dmv` section of a user config file.
 type DmvConfig struct {
+	// The key itself or a `keyring:`/`cmd:` reference to it. Read it through [Config.APIKey].
 	APIKey string `mapstructure:"api_key"`
+	// A command whose output is the key, e.g. `pass show wmata`.
+	APIKeyCmd string `mapstructure:"api_key_cmd"`
 }
 
 // SFConfig holds options for the `sf` section of a user config file.
 type SFConfig struct {
+	// The key itself or a `keyring:`/`cmd:` reference to it. Read it through [Config.APIKey].
 	APIKey string `mapstructure:"api_key"`
+	// A command whose output is the key, e.g. `pass show 511`.
+	APIKeyCmd string `mapstructure:"api_key_cmd"`
+	// How many requests are in flight at once.
+	Concurrency int `mapstructure:"concurrency"`
 }
 
 // CoreConfig holds options for the `core` section of a user config file.
 type CoreConfig struct {
 	Location      string `mapstructure:"location"`
 	WatchInterval int    `mapstructure:"watch_interval"`
+	Language      string `mapstructure:"language"`
+}
+
+// NotifyConfig holds options for the `notify` section of a user config file.
+type NotifyConfig struct {
+	Lines      string `mapstructure:"lines"` // Comma separated.
+	Stops      string `mapstructure:"stops"` // Comma separated.
+	Sink       string `mapstructure:"sink"`
+	Command    string `mapstructure:"command"`
+	WebhookURL string `mapstructure:"webhook_url"`
+	Interval   int    `mapstructure:"interval"`
+}
+
+// KeepPredictionsFor is how long the departures `at` records are kept for
+// `transit stats`. It looks back a week by default, a month leaves room to compare.
+const KeepPredictionsFor = 30 * 24 * time.Hour
+
+// StatsConfig holds options for the `stats` section of a user config file.
+type StatsConfig struct {
+	// Whether `at` keeps the departures it fetches. Off unless asked for, every fetch
+	// adds a row per departure until they're dropped after [KeepPredictionsFor].
+	RecordPredictions bool `mapstructure:"record_predictions"`
 }
 
 type Config struct {
-	Core CoreConfig `mapstructure:"core"`
-	DMV  DmvConfig  `mapstructure:"dmv"`
-	SF   SFConfig   `mapstructure:"sf"`
+	Core   CoreConfig   `mapstructure:"core"`
+	DMV    DmvConfig    `mapstructure:"dmv"`
+	SF     SFConfig     `mapstructure:"sf"`
+	Stats  StatsConfig  `mapstructure:"stats"`
+	Notify NotifyConfig `mapstructure:"notify"`
 
 	// The file these values were decoded from. Kept so that Get and Set can
 	// address keys by a runtime string, which a struct can't do.
 	vp *viper.Viper
+
+	// The profile layered over the base section. Empty means none.
+	profile string
 }
 
 // Load reads the config file and decodes it into a Config. An override path is
 // used as-is when non-empty, otherwise the default config directory is used and
 // an empty config file is created there if none exists yet.
-func Load(override string) (*Config, error) {
+//
+// A non-empty profile (or TRANSIT_PROFILE when it's empty) layers the keys under
+// `profiles.<name>` over the base section. A profile the file doesn't have wraps
+// [ErrInvalid].
+func Load(override, profile string) (*Config, error) {
+	if profile == "" {
+		profile = os.Getenv(profileEnvVar)
+	}
+
 	vp := viper.New()
-	c := &Config{vp: vp}
+	c := &Config{vp: vp, profile: profile}
 
 	c.setDefaults()
+	c.bindEnv()
 
 	if override != "" {
 		vp.SetConfigFile(override)
@@ -80,6 +127,12 @@ func Load(override string) (*Config, error) {
 	return c, nil
 }
 
+// profilesKey is the section of the file that holds named profiles.
+const profilesKey = "profiles"
+
+// profileEnvVar selects a profile when --profile isn't passed.
+const profileEnvVar = envPrefix + "_PROFILE"
+
 // Get returns a config value looked up by a key from user input, or nil if the
 // key isn't set. Nested fields are addressable by using a dot (.) as a
 // delimiter e.g. `core.location`.
@@ -89,18 +142,131 @@ func (c *Config) Get(key string) any {
 
 // Set writes a config value from user input back to the file and re-decodes it,
 // so the file and this Config agree once it returns. Nested fields are
-// addressable by using a dot (.) as a delimiter e.g. `core.location`.
+// addressable by using a dot (.) as a delimiter e.g. `core.location`. A key
+// missing from [Schema] or a value it rejects wraps [ErrInvalid]. With a profile
+// active the value is written to that profile rather than the base section.
 func (c *Config) Set(key, value string) error {
-	c.vp.Set(key, value)
-	if err := c.vp.WriteConfig(); err != nil {
+	field, ok := Lookup(key)
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalid, key)
+	}
+
+	typed, err := field.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	// Written from a file-only view so that env vars and defaults never land in the file.
+	file, err := c.fileOnly()
+	if err != nil {
+		return err
+	}
+
+	file.Set(c.fileKey(field.Key), typed)
+	if err := file.WriteConfig(); err != nil {
 		return fmt.Errorf("write config %s: %w", c.vp.ConfigFileUsed(), err)
 	}
 
-	if err := c.vp.Unmarshal(c); err != nil {
-		return fmt.Errorf("decode config after write: %w", err)
+	return c.reload()
+}
+
+// Unset removes a key from the file and re-decodes it. Unknown keys can be
+// removed too, that's how a typo gets cleaned up. A key the file doesn't set
+// wraps [ErrInvalid]. With a profile active the key is removed from that profile.
+func (c *Config) Unset(key string) error {
+	file, err := c.fileOnly()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	key = c.fileKey(strings.ToLower(key))
+	if !file.IsSet(key) {
+		return fmt.Errorf("%w: %q is not set in %s", ErrInvalid, key, c.vp.ConfigFileUsed())
+	}
+
+	settings := file.AllSettings()
+	deleteNested(settings, strings.Split(key, "."))
+
+	// Viper can't remove a key, so the file is written from a copy that never had it.
+	out := viper.New()
+	out.SetConfigFile(c.vp.ConfigFileUsed())
+	if err := out.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("rebuild config: %w", err)
+	}
+
+	if err := out.WriteConfig(); err != nil {
+		return fmt.Errorf("write config %s: %w", c.vp.ConfigFileUsed(), err)
+	}
+
+	return c.reload()
+}
+
+// Settings returns the effective value of every field in [Schema] and where it
+// came from.
+func (c *Config) Settings() ([]Setting, error) {
+	file, err := c.fileOnly()
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]Setting, 0, len(Schema))
+	for _, f := range Schema {
+		settings = append(settings, Setting{
+			Field:  f,
+			Value:  c.vp.Get(f.Key),
+			Origin: c.origin(f, file),
+		})
+	}
+
+	return settings, nil
+}
+
+// Validate reports keys in the file that transit doesn't know and values that
+// [Schema] rejects, in the base section and in every profile. No problems is an
+// empty slice.
+func (c *Config) Validate() ([]Problem, error) {
+	file, err := c.fileOnly()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+
+	keys := file.AllKeys()
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		field, ok := Lookup(profileKey(key))
+		if !ok {
+			problems = append(problems, Problem{Key: key, Message: "unknown key"})
+			continue
+		}
+
+		if key == field.Key {
+			continue // The effective value is checked below, env vars included.
+		}
+
+		if _, err := field.parse(fmt.Sprint(file.Get(key))); err != nil {
+			problems = append(problems, Problem{Key: key, Message: err.Error()})
+		}
+	}
+
+	for _, f := range Schema {
+		if c.origin(f, file) == OriginUnset || c.origin(f, file) == OriginProfile {
+			continue
+		}
+
+		if _, err := f.parse(fmt.Sprint(c.vp.Get(f.Key))); err != nil {
+			problems = append(problems, Problem{Key: f.Key, Message: err.Error()})
+		}
+	}
+
+	return problems, nil
+}
+
+// Profile returns the active profile. Empty means none.
+func (c *Config) Profile() string {
+	return c.profile
 }
 
 // FileUsed returns the path to the config file these values were loaded from.
@@ -108,8 +274,73 @@ func (c *Config) FileUsed() string {
 	return c.vp.ConfigFileUsed()
 }
 
+func (c *Config) origin(f Field, file *viper.Viper) Origin {
+	if v, ok := os.LookupEnv(EnvVar(f.Key)); ok && v != "" {
+		return OriginEnv
+	}
+
+	if c.profile != "" && file.IsSet(c.fileKey(f.Key)) {
+		return OriginProfile
+	}
+
+	if file.IsSet(f.Key) {
+		return OriginFile
+	}
+
+	if f.Default != nil {
+		return OriginDefault
+	}
+
+	return OriginUnset
+}
+
+// fileOnly reads the config file again without the env vars and defaults layered
+// over it.
+func (c *Config) fileOnly() (*viper.Viper, error) {
+	file := viper.New()
+	file.SetConfigFile(c.vp.ConfigFileUsed())
+
+	if err := file.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read config %s: %w", c.vp.ConfigFileUsed(), err)
+	}
+
+	return file, nil
+}
+
+// reload decodes the file again from scratch, so a removed key doesn't linger
+// in the struct.
+func (c *Config) reload() error {
+	next := &Config{vp: viper.New(), profile: c.profile}
+	next.vp.SetConfigFile(c.vp.ConfigFileUsed())
+	next.setDefaults()
+	next.bindEnv()
+
+	if err := next.read(); err != nil {
+		return fmt.Errorf("decode config after write: %w", err)
+	}
+
+	*c = *next
+	return nil
+}
+
 func (c *Config) setDefaults() {
-	c.vp.SetDefault("core.watch_interval", 10)
+	for _, f := range Schema {
+		if f.Default != nil {
+			c.vp.SetDefault(f.Key, f.Default)
+		}
+	}
+}
+
+// bindEnv lets TRANSIT_<SECTION>_<KEY> override a key from the file. Unmarshal
+// only sees env vars for keys bound up front, so every field is bound by name.
+func (c *Config) bindEnv() {
+	c.vp.SetEnvPrefix(envPrefix)
+	c.vp.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	c.vp.AutomaticEnv()
+
+	for _, f := range Schema {
+		_ = c.vp.BindEnv(f.Key) // only errors without a key name
+	}
 }
 
 func (c *Config) read() error {
@@ -118,6 +349,10 @@ func (c *Config) read() error {
 		return err
 	}
 
+	if err = c.applyProfile(); err != nil {
+		return err
+	}
+
 	err = c.vp.Unmarshal(c)
 	if err != nil {
 		return err
@@ -126,6 +361,65 @@ func (c *Config) read() error {
 	return nil
 }
 
+// applyProfile merges the active profile over the base section. It's merged into
+// the file's layer rather than set as an override, so env vars still win over it.
+func (c *Config) applyProfile() error {
+	if c.profile == "" {
+		return nil
+	}
+
+	section := c.vp.Sub(profilesKey + "." + c.profile)
+	if section == nil {
+		return fmt.Errorf("unknown profile %q", c.profile)
+	}
+
+	return c.vp.MergeConfigMap(section.AllSettings())
+}
+
+// fileKey is where key lives in the file, which is under the active profile when there is one.
+func (c *Config) fileKey(key string) string {
+	if c.profile == "" {
+		return key
+	}
+
+	return profilesKey + "." + c.profile + "." + key
+}
+
+// profileKey strips the `profiles.<name>.` prefix from a file key. Any other key
+// is returned as it is.
+func profileKey(key string) string {
+	rest, ok := strings.CutPrefix(key, profilesKey+".")
+	if !ok {
+		return key
+	}
+
+	_, inner, ok := strings.Cut(rest, ".")
+	if !ok {
+		return key
+	}
+
+	return inner
+}
+
+// deleteNested removes the value at path from a tree of settings, along with any
+// section the removal leaves empty.
+func deleteNested(settings map[string]any, path []string) {
+	if len(path) == 1 {
+		delete(settings, path[0])
+		return
+	}
+
+	child, ok := settings[path[0]].(map[string]any)
+	if !ok {
+		return
+	}
+
+	deleteNested(child, path[1:])
+	if len(child) == 0 {
+		delete(settings, path[0])
+	}
+}
+
 // GetConfigDir returns the location of transit's config directory.
 func GetConfigDir() (string, error) {
 	return getDefaultConfigDir()
@@ -0,0 +1,284 @@
+package config_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ismailshak/transit/internal/config"
+)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		key   string
+		value string
+		err   error
+	}{
+		"a known key":               {key: "core.location", value: "dmv"},
+		"an int from user input":    {key: "core.watch_interval", value: "30"},
+		"a typo'd key":              {key: "core.loaction", value: "dmv", err: config.ErrInvalid},
+		"not an integer":            {key: "core.watch_interval", value: "soon", err: config.ErrInvalid},
+		"an integer out of range":   {key: "core.watch_interval", value: "0", err: config.ErrInvalid},
+		"an empty location":         {key: "core.location", value: "", err: config.ErrInvalid},
+		"keys are case insensitive": {key: "CORE.Location", value: "sf"},
+		"a language with a region":  {key: "core.language", value: "es-MX"},
+		"a language with a script":  {key: "core.language", value: "zh-Hant"},
+		"a language name":           {key: "core.language", value: "spanish", err: config.ErrInvalid},
+		"a known sink":              {key: "notify.sink", value: "webhook"},
+		"an unknown sink":           {key: "notify.sink", value: "pager", err: config.ErrInvalid},
+		"a bool":                    {key: "stats.record_predictions", value: "true"},
+		"not a bool":                {key: "stats.record_predictions", value: "sometimes", err: config.ErrInvalid},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := loadFrom(t, "")
+
+			err := cfg.Set(tc.key, tc.value)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected an error wrapping %v but got %v", tc.err, err)
+				}
+
+				if problems, _ := cfg.Validate(); len(problems) != 0 {
+					t.Errorf("expected a rejected value to stay out of the file but got %v", problems)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestSetDecodesTheStruct(t *testing.T) {
+	t.Parallel()
+
+	cfg := loadFrom(t, "")
+
+	if err := cfg.Set("core.watch_interval", "30"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if cfg.Core.WatchInterval != 30 {
+		t.Errorf("expected 30 but got %d", cfg.Core.WatchInterval)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	t.Parallel()
+
+	cfg := loadFrom(t, "core:\n  location: dmv\n  watch_interval: 30\nfoo:\n  bar: 1\n")
+
+	if err := cfg.Unset("core.watch_interval"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if cfg.Core.WatchInterval != 10 {
+		t.Errorf("expected the default 10 but got %d, the old value lingered", cfg.Core.WatchInterval)
+	}
+
+	if cfg.Core.Location != "dmv" {
+		t.Errorf("expected the sibling key to survive but got %q", cfg.Core.Location)
+	}
+
+	if err := cfg.Unset("foo.bar"); err != nil {
+		t.Fatalf("expected an unknown key to be removable but got %v", err)
+	}
+
+	if cfg.Get("foo") != nil {
+		t.Errorf("expected the emptied section to be removed but got %v", cfg.Get("foo"))
+	}
+
+	if err := cfg.Unset("sf.api_key"); !errors.Is(err, config.ErrInvalid) {
+		t.Errorf("expected an error wrapping %v for a key the file doesn't set but got %v", config.ErrInvalid, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cfg := loadFrom(t, "core:\n  location: dmv\n  watch_interval: -5\n  loaction: sf\nwmata:\n  api_key: abc\n")
+
+	problems, err := cfg.Validate()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+
+	expected := []string{
+		"core.loaction: unknown key",
+		"wmata.api_key: unknown key",
+		"core.watch_interval: must be greater than 0",
+	}
+
+	if !slices.Equal(expected, got) {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}
+
+func TestSettingsOrigin(t *testing.T) {
+	t.Setenv("TRANSIT_SF_API_KEY", "from-env")
+
+	cfg := loadFrom(t, "core:\n  location: dmv\n")
+
+	settings, err := cfg.Settings()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	expected := map[string]config.Origin{
+		"core.location":       config.OriginFile,
+		"core.watch_interval": config.OriginDefault,
+		"core.language":       config.OriginDefault,
+		"dmv.api_key":         config.OriginUnset,
+		"sf.api_key":          config.OriginEnv,
+	}
+
+	for _, s := range settings {
+		want, ok := expected[s.Key]
+		if !ok {
+			continue
+		}
+
+		if s.Origin != want {
+			t.Errorf("expected %s to come from %q but got %q", s.Key, want, s.Origin)
+		}
+	}
+}
+
+const profilesFile = `core:
+  location: dmv
+  watch_interval: 30
+profiles:
+  kiosk:
+    core:
+      location: sf
+  ci:
+    core:
+      watch_interval: 0
+`
+
+func TestLoadProfile(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		profile  string
+		location string
+		interval int
+	}{
+		"no profile is the base section":   {profile: "", location: "dmv", interval: 30},
+		"a profile overrides what it sets": {profile: "kiosk", location: "sf", interval: 30},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := loadProfile(t, profilesFile, tc.profile)
+
+			if cfg.Core.Location != tc.location {
+				t.Errorf("expected location %q but got %q", tc.location, cfg.Core.Location)
+			}
+
+			if cfg.Core.WatchInterval != tc.interval {
+				t.Errorf("expected watch interval %d but got %d", tc.interval, cfg.Core.WatchInterval)
+			}
+		})
+	}
+}
+
+func TestLoadUnknownProfile(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfig(t, profilesFile)
+
+	if _, err := config.Load(path, "laptop"); !errors.Is(err, config.ErrInvalid) {
+		t.Errorf("expected an error wrapping %v but got %v", config.ErrInvalid, err)
+	}
+}
+
+func TestProfileFromEnv(t *testing.T) {
+	t.Setenv("TRANSIT_PROFILE", "kiosk")
+
+	cfg := loadProfile(t, profilesFile, "")
+
+	if cfg.Profile() != "kiosk" || cfg.Core.Location != "sf" {
+		t.Errorf("expected the kiosk profile but got %q with location %q", cfg.Profile(), cfg.Core.Location)
+	}
+}
+
+func TestEnvWinsOverProfile(t *testing.T) {
+	t.Setenv("TRANSIT_CORE_LOCATION", "dmv")
+
+	cfg := loadProfile(t, profilesFile, "kiosk")
+
+	if cfg.Core.Location != "dmv" {
+		t.Errorf("expected the env var to win but got %q", cfg.Core.Location)
+	}
+}
+
+func TestSetWritesToTheActiveProfile(t *testing.T) {
+	t.Parallel()
+
+	cfg := loadProfile(t, profilesFile, "kiosk")
+
+	if err := cfg.Set("core.watch_interval", "5"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if got := cfg.Get("profiles.kiosk.core.watch_interval"); got != 5 {
+		t.Errorf("expected the profile to hold 5 but got %v", got)
+	}
+
+	base := loadProfile(t, profilesFile, "")
+	if base.Core.WatchInterval != 30 {
+		t.Errorf("expected the base section untouched but got %d", base.Core.WatchInterval)
+	}
+
+	settings, err := cfg.Settings()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for _, s := range settings {
+		if s.Key == "core.watch_interval" && s.Origin != config.OriginProfile {
+			t.Errorf("expected %q but got %q", config.OriginProfile, s.Origin)
+		}
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	t.Parallel()
+
+	cfg := loadProfile(t, profilesFile+"  laptop:\n    core:\n      loaction: sf\n", "")
+
+	problems, err := cfg.Validate()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+
+	expected := []string{
+		"profiles.ci.core.watch_interval: must be greater than 0",
+		"profiles.laptop.core.loaction: unknown key",
+	}
+
+	if !slices.Equal(expected, got) {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}
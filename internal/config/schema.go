@@ -0,0 +1,269 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the kind of value a config key holds.
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeBool   Type = "bool"
+)
+
+// Origin is where a key's effective value came from.
+type Origin string
+
+const (
+	OriginEnv     Origin = "env"     // A TRANSIT_* environment variable.
+	OriginProfile Origin = "profile" // The active profile's section of the config file.
+	OriginFile    Origin = "file"    // The config file.
+	OriginDefault Origin = "default" // The schema's default.
+	OriginUnset   Origin = "unset"   // Nowhere, the key has no value.
+)
+
+// Field describes one key transit knows about.
+type Field struct {
+	Key         string // Dot delimited path e.g. `core.location`.
+	Type        Type
+	Default     any    // Nil means the key has no default.
+	Description string // One line shown by `config list`.
+	Secret      bool   // Holds a credential, so it's masked wherever it's printed.
+
+	// check adds constraints on top of Type. Nil accepts any value of the type.
+	check func(value any) error
+}
+
+// Setting is a field's effective value and where it came from.
+type Setting struct {
+	Field
+	Value  any
+	Origin Origin
+}
+
+// Problem is something wrong with the config that [Config.Validate] found.
+type Problem struct {
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Key + ": " + p.Message
+}
+
+// Schema is every key transit reads, in the order `config list` prints them.
+var Schema = []Field{
+	{
+		Key:         "core.location",
+		Type:        TypeString,
+		Description: "Location used when --location isn't passed",
+		check:       notEmpty,
+	},
+	{
+		Key:         "core.watch_interval",
+		Type:        TypeInt,
+		Default:     10,
+		Description: "Seconds between refreshes in watch mode",
+		check:       positive,
+	},
+	{
+		Key:         "core.language",
+		Type:        TypeString,
+		Default:     "en",
+		Description: "Language alerts are shown in when the agency publishes it, e.g. es or zh-Hant",
+		check:       languageTag,
+	},
+	{
+		Key:         "dmv.api_key",
+		Type:        TypeString,
+		Description: "WMATA API key, or a keyring:/cmd: reference to it",
+		Secret:      true,
+	},
+	{
+		Key:         "dmv.api_key_cmd",
+		Type:        TypeString,
+		Description: "Command that prints the WMATA API key",
+	},
+	{
+		Key:         "sf.api_key",
+		Type:        TypeString,
+		Description: "511 API key, or a keyring:/cmd: reference to it",
+		Secret:      true,
+	},
+	{
+		Key:         "sf.api_key_cmd",
+		Type:        TypeString,
+		Description: "Command that prints the 511 API key",
+	},
+	{
+		Key:         "sf.concurrency",
+		Type:        TypeInt,
+		Default:     4,
+		Description: "Requests sent to 511 at once, 511 needs one per stop or agency",
+		check:       positive,
+	},
+	{
+		Key:         "stats.record_predictions",
+		Type:        TypeBool,
+		Default:     false,
+		Description: fmt.Sprintf("Keep every departure at and its watch mode fetch for %d days, for transit stats", KeepPredictionsFor/(24*time.Hour)),
+	},
+	{
+		Key:         "notify.lines",
+		Type:        TypeString,
+		Description: "Lines transit notify watches, comma separated, e.g. OR,SV",
+	},
+	{
+		Key:         "notify.stops",
+		Type:        TypeString,
+		Description: "Stations transit notify watches, comma separated, matched like `at` does",
+	},
+	{
+		Key:         "notify.sink",
+		Type:        TypeString,
+		Default:     "stdout",
+		Description: "Where notifications go: stdout, bell, command or webhook",
+		check:       oneOf("stdout", "bell", "command", "webhook"),
+	},
+	{
+		Key:         "notify.command",
+		Type:        TypeString,
+		Default:     "notify-send",
+		Description: "Command the command sink runs, the title and body are appended as arguments",
+	},
+	{
+		Key:         "notify.webhook_url",
+		Type:        TypeString,
+		Description: "URL the webhook sink POSTs each notification to as JSON",
+	},
+	{
+		Key:         "notify.interval",
+		Type:        TypeInt,
+		Description: "Seconds between checks in transit notify, unset picks one that suits the location",
+		check:       positive,
+	},
+}
+
+// Lookup returns the field for key. Keys are matched case insensitively, the
+// way the config file is read.
+func Lookup(key string) (Field, bool) {
+	key = strings.ToLower(key)
+	for _, f := range Schema {
+		if f.Key == key {
+			return f, true
+		}
+	}
+
+	return Field{}, false
+}
+
+// Parse converts raw user input into a value of the field's type and checks it.
+// The error wraps [ErrInvalid].
+func (f Field) Parse(raw string) (any, error) {
+	value, err := f.parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %w", ErrInvalid, f.Key, err)
+	}
+
+	return value, nil
+}
+
+// parse is Parse without the key in the error, for callers that print it alongside.
+func (f Field) parse(raw string) (any, error) {
+	var value any
+
+	switch f.Type {
+	case TypeInt:
+		i, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+
+		value = i
+	case TypeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+
+		value = b
+	default:
+		value = raw
+	}
+
+	if f.check != nil {
+		if err := f.check(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+func notEmpty(value any) error {
+	if value == "" {
+		return errors.New("must not be empty")
+	}
+
+	return nil
+}
+
+func positive(value any) error {
+	if i, ok := value.(int); ok && i <= 0 {
+		return errors.New("must be greater than 0")
+	}
+
+	return nil
+}
+
+func oneOf(allowed ...string) func(value any) error {
+	return func(value any) error {
+		if s, ok := value.(string); ok && !slices.Contains(allowed, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		}
+
+		return nil
+	}
+}
+
+// languageTag accepts the shape of a BCP 47 tag, a 2 or 3 letter language and optional
+// subtags. It doesn't check the language exists, an unknown one just never matches.
+func languageTag(value any) error {
+	tag, ok := value.(string)
+	if !ok {
+		return nil
+	}
+
+	invalid := fmt.Errorf("must be a language tag like en or zh-Hant, not %q", tag)
+
+	subtags := strings.Split(tag, "-")
+	if n := len(subtags[0]); n < 2 || n > 3 || !isAlnum(subtags[0], false) {
+		return invalid
+	}
+
+	for _, sub := range subtags[1:] {
+		if n := len(sub); n < 1 || n > 8 || !isAlnum(sub, true) {
+			return invalid
+		}
+	}
+
+	return nil
+}
+
+func isAlnum(s string, digits bool) bool {
+	for _, r := range s {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !isLetter && !(digits && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// keyringService is the service every secret is filed under in the OS keyring.
+	keyringService = "transit"
+
+	// keyringPrefix marks a value as a reference to a secret in the OS keyring,
+	// e.g. `keyring:dmv.api_key`.
+	keyringPrefix = "keyring:"
+
+	// cmdPrefix marks a value as a command whose output is the secret,
+	// e.g. `cmd:pass show wmata`.
+	cmdPrefix = "cmd:"
+
+	// envPrefix is prepended to a key to name the environment variable that
+	// overrides it, e.g. TRANSIT_DMV_API_KEY for `dmv.api_key`.
+	envPrefix = "TRANSIT"
+)
+
+// IsSecret reports whether key holds a credential.
+func IsSecret(key string) bool {
+	f, ok := Lookup(key)
+	return ok && f.Secret
+}
+
+// Mask hides a secret so it can be printed. References to a keyring entry or a
+// command aren't secret themselves and are returned as they are.
+func Mask(value string) string {
+	if isReference(value) {
+		return value
+	}
+
+	const visible = 4
+	if len(value) <= visible {
+		return strings.Repeat("*", len(value))
+	}
+
+	return strings.Repeat("*", len(value)-visible) + value[len(value)-visible:]
+}
+
+// EnvVar returns the environment variable that overrides key.
+func EnvVar(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// HasAPIKey reports whether a location has credentials from any source, without
+// resolving them.
+func (c *Config) HasAPIKey(location string) bool {
+	return c.vp.GetString(location+".api_key") != "" || c.vp.GetString(location+".api_key_cmd") != ""
+}
+
+// APIKey resolves the credentials of a location. The environment variable wins,
+// then `<location>.api_key_cmd`, then `<location>.api_key`, which can hold the key
+// itself or a `keyring:` or `cmd:` reference to it. No key is an empty string.
+func (c *Config) APIKey(ctx context.Context, location string) (string, error) {
+	key := location + ".api_key"
+
+	// The env var reaches GetString too, it only has to skip the command.
+	if _, fromEnv := os.LookupEnv(EnvVar(key)); !fromEnv {
+		if command := c.vp.GetString(location + ".api_key_cmd"); command != "" {
+			return runSecretCommand(ctx, key, command)
+		}
+	}
+
+	return resolveSecret(ctx, key, c.vp.GetString(key))
+}
+
+// SetSecret files value in the OS keyring and writes a reference to it under key,
+// so the file never holds the secret itself.
+func (c *Config) SetSecret(key, value string) error {
+	if err := keyring.Set(keyringService, key, value); err != nil {
+		return fmt.Errorf("store %s in the keyring: %w", key, err)
+	}
+
+	return c.Set(key, keyringPrefix+key)
+}
+
+func isReference(value string) bool {
+	return strings.HasPrefix(value, keyringPrefix) || strings.HasPrefix(value, cmdPrefix)
+}
+
+// resolveSecret follows a `keyring:` or `cmd:` reference. Anything else is the secret.
+func resolveSecret(ctx context.Context, key, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, keyringPrefix):
+		name := strings.TrimPrefix(value, keyringPrefix)
+
+		secret, err := keyring.Get(keyringService, name)
+		if errors.Is(err, keyring.ErrNotFound) {
+			return "", fmt.Errorf("%w: %s refers to %q but the keyring has no such entry", ErrInvalid, key, name)
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("read %s from the keyring: %w", key, err)
+		}
+
+		return secret, nil
+	case strings.HasPrefix(value, cmdPrefix):
+		return runSecretCommand(ctx, key, strings.TrimPrefix(value, cmdPrefix))
+	default:
+		return value, nil
+	}
+}
+
+// runSecretCommand runs command through the platform's shell and returns its
+// trimmed output. Pipes and quoting behave the way they do at a prompt.
+func runSecretCommand(ctx context.Context, key, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		return "", fmt.Errorf("%w: command for %s failed: %w: %s", ErrInvalid, key, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
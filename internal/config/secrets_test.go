@@ -0,0 +1,180 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/zalando/go-keyring"
+)
+
+func TestMask(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value string
+		want  string
+	}{
+		"only the last four are shown":  {value: "abcdef123456", want: "********3456"},
+		"short values are hidden whole": {value: "abc", want: "***"},
+		"empty":                         {value: "", want: ""},
+		"a keyring reference":           {value: "keyring:dmv.api_key", want: "keyring:dmv.api_key"},
+		"a command reference":           {value: "cmd:pass show wmata", want: "cmd:pass show wmata"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := config.Mask(tc.value); got != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	t.Parallel()
+
+	if got := config.EnvVar("dmv.api_key"); got != "TRANSIT_DMV_API_KEY" {
+		t.Errorf("expected %q but got %q", "TRANSIT_DMV_API_KEY", got)
+	}
+}
+
+// Nothing here calls t.Parallel because t.Setenv and the keyring mock are process wide.
+func TestAPIKey(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands below are written for sh")
+	}
+
+	keyring.MockInit()
+
+	tests := map[string]struct {
+		file string
+		env  string
+		want string
+		err  error
+	}{
+		"a key in the file": {
+			file: "dmv:\n  api_key: from-file\n",
+			want: "from-file",
+		},
+		"the env var wins over the file": {
+			file: "dmv:\n  api_key: from-file\n  api_key_cmd: echo from-cmd\n",
+			env:  "from-env",
+			want: "from-env",
+		},
+		"the command wins over the file": {
+			file: "dmv:\n  api_key: from-file\n  api_key_cmd: echo from-cmd\n",
+			want: "from-cmd",
+		},
+		"a command reference": {
+			file: "dmv:\n  api_key: \"cmd:printf ' padded '\"\n",
+			want: "padded",
+		},
+		"a keyring reference": {
+			file: "dmv:\n  api_key: keyring:dmv.api_key\n",
+			want: "from-keyring",
+		},
+		"a keyring reference with no entry": {
+			file: "dmv:\n  api_key: keyring:missing\n",
+			err:  config.ErrInvalid,
+		},
+		"a failing command": {
+			file: "dmv:\n  api_key_cmd: exit 3\n",
+			err:  config.ErrInvalid,
+		},
+		"nothing configured": {
+			file: "",
+			want: "",
+		},
+	}
+
+	if err := keyring.Set("transit", "dmv.api_key", "from-keyring"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.env != "" {
+				t.Setenv("TRANSIT_DMV_API_KEY", tc.env)
+			}
+
+			cfg := loadFrom(t, tc.file)
+
+			got, err := cfg.APIKey(t.Context(), "dmv")
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected an error wrapping %v but got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetDoesNotWriteTheEnvironment(t *testing.T) {
+	t.Setenv("TRANSIT_SF_API_KEY", "from-env")
+
+	cfg := loadFrom(t, "core:\n  location: sf\n")
+
+	if err := cfg.Set("core.watch_interval", "30"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	written, err := os.ReadFile(cfg.FileUsed())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if strings.Contains(string(written), "from-env") {
+		t.Errorf("expected only file values but got %q, the env var leaked into the file", written)
+	}
+
+	if !strings.Contains(string(written), "watch_interval") {
+		t.Errorf("expected the new value in the file but got %q", written)
+	}
+}
+
+// loadFrom writes content to a config file the test owns and loads it.
+func loadFrom(t *testing.T, content string) *config.Config {
+	t.Helper()
+
+	return loadProfile(t, content, "")
+}
+
+// loadProfile writes content to a config file the test owns and loads it with a profile.
+func loadProfile(t *testing.T, content, profile string) *config.Config {
+	t.Helper()
+
+	cfg, err := config.Load(writeConfig(t, content), profile)
+	if err != nil {
+		t.Fatalf("load config: %s", err)
+	}
+
+	return cfg
+}
+
+// writeConfig writes content to a config file the test owns and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %s", err)
+	}
+
+	return path
+}
@@ -3,11 +3,16 @@ package gtfs
 import (
 	"archive/zip"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ismailshak/transit/internal/transit"
 )
@@ -35,12 +40,43 @@ func ResolveGTFSAlertEffect(effect int) string {
 	case 9:
 		return "No Effect"
 	case 10:
-		return "Accessibility Issue"
+		return transit.EffectAccessibility
 	default:
 		return "Notice"
 	}
 }
 
+// ResolveGTFSAlertCause resolves the GTFS Service Alert "Cause" field to a human-readable string.
+// An unknown, unset or invalid cause will return an empty string, there's nothing worth showing.
+func ResolveGTFSAlertCause(cause int) string {
+	switch cause {
+	case 2:
+		return "Other Cause"
+	case 3:
+		return "Technical Problem"
+	case 4:
+		return "Strike"
+	case 5:
+		return "Demonstration"
+	case 6:
+		return "Accident"
+	case 7:
+		return "Holiday"
+	case 8:
+		return "Weather"
+	case 9:
+		return "Maintenance"
+	case 10:
+		return "Construction"
+	case 11:
+		return "Police Activity"
+	case 12:
+		return "Medical Emergency"
+	default:
+		return ""
+	}
+}
+
 // UnzipStaticGTFS unzips file (which holds the content of a GTFS Static feed)
 // located at `path` into a destination provided by `dest`.
 // Destination is assumed to already exist. Zip file will not be deleted
@@ -78,14 +114,267 @@ func ParseGTFS(path string, location transit.LocationSlug, st transit.StopType,
 		return nil, fmt.Errorf("parse %s: %w", stopsFile, err)
 	}
 
+	trips, err := ParseGTFSTrips(path)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, err := ParseGTFSPatterns(path, trips)
+	if err != nil {
+		return nil, err
+	}
+
 	static := &transit.Static{
 		Agencies: agencies,
 		Stops:    stops,
+		Trips:    trips,
+		Patterns: patterns,
 	}
 
 	return static, nil
 }
 
+// ParseGTFSTrips parses trips.txt from an unzipped GTFS Static feed. A feed without
+// one returns no trips rather than an error.
+func ParseGTFSTrips(path string) ([]transit.Trip, error) {
+	tripsFile := filepath.Join(path, "trips.txt")
+	if _, err := os.Stat(tripsFile); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	trips := make([]transit.Trip, 0, 64)
+	err := parseGTFSEntity(tripsFile, func(record []string, headerMap map[string]int) {
+		headsign, hasHeadsign := headerMap["trip_headsign"]
+		shape, hasShape := headerMap["shape_id"]
+		trips = append(trips, transit.Trip{
+			TripID:   record[headerMap["trip_id"]],
+			RouteID:  record[headerMap["route_id"]],
+			Headsign: valueOrFallback(record[headsign], "", hasHeadsign),
+			ShapeID:  valueOrFallback(record[shape], "", hasShape),
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", tripsFile, err)
+	}
+
+	return trips, nil
+}
+
+// stopTime is a row of stop_times.txt. A time that's left blank is -1.
+type stopTime struct {
+	stopID   string
+	sequence int
+	arrives  time.Duration
+	departs  time.Duration
+}
+
+// ParseGTFSPatterns reads stop_times.txt and routes.txt from an unzipped GTFS Static
+// feed into the patterns the trips run, trips being what [ParseGTFSTrips] read from
+// the same feed. A feed without stop times returns no patterns rather than an error.
+func ParseGTFSPatterns(path string, trips []transit.Trip) ([]transit.Pattern, error) {
+	stopTimesFile := filepath.Join(path, "stop_times.txt")
+	if _, err := os.Stat(stopTimesFile); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	lines, err := parseGTFSLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var order []string
+	stopTimes := make(map[string][]stopTime)
+	var parseErr error
+	err = parseGTFSEntity(stopTimesFile, func(record []string, headerMap map[string]int) {
+		if parseErr != nil {
+			return
+		}
+
+		tripID := record[headerMap["trip_id"]]
+		st := stopTime{stopID: record[headerMap["stop_id"]]}
+
+		if st.sequence, parseErr = strconv.Atoi(record[headerMap["stop_sequence"]]); parseErr != nil {
+			parseErr = fmt.Errorf("trip %s: stop_sequence: %w", tripID, parseErr)
+			return
+		}
+
+		if st.arrives, parseErr = parseGTFSTime(record[headerMap["arrival_time"]]); parseErr != nil {
+			parseErr = fmt.Errorf("trip %s: arrival_time: %w", tripID, parseErr)
+			return
+		}
+
+		if st.departs, parseErr = parseGTFSTime(record[headerMap["departure_time"]]); parseErr != nil {
+			parseErr = fmt.Errorf("trip %s: departure_time: %w", tripID, parseErr)
+			return
+		}
+
+		if _, ok := stopTimes[tripID]; !ok {
+			order = append(order, tripID)
+		}
+
+		stopTimes[tripID] = append(stopTimes[tripID], st)
+	})
+
+	if err == nil {
+		err = parseErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", stopTimesFile, err)
+	}
+
+	tripsByID := make(map[string]transit.Trip, len(trips))
+	for _, trip := range trips {
+		tripsByID[trip.TripID] = trip
+	}
+
+	seen := make(map[string]bool)
+	var patterns []transit.Pattern
+	for _, tripID := range order {
+		trip, ok := tripsByID[tripID]
+		if !ok {
+			continue
+		}
+
+		times := stopTimes[tripID]
+		slices.SortFunc(times, func(a, b stopTime) int { return a.sequence - b.sequence })
+
+		stops := patternStops(times)
+		if len(stops) < 2 {
+			continue
+		}
+
+		key := trip.RouteID
+		for _, stop := range stops {
+			key += "|" + stop.StopID
+		}
+
+		if seen[key] {
+			continue
+		}
+
+		line := lines[trip.RouteID]
+		if line == "" {
+			line = trip.RouteID
+		}
+
+		seen[key] = true
+		patterns = append(patterns, transit.Pattern{
+			PatternID: tripID,
+			RouteID:   trip.RouteID,
+			Line:      line,
+			Headsign:  trip.Headsign,
+			Stops:     stops,
+		})
+	}
+
+	return patterns, nil
+}
+
+// patternStops times a trip's stops from when it leaves the first one. Stops that
+// aren't timepoints leave their times blank, those are spread evenly between the
+// timepoints either side. A trip that doesn't time its first and last stop can't be
+// timed and has no stops.
+func patternStops(times []stopTime) []transit.PatternStop {
+	if len(times) == 0 {
+		return nil
+	}
+
+	first, last := times[0], times[len(times)-1]
+	if max(first.departs, first.arrives) < 0 || max(last.arrives, last.departs) < 0 {
+		return nil
+	}
+
+	start := first.departs
+	if start < 0 {
+		start = first.arrives
+	}
+
+	stops := make([]transit.PatternStop, len(times))
+	prev := 0 // Index of the last timed stop.
+	for i, st := range times {
+		arrives, departs := st.arrives, st.departs
+		if arrives < 0 {
+			arrives = departs
+		}
+
+		if departs < 0 {
+			departs = arrives
+		}
+
+		stops[i] = transit.PatternStop{StopID: st.stopID, Arrives: arrives - start, Departs: departs - start}
+		if arrives < 0 {
+			continue
+		}
+
+		// Spread the untimed stops since the last timed one.
+		from, gap := stops[prev].Departs, i-prev
+		for j := prev + 1; j < i; j++ {
+			at := from + (stops[i].Arrives-from)*time.Duration(j-prev)/time.Duration(gap)
+			stops[j].Arrives, stops[j].Departs = at, at
+		}
+
+		prev = i
+	}
+
+	return stops
+}
+
+// parseGTFSTime parses a GTFS time, HH:MM:SS into the service day. Hours run past
+// 24 for trips that finish after midnight. Blank is -1.
+func parseGTFSTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return -1, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("%q isn't HH:MM:SS", value)
+	}
+
+	var hms [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q isn't HH:MM:SS", value)
+		}
+
+		hms[i] = n
+	}
+
+	return time.Duration(hms[0])*time.Hour + time.Duration(hms[1])*time.Minute + time.Duration(hms[2])*time.Second, nil
+}
+
+// parseGTFSLines maps route IDs to the name riders know the route by, from
+// routes.txt. A feed without one maps nothing.
+func parseGTFSLines(path string) (map[string]string, error) {
+	routesFile := filepath.Join(path, "routes.txt")
+	if _, err := os.Stat(routesFile); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	lines := make(map[string]string)
+	err := parseGTFSEntity(routesFile, func(record []string, headerMap map[string]int) {
+		short, hasShort := headerMap["route_short_name"]
+		long, hasLong := headerMap["route_long_name"]
+
+		name := valueOrFallback(record[short], "", hasShort)
+		if name == "" {
+			name = valueOrFallback(record[long], "", hasLong)
+		}
+
+		lines[record[headerMap["route_id"]]] = name
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", routesFile, err)
+	}
+
+	return lines, nil
+}
+
 func parseGTFSAgency(path string, location transit.LocationSlug) ([]transit.Agency, error) {
 	agencies := make([]transit.Agency, 0)
 	err := parseGTFSEntity(path, func(record []string, headerMap map[string]int) {
@@ -147,7 +436,8 @@ func parseGTFSEntity(path string, fn ParseEntityFunc) error {
 	defer f.Close() //nolint:errcheck // read-only handle, nothing buffered to lose
 
 	r := csv.NewReader(f)
-	r.LazyQuotes = true // Fields are often quoted, without this it breaks
+	r.LazyQuotes = true    // Fields are often quoted, without this it breaks
+	r.FieldsPerRecord = -1 // Some feeds leave off trailing empty fields, those rows are padded below
 
 	// Read the header column separately
 	header, err := r.Read()
@@ -171,6 +461,10 @@ func parseGTFSEntity(path string, fn ParseEntityFunc) error {
 			return err
 		}
 
+		for len(record) < len(header) {
+			record = append(record, "")
+		}
+
 		fn(record, headerMap)
 	}
 
@@ -1,6 +1,12 @@
 package gtfs
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestValueOrFallback(t *testing.T) {
 	t.Parallel()
@@ -81,3 +87,83 @@ func TestValueOrFallback(t *testing.T) {
 		}
 	})
 }
+
+func TestPatternStops(t *testing.T) {
+	t.Parallel()
+
+	const blank = -1
+
+	tests := map[string]struct {
+		times []stopTime
+		want  []transit.PatternStop
+	}{
+		"timed from the first departure": {
+			times: []stopTime{
+				{stopID: "A", arrives: 8 * time.Hour, departs: 8*time.Hour + time.Minute},
+				{stopID: "B", arrives: 8*time.Hour + 10*time.Minute, departs: 8*time.Hour + 10*time.Minute},
+			},
+			want: []transit.PatternStop{
+				{StopID: "A", Arrives: -time.Minute, Departs: 0},
+				{StopID: "B", Arrives: 9 * time.Minute, Departs: 9 * time.Minute},
+			},
+		},
+		"stops between timepoints are spread evenly": {
+			times: []stopTime{
+				{stopID: "A", arrives: 8 * time.Hour, departs: 8 * time.Hour},
+				{stopID: "B", arrives: blank, departs: blank},
+				{stopID: "C", arrives: blank, departs: blank},
+				{stopID: "D", arrives: 8*time.Hour + 9*time.Minute, departs: 8*time.Hour + 9*time.Minute},
+			},
+			want: []transit.PatternStop{
+				{StopID: "A"},
+				{StopID: "B", Arrives: 3 * time.Minute, Departs: 3 * time.Minute},
+				{StopID: "C", Arrives: 6 * time.Minute, Departs: 6 * time.Minute},
+				{StopID: "D", Arrives: 9 * time.Minute, Departs: 9 * time.Minute},
+			},
+		},
+		"an untimed last stop can't be timed": {
+			times: []stopTime{
+				{stopID: "A", arrives: 8 * time.Hour, departs: 8 * time.Hour},
+				{stopID: "B", arrives: blank, departs: blank},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, patternStops(tc.times))
+		})
+	}
+}
+
+func TestParseGTFSTime(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value string
+		want  time.Duration
+		err   bool
+	}{
+		"morning":        {value: "6:05:00", want: 6*time.Hour + 5*time.Minute},
+		"after midnight": {value: "25:10:30", want: 25*time.Hour + 10*time.Minute + 30*time.Second},
+		"blank":          {value: "", want: -1},
+		"not a time":     {value: "6:05", err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseGTFSTime(tc.value)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ismailshak/transit/internal/fixtures"
 	"github.com/ismailshak/transit/internal/gtfs"
@@ -119,6 +120,54 @@ func TestParseGTFS(t *testing.T) {
 		assert.Equal(t, expected.Longitude, stop.Longitude)
 	}
 
+	if len(gtfs.Trips) != 11 {
+		t.Fatalf("expected 11 trips. Got %d", len(gtfs.Trips))
+	}
+
+	assert.Equal(t, transit.Trip{TripID: "AB1", RouteID: "AB", Headsign: "to Bullfrog"}, gtfs.Trips[0])
+	assert.Len(t, gtfs.Patterns, 9)
+}
+
+func TestParseGTFSPatterns(t *testing.T) {
+	t.Parallel()
+
+	trips, err := gtfs.ParseGTFSTrips(fixtures.Path("sample-feed"))
+	if err != nil {
+		t.Fatalf("ParseGTFSTrips() returned an error: %s", err)
+	}
+
+	patterns, err := gtfs.ParseGTFSPatterns(fixtures.Path("sample-feed"), trips)
+	if err != nil {
+		t.Fatalf("ParseGTFSPatterns() returned an error: %s", err)
+	}
+
+	// AAMV3 and AAMV4 call at the same stops as AAMV1 and AAMV2.
+	if len(patterns) != 9 {
+		t.Fatalf("expected 9 patterns. Got %d", len(patterns))
+	}
+
+	city := patterns[1]
+	assert.Equal(t, "CITY1", city.PatternID)
+	assert.Equal(t, "CITY", city.RouteID)
+	assert.Equal(t, "40", city.Line)
+	assert.Equal(t, []transit.PatternStop{
+		{StopID: "STAGECOACH", Arrives: 0, Departs: 0},
+		{StopID: "NANAA", Arrives: 5 * time.Minute, Departs: 7 * time.Minute},
+		{StopID: "NADAV", Arrives: 12 * time.Minute, Departs: 14 * time.Minute},
+		{StopID: "DADAN", Arrives: 19 * time.Minute, Departs: 21 * time.Minute},
+		{StopID: "EMSI", Arrives: 26 * time.Minute, Departs: 28 * time.Minute},
+	}, city.Stops)
+}
+
+func TestParseGTFSTripsWithoutTripsFile(t *testing.T) {
+	t.Parallel()
+
+	trips, err := gtfs.ParseGTFSTrips(t.TempDir())
+	if err != nil {
+		t.Fatalf("ParseGTFSTrips() returned an error: %s", err)
+	}
+
+	assert.Empty(t, trips)
 }
 
 // Filters out `\r` to make testing on Windows easier.
@@ -0,0 +1,235 @@
+// Package ics writes iCalendar (RFC 5545) files, just enough of the format to put
+// departures on a calendar.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLine is how many octets a content line can take before it's folded.
+	maxLine = 75
+
+	localFormat = "20060102T150405"
+	utcFormat   = "20060102T150405Z"
+)
+
+// Calendar is a VCALENDAR of events.
+type Calendar struct {
+	ProdID string // Identifies the product that made the calendar, e.g. "-//transit//EN".
+	Name   string // Shown by clients that support X-WR-CALNAME. Optional.
+	Events []Event
+}
+
+// Event is a VEVENT.
+type Event struct {
+	UID         string // Globally unique, the same every time the event is written.
+	Stamp       time.Time
+	Start       time.Time // Written in its location, with a TZID unless it's UTC.
+	End         time.Time // Optional, an event without one ends when it starts.
+	Summary     string
+	Location    string
+	Description string
+}
+
+// Write writes the calendar to w. Every zone an event starts or ends in gets a
+// VTIMEZONE with the offset changes between the first and the last of its times.
+func Write(w io.Writer, cal Calendar) error {
+	cw := &contentWriter{w: bufio.NewWriter(w)}
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.property("PRODID", cal.ProdID)
+	cw.line("CALSCALE:GREGORIAN")
+	if cal.Name != "" {
+		cw.property("X-WR-CALNAME", cal.Name)
+	}
+
+	for _, z := range zoneSpans(cal.Events) {
+		cw.timezone(z)
+	}
+
+	for _, e := range cal.Events {
+		cw.line("BEGIN:VEVENT")
+		cw.property("UID", e.UID)
+		cw.line("DTSTAMP:" + e.Stamp.UTC().Format(utcFormat))
+		cw.dateTime("DTSTART", e.Start)
+		if !e.End.IsZero() {
+			cw.dateTime("DTEND", e.End)
+		}
+		cw.property("SUMMARY", e.Summary)
+		if e.Location != "" {
+			cw.property("LOCATION", e.Location)
+		}
+		if e.Description != "" {
+			cw.property("DESCRIPTION", e.Description)
+		}
+		cw.line("END:VEVENT")
+	}
+
+	cw.line("END:VCALENDAR")
+
+	if cw.err != nil {
+		return cw.err
+	}
+
+	return cw.w.Flush()
+}
+
+// contentWriter writes content lines, keeping the first error.
+type contentWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folded so no line runs past maxLine octets. A fold
+// never splits a UTF-8 sequence.
+func (cw *contentWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !startsRune(s[cut]) {
+			cut--
+		}
+
+		if _, cw.err = cw.w.WriteString(s[:cut] + "\r\n "); cw.err != nil {
+			return
+		}
+
+		s = s[cut:]
+		limit = maxLine - 1 // The leading space of a continuation counts.
+	}
+
+	_, cw.err = cw.w.WriteString(s + "\r\n")
+}
+
+func (cw *contentWriter) property(name, value string) {
+	cw.line(name + ":" + escape(value))
+}
+
+func (cw *contentWriter) dateTime(name string, t time.Time) {
+	if isUTC(t) {
+		cw.line(name + ":" + t.UTC().Format(utcFormat))
+		return
+	}
+
+	cw.line(fmt.Sprintf("%s;TZID=%s:%s", name, t.Location(), t.Format(localFormat)))
+}
+
+// zoneSpan is the first and last time the events have in one zone.
+type zoneSpan struct {
+	first, last time.Time
+}
+
+// zoneSpans returns a span per zone the events start or end in, other than UTC, in
+// the order the zones first appear.
+func zoneSpans(events []Event) []zoneSpan {
+	var spans []zoneSpan
+	index := make(map[string]int)
+
+	for _, e := range events {
+		for _, t := range []time.Time{e.Start, e.End} {
+			if t.IsZero() || isUTC(t) {
+				continue
+			}
+
+			n, ok := index[t.Location().String()]
+			if !ok {
+				index[t.Location().String()] = len(spans)
+				spans = append(spans, zoneSpan{first: t, last: t})
+				continue
+			}
+
+			if t.Before(spans[n].first) {
+				spans[n].first = t
+			}
+			if t.After(spans[n].last) {
+				spans[n].last = t
+			}
+		}
+	}
+
+	return spans
+}
+
+// timezone writes a VTIMEZONE for the span's zone. It has an observance for the
+// offset in effect at the first time, then one for each change up to the last, each
+// with the onset the zone's own rules give it. That's all a client needs to place
+// the events, the zone's rules outside the span don't matter to them.
+func (cw *contentWriter) timezone(z zoneSpan) {
+	cw.line("BEGIN:VTIMEZONE")
+	cw.line("TZID:" + z.first.Location().String())
+
+	onset, next := z.first.ZoneBounds()
+	if onset.IsZero() {
+		// The offset never changed before the first time, any onset before it will do.
+		onset = time.Date(1970, 1, 1, 0, 0, 0, 0, z.first.Location())
+	}
+
+	for {
+		cw.observance(onset)
+
+		if next.IsZero() || next.After(z.last) {
+			break
+		}
+
+		onset = next
+		_, next = onset.ZoneBounds()
+	}
+
+	cw.line("END:VTIMEZONE")
+}
+
+// observance writes the STANDARD or DAYLIGHT component for the offset that takes
+// effect at onset. Its DTSTART is the onset in the local time before the change.
+func (cw *contentWriter) observance(onset time.Time) {
+	kind := "STANDARD"
+	if onset.IsDST() {
+		kind = "DAYLIGHT"
+	}
+
+	abbrev, offset := onset.Zone()
+	_, before := onset.Add(-time.Second).Zone()
+
+	cw.line("BEGIN:" + kind)
+	cw.line("DTSTART:" + onset.UTC().Add(time.Duration(before)*time.Second).Format(localFormat))
+	cw.line("TZOFFSETFROM:" + formatOffset(before))
+	cw.line("TZOFFSETTO:" + formatOffset(offset))
+	cw.property("TZNAME", abbrev)
+	cw.line("END:" + kind)
+}
+
+// formatOffset writes seconds east of UTC as ±hhmm.
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func isUTC(t time.Time) bool {
+	return t.Location() == time.UTC
+}
+
+// startsRune reports whether b can start a UTF-8 sequence, i.e. isn't a continuation byte.
+func startsRune(b byte) bool {
+	return b&0xC0 != 0x80
+}
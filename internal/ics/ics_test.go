@@ -0,0 +1,184 @@
+package ics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/ics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	eastern, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	cal := ics.Calendar{
+		ProdID: "-//transit//EN",
+		Events: []ics.Event{
+			{
+				UID:         "1@transit",
+				Stamp:       time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+				Start:       time.Date(2026, 10, 19, 8, 15, 0, 0, eastern),
+				End:         time.Date(2026, 10, 19, 8, 20, 0, 0, eastern),
+				Summary:     "RD to Shady Grove",
+				Location:    "Metro Center",
+				Description: "Red line, toward Shady Grove; board at Metro Center",
+			},
+			{
+				UID:     "2@transit",
+				Stamp:   time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+				Start:   time.Date(2026, 11, 2, 8, 15, 0, 0, eastern), // After clocks go back.
+				Summary: "OR to Vienna",
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, ics.Write(&out, cal))
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//transit//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VTIMEZONE",
+		"TZID:America/New_York",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20260308T020000",
+		"TZOFFSETFROM:-0500",
+		"TZOFFSETTO:-0400",
+		"TZNAME:EDT",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20261101T020000",
+		"TZOFFSETFROM:-0400",
+		"TZOFFSETTO:-0500",
+		"TZNAME:EST",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:1@transit",
+		"DTSTAMP:20261019T120000Z",
+		"DTSTART;TZID=America/New_York:20261019T081500",
+		"DTEND;TZID=America/New_York:20261019T082000",
+		"SUMMARY:RD to Shady Grove",
+		"LOCATION:Metro Center",
+		`DESCRIPTION:Red line\, toward Shady Grove\; board at Metro Center`,
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:2@transit",
+		"DTSTAMP:20261019T120000Z",
+		"DTSTART;TZID=America/New_York:20261102T081500",
+		"SUMMARY:OR to Vienna",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	assert.Equal(t, want, out.String())
+}
+
+func TestWriteTimezones(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		starts []time.Time
+		want   []string
+	}{
+		"one offset has one observance": {
+			starts: []time.Time{time.Date(2026, 7, 1, 9, 0, 0, 0, mustZone(t, "America/Los_Angeles"))},
+			want: []string{
+				"TZID:America/Los_Angeles",
+				"BEGIN:DAYLIGHT",
+				"DTSTART:20260308T020000",
+				"TZOFFSETFROM:-0800",
+				"TZOFFSETTO:-0700",
+				"TZNAME:PDT",
+				"END:DAYLIGHT",
+			},
+		},
+		"a zone without daylight saving": {
+			starts: []time.Time{time.Date(2026, 7, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))},
+			want: []string{
+				"TZID:JST",
+				"BEGIN:STANDARD",
+				"DTSTART:19700101T000000",
+				"TZOFFSETFROM:+0900",
+				"TZOFFSETTO:+0900",
+				"TZNAME:JST",
+				"END:STANDARD",
+			},
+		},
+		"UTC has no zone": {
+			starts: []time.Time{time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cal := ics.Calendar{ProdID: "-//transit//EN"}
+			for _, start := range tc.starts {
+				cal.Events = append(cal.Events, ics.Event{UID: "1", Start: start})
+			}
+
+			var out bytes.Buffer
+			require.NoError(t, ics.Write(&out, cal))
+
+			var got []string
+			inZone := false
+			for line := range strings.SplitSeq(out.String(), "\r\n") {
+				switch line {
+				case "BEGIN:VTIMEZONE":
+					inZone = true
+				case "END:VTIMEZONE":
+					inZone = false
+				default:
+					if inZone {
+						got = append(got, line)
+					}
+				}
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	zone, err := time.LoadLocation(name)
+	require.NoError(t, err)
+
+	return zone
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	t.Parallel()
+
+	summary := strings.Repeat("é", 60) // Two octets each.
+
+	var out bytes.Buffer
+	require.NoError(t, ics.Write(&out, ics.Calendar{ProdID: "-//transit//EN", Events: []ics.Event{{UID: "1", Summary: summary}}}))
+
+	var unfolded strings.Builder
+	for line := range strings.SplitSeq(strings.TrimSuffix(out.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line %q", line)
+
+		if rest, ok := strings.CutPrefix(line, " "); ok {
+			unfolded.WriteString(rest)
+			continue
+		}
+
+		unfolded.WriteString("\n" + line)
+	}
+
+	assert.Contains(t, unfolded.String(), "\nSUMMARY:"+summary+"\n")
+}
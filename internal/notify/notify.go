@@ -0,0 +1,154 @@
+// Package notify delivers notifications about disruptions to wherever the rider
+// wants them, through a [Sink].
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrUnknownSink is returned by [New] for a sink name it doesn't know.
+var ErrUnknownSink = errors.New("unknown sink")
+
+// webhookTimeout bounds each POST, so a hung receiver doesn't stall the daemon.
+const webhookTimeout = 10 * time.Second
+
+// Kind is what a notification is about.
+type Kind string
+
+const (
+	KindAlert     Kind = "alert"      // An alert affecting a watched line or stop was posted.
+	KindNoService Kind = "no_service" // A watched stop has nothing predicted to depart.
+	KindResumed   Kind = "resumed"    // A watched stop has departures again.
+)
+
+// Notification is one message for the rider.
+type Notification struct {
+	Key   string    `json:"key"` // Identifies what it's about, the same across polls.
+	Kind  Kind      `json:"kind"`
+	Title string    `json:"title"`
+	Body  string    `json:"body"`
+	Time  time.Time `json:"time"`
+}
+
+// Sink delivers notifications.
+type Sink interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Options configures the sink [New] builds. Each sink only reads what it needs.
+type Options struct {
+	Out        io.Writer    // Where stdout and bell write.
+	Command    string       // Command line the command sink runs, e.g. "notify-send -u critical".
+	WebhookURL string       // Where the webhook sink POSTs.
+	Client     *http.Client // The webhook sink's client. Nil means one with a timeout.
+}
+
+// New builds the sink called name: stdout, bell, command or webhook.
+func New(name string, opts Options) (Sink, error) {
+	switch name {
+	case "stdout":
+		return &JSONLines{Out: opts.Out}, nil
+	case "bell":
+		return &Bell{Out: opts.Out}, nil
+	case "command":
+		args := strings.Fields(opts.Command)
+		if len(args) == 0 {
+			return nil, errors.New("the command sink needs a command")
+		}
+
+		return &Command{Name: args[0], Args: args[1:]}, nil
+	case "webhook":
+		if opts.WebhookURL == "" {
+			return nil, errors.New("the webhook sink needs a URL")
+		}
+
+		client := opts.Client
+		if client == nil {
+			client = &http.Client{Timeout: webhookTimeout}
+		}
+
+		return &Webhook{URL: opts.WebhookURL, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("%w %q, use stdout, bell, command or webhook", ErrUnknownSink, name)
+	}
+}
+
+// JSONLines writes each notification as one line of JSON, for piping into other tools.
+type JSONLines struct {
+	Out io.Writer
+}
+
+func (s *JSONLines) Notify(_ context.Context, n Notification) error {
+	return json.NewEncoder(s.Out).Encode(n)
+}
+
+// Bell rings the terminal bell and prints the notification, for a terminal left open.
+type Bell struct {
+	Out io.Writer
+}
+
+func (s *Bell) Notify(_ context.Context, n Notification) error {
+	_, err := fmt.Fprintf(s.Out, "\a%s %s\n  %s\n", n.Time.Format(time.Kitchen), n.Title, n.Body)
+	return err
+}
+
+// Command runs a program with the title and body appended to its arguments, which
+// is how notify-send and osascript wrappers expect them.
+type Command struct {
+	Name string
+	Args []string
+}
+
+func (s *Command) Notify(ctx context.Context, n Notification) error {
+	args := append(append([]string(nil), s.Args...), n.Title, n.Body)
+
+	out, err := exec.CommandContext(ctx, s.Name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("run %s: %w: %s", s.Name, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Webhook POSTs each notification as JSON. Any 2xx counts as delivered.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post notification: %w", err)
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post notification: the webhook answered %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package notify_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var orangeDelay = notify.Notification{
+	Key:   "alert:wmata/42",
+	Kind:  notify.KindAlert,
+	Title: "Delay on OR",
+	Body:  "Orange Line trains are delayed due to a disabled train at Vienna.",
+	Time:  time.Date(2026, 10, 19, 8, 15, 0, 0, time.UTC),
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		name string
+		opts notify.Options
+		err  bool
+	}{
+		"stdout":                 {name: "stdout", opts: notify.Options{Out: io.Discard}},
+		"bell":                   {name: "bell", opts: notify.Options{Out: io.Discard}},
+		"command":                {name: "command", opts: notify.Options{Command: "notify-send -u critical"}},
+		"command without one":    {name: "command", err: true},
+		"webhook":                {name: "webhook", opts: notify.Options{WebhookURL: "http://localhost/hook"}},
+		"webhook without a URL":  {name: "webhook", err: true},
+		"a sink that isn't real": {name: "pager", err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sink, err := notify.New(tc.name, tc.opts)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, sink)
+		})
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	sink := &notify.JSONLines{Out: out}
+
+	require.NoError(t, sink.Notify(t.Context(), orangeDelay))
+	require.NoError(t, sink.Notify(t.Context(), orangeDelay))
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var got notify.Notification
+	require.NoError(t, json.Unmarshal(lines[0], &got))
+	assert.Equal(t, orangeDelay, got)
+}
+
+func TestBell(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	require.NoError(t, (&notify.Bell{Out: out}).Notify(t.Context(), orangeDelay))
+
+	assert.Equal(t, "\a8:15AM Delay on OR\n  "+orangeDelay.Body+"\n", out.String())
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	t.Run("posts the notification as json", func(t *testing.T) {
+		t.Parallel()
+
+		var got notify.Notification
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		t.Cleanup(srv.Close)
+
+		sink := &notify.Webhook{URL: srv.URL, Client: srv.Client()}
+		require.NoError(t, sink.Notify(t.Context(), orangeDelay))
+		assert.Equal(t, orangeDelay, got)
+	})
+
+	t.Run("a non 2xx answer is an error", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(srv.Close)
+
+		sink := &notify.Webhook{URL: srv.URL, Client: srv.Client()}
+		assert.ErrorContains(t, sink.Notify(t.Context(), orangeDelay), "502")
+	})
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+
+	// $0 is the title and $1 the body, sh puts the arguments after -c's script there.
+	ok := &notify.Command{Name: "sh", Args: []string{"-c", `test "$0" = "Delay on OR" && test -n "$1"`}}
+	assert.NoError(t, ok.Notify(t.Context(), orangeDelay))
+
+	failing := &notify.Command{Name: "sh", Args: []string{"-c", "echo no display >&2; exit 1"}}
+	assert.ErrorContains(t, failing.Notify(t.Context(), orangeDelay), "no display")
+}
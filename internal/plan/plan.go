@@ -0,0 +1,237 @@
+// Package plan finds ways to ride from one station to another on the patterns a
+// schedule runs, directly or with one transfer.
+//
+// Patterns call at stops, which are often platforms inside a station. Planning is
+// done between stations, a transfer is to another platform of the same one.
+package plan
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+// Leg is a ride on one route between two stations.
+type Leg struct {
+	RouteID string
+	Line    string
+	From    string        // Station the leg boards at.
+	To      string        // Station the leg gets off at.
+	Ride    time.Duration // The shortest scheduled ride over the patterns that make it.
+
+	// Terminals are the stations this leg's trips end at. A departure showing one of
+	// them, or one of Headsigns, runs far enough to be taken.
+	Terminals []string
+	Headsigns []string
+}
+
+// Option is one way to make the journey, a single leg or two with a transfer
+// between them.
+type Option struct {
+	Legs []Leg
+}
+
+// Ride is the scheduled time spent riding, transfers not included.
+func (o Option) Ride() time.Duration {
+	var ride time.Duration
+	for _, l := range o.Legs {
+		ride += l.Ride
+	}
+
+	return ride
+}
+
+// Transfer is the station the option changes trains at, or empty for a direct one.
+func (o Option) Transfer() string {
+	if len(o.Legs) < 2 {
+		return ""
+	}
+
+	return o.Legs[0].To
+}
+
+// Find returns the ways to ride from station from to station to: direct options
+// first, then ones with one transfer, each fastest first. station maps a stop to
+// the station it's in.
+//
+// A transfer is only offered between two routes that don't go the whole way on
+// their own, and only at the station that makes the fastest ride for that pair.
+func Find(patterns []transit.Pattern, station func(stopID string) string, from, to string) []Option {
+	if from == to {
+		return nil
+	}
+
+	indexed := make([]stationIndex, len(patterns))
+	for n, p := range patterns {
+		indexed[n] = indexStations(p, station)
+	}
+
+	direct := make(map[string]*Leg)
+	for n, p := range patterns {
+		if leg, ok := ride(p, indexed[n], station, from, to); ok {
+			merge(direct, p.RouteID, leg)
+		}
+	}
+
+	// Legs that could make up a transfer, keyed by route and the station where they
+	// meet the other leg.
+	firsts := make(map[legKey]*Leg)
+	seconds := make(map[legKey]*Leg)
+	for n, p := range patterns {
+		if _, ok := direct[p.RouteID]; ok {
+			continue
+		}
+
+		idx := indexed[n]
+		if i, ok := idx[from]; ok {
+			for _, s := range p.Stops[i+1:] {
+				via := station(s.StopID)
+				if via == from || via == to {
+					continue
+				}
+
+				if leg, ok := ride(p, idx, station, from, via); ok {
+					merge(firsts, legKey{p.RouteID, via}, leg)
+				}
+			}
+		}
+
+		if j, ok := idx[to]; ok {
+			for _, s := range p.Stops[:j] {
+				via := station(s.StopID)
+				if via == from || via == to {
+					continue
+				}
+
+				if leg, ok := ride(p, idx, station, via, to); ok {
+					merge(seconds, legKey{p.RouteID, via}, leg)
+				}
+			}
+		}
+	}
+
+	best := make(map[[2]string]Option)
+	for fk, first := range firsts {
+		for sk, second := range seconds {
+			if fk.via != sk.via || fk.route == sk.route {
+				continue
+			}
+
+			option := Option{Legs: []Leg{*first, *second}}
+			pair := [2]string{fk.route, sk.route}
+			if current, ok := best[pair]; !ok || betterTransfer(option, current) {
+				best[pair] = option
+			}
+		}
+	}
+
+	options := make([]Option, 0, len(direct)+len(best))
+	for _, leg := range direct {
+		options = append(options, Option{Legs: []Leg{*leg}})
+	}
+
+	slices.SortFunc(options, fastest)
+
+	transfers := make([]Option, 0, len(best))
+	for _, option := range best {
+		transfers = append(transfers, option)
+	}
+
+	slices.SortFunc(transfers, fastest)
+
+	return append(options, transfers...)
+}
+
+// stationIndex maps a station to the first position a pattern calls at it.
+type stationIndex map[string]int
+
+func indexStations(p transit.Pattern, station func(string) string) stationIndex {
+	idx := make(stationIndex, len(p.Stops))
+	for i, s := range p.Stops {
+		if _, ok := idx[station(s.StopID)]; !ok {
+			idx[station(s.StopID)] = i
+		}
+	}
+
+	return idx
+}
+
+// ride is the leg a pattern makes from one station to a later one.
+func ride(p transit.Pattern, idx stationIndex, station func(string) string, from, to string) (Leg, bool) {
+	i, ok := idx[from]
+	if !ok {
+		return Leg{}, false
+	}
+
+	j := slices.IndexFunc(p.Stops[i+1:], func(s transit.PatternStop) bool { return station(s.StopID) == to })
+	if j < 0 {
+		return Leg{}, false
+	}
+
+	last := p.Stops[len(p.Stops)-1]
+	leg := Leg{
+		RouteID:   p.RouteID,
+		Line:      p.Line,
+		From:      from,
+		To:        to,
+		Ride:      p.Stops[i+1+j].Arrives - p.Stops[i].Departs,
+		Terminals: []string{station(last.StopID)},
+	}
+
+	if p.Headsign != "" {
+		leg.Headsigns = []string{p.Headsign}
+	}
+
+	return leg, true
+}
+
+type legKey struct {
+	route string
+	via   string
+}
+
+// merge adds a pattern's take on a leg to legs, folding it into the one already
+// under key.
+func merge[K comparable](legs map[K]*Leg, key K, leg Leg) {
+	current, ok := legs[key]
+	if !ok {
+		legs[key] = &leg
+		return
+	}
+
+	current.Ride = min(current.Ride, leg.Ride)
+
+	for _, t := range leg.Terminals {
+		if !slices.Contains(current.Terminals, t) {
+			current.Terminals = append(current.Terminals, t)
+		}
+	}
+
+	for _, h := range leg.Headsigns {
+		if !slices.Contains(current.Headsigns, h) {
+			current.Headsigns = append(current.Headsigns, h)
+		}
+	}
+}
+
+// fastest orders options by ride, then by line so the order doesn't depend on map order.
+func fastest(a, b Option) int {
+	if c := cmp.Compare(a.Ride(), b.Ride()); c != 0 {
+		return c
+	}
+
+	return slices.CompareFunc(a.Legs, b.Legs, func(x, y Leg) int { return cmp.Compare(x.Line, y.Line) })
+}
+
+// betterTransfer picks between two stations for the same pair of routes, the faster
+// one or, at the same ride, the one first in name so the answer doesn't depend on
+// map order.
+func betterTransfer(a, b Option) bool {
+	if a.Ride() != b.Ride() {
+		return a.Ride() < b.Ride()
+	}
+
+	return a.Transfer() < b.Transfer()
+}
@@ -0,0 +1,91 @@
+package plan_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/plan"
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pattern calls at platforms named <station>_<line>, a minute apart, leaving each
+// a minute after it arrives.
+func pattern(id, route, headsign string, stations ...string) transit.Pattern {
+	p := transit.Pattern{PatternID: id, RouteID: route, Line: route, Headsign: headsign}
+	for n, s := range stations {
+		at := time.Duration(2*n) * time.Minute
+		p.Stops = append(p.Stops, transit.PatternStop{StopID: s + "_" + route, Arrives: at, Departs: at + time.Minute})
+	}
+
+	return p
+}
+
+func station(stopID string) string {
+	s, _, _ := strings.Cut(stopID, "_")
+	return s
+}
+
+var network = []transit.Pattern{
+	pattern("R1", "RD", "Glenmont", "MetroCenter", "Gallery", "Union"),
+	pattern("R2", "RD", "Gallery Pl", "MetroCenter", "Gallery"),
+	pattern("G1", "GR", "Greenbelt", "LEnfant", "Gallery", "MtVernon"),
+	pattern("O1", "OR", "New Carrollton", "Vienna", "LEnfant", "MetroCenter"),
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	t.Run("direct", func(t *testing.T) {
+		t.Parallel()
+
+		options := plan.Find(network, station, "MetroCenter", "Gallery")
+		require.Len(t, options, 1)
+
+		leg := options[0].Legs[0]
+		assert.Equal(t, "RD", leg.Line)
+		assert.Equal(t, time.Minute, leg.Ride)
+		assert.ElementsMatch(t, []string{"Union", "Gallery"}, leg.Terminals, "a short turn still gets there")
+		assert.ElementsMatch(t, []string{"Glenmont", "Gallery Pl"}, leg.Headsigns)
+		assert.Empty(t, options[0].Transfer())
+	})
+
+	t.Run("one transfer", func(t *testing.T) {
+		t.Parallel()
+
+		options := plan.Find(network, station, "Vienna", "Union")
+		require.Len(t, options, 1)
+
+		option := options[0]
+		assert.Equal(t, "MetroCenter", option.Transfer())
+		assert.Equal(t, "OR", option.Legs[0].Line)
+		assert.Equal(t, "RD", option.Legs[1].Line)
+		assert.Equal(t, []string{"Union"}, option.Legs[1].Terminals, "the short turn doesn't get to Union")
+		assert.Equal(t, 3*time.Minute+3*time.Minute, option.Ride())
+	})
+
+	t.Run("direct options come before transfers", func(t *testing.T) {
+		t.Parallel()
+
+		options := plan.Find(network, station, "LEnfant", "Gallery")
+		require.Len(t, options, 2)
+
+		assert.Empty(t, options[0].Transfer())
+		assert.Equal(t, "GR", options[0].Legs[0].Line)
+		assert.Equal(t, "MetroCenter", options[1].Transfer())
+	})
+
+	t.Run("trains only run one way along a pattern", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, plan.Find(network, station, "Union", "MetroCenter"))
+	})
+
+	t.Run("already there", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, plan.Find(network, station, "Gallery", "Gallery"))
+	})
+}
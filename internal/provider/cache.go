@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	departuresTTL = 10 * time.Second // WMATA and 511 refresh predictions about this often.
+	alertsTTL     = time.Minute
+	faresTTL      = 24 * time.Hour // Fares and run times change with the schedule, not during the day.
+	routesTTL     = 24 * time.Hour // Track layouts change with construction, not between requests.
+)
+
+// response is what a fetch hands back to a client, whether or not it reached the upstream.
+type response struct {
+	Status  int
+	Body    []byte
+	Fetched time.Time // When the body was fetched from the upstream. Only set for cached bodies.
+	Cached  bool      // The body came from the cache rather than this request.
+}
+
+// cacheEntry is one response as it's stored on disk.
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	Fetched      time.Time `json:"fetched"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Body         []byte    `json:"body"`
+}
+
+// responseCache keeps successful responses on disk, keyed by request URL, so that several
+// processes asking the same thing share one upstream call. It's best effort: an entry
+// that can't be read or written is treated as a miss.
+//
+// A nil *responseCache is valid and sends every request upstream.
+type responseCache struct {
+	dir string
+	now func() time.Time
+	log *slog.Logger
+}
+
+func newResponseCache(dir string, now func() time.Time, log *slog.Logger) *responseCache {
+	return &responseCache{dir: dir, now: now, log: log}
+}
+
+// fetch serves req from the cache while the entry is younger than ttl. An older entry is
+// revalidated with If-None-Match/If-Modified-Since when the upstream sent validators.
+func (c *responseCache) fetch(client *http.Client, req *http.Request, ttl time.Duration) (*response, error) {
+	if c == nil {
+		return send(client, req)
+	}
+
+	key := scrubURL(req.URL)
+	entry, hit := c.load(key)
+
+	if hit && c.now().Sub(entry.Fetched) < ttl {
+		c.log.DebugContext(req.Context(), "cache hit", "url", key, "age", c.now().Sub(entry.Fetched))
+		return &response{Status: http.StatusOK, Body: entry.Body, Fetched: entry.Fetched, Cached: true}, nil
+	}
+
+	if hit {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	// The upstream just vouched for the body, so it's as fresh as a new one.
+	if hit && resp.StatusCode == http.StatusNotModified {
+		entry.Fetched = c.now()
+		c.store(key, entry)
+		c.log.DebugContext(req.Context(), "cache revalidated", "url", key)
+
+		return &response{Status: http.StatusOK, Body: entry.Body}, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		c.store(key, &cacheEntry{
+			URL:          key,
+			Fetched:      c.now(),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Body:         body,
+		})
+	}
+
+	return &response{Status: resp.StatusCode, Body: body}, nil
+}
+
+func (c *responseCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *responseCache) load(key string) (*cacheEntry, bool) {
+	raw, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || entry.URL != key {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// store writes through a temp file and a rename, so a process reading at the same
+// time never sees half an entry.
+func (c *responseCache) store(key string, entry *cacheEntry) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return
+	}
+
+	_, writeErr := tmp.Write(raw)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// scrubURL is the request URL without the api_key, the same way newSFHTTPError strips it.
+// WMATA sends its key as a header so its URLs are used as they are. Cache entries and
+// cassettes are both keyed by it.
+func scrubURL(u *url.URL) string {
+	stripped := *u
+	q := stripped.Query()
+	q.Del("api_key")
+	stripped.RawQuery = q.Encode()
+
+	return stripped.String()
+}
+
+// send is a fetch with no cache in front of it.
+func send(client *http.Client, req *http.Request) (*response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{Status: resp.StatusCode, Body: body}, nil
+}
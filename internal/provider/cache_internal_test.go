@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etagServer answers with body and an ETag, and with 304 when the request already has it.
+func etagServer(t *testing.T, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		_, _ = w.Write([]byte(body))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		age        time.Duration // How long after the first fetch the second one happens.
+		calls      int32
+		revalidate bool
+	}{
+		"a fresh entry is served locally": {
+			age:   5 * time.Second,
+			calls: 1,
+		},
+		"a stale entry is revalidated": {
+			age:        time.Minute,
+			calls:      2,
+			revalidate: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, calls := etagServer(t, "predictions")
+
+			now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			c := newResponseCache(t.TempDir(), func() time.Time { return now }, slog.New(slog.DiscardHandler))
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/predictions", nil)
+			require.NoError(t, err)
+
+			first, err := c.fetch(srv.Client(), req, departuresTTL)
+			require.NoError(t, err)
+			assert.False(t, first.Cached)
+
+			fetched := now
+			now = now.Add(tc.age)
+
+			req, err = http.NewRequest(http.MethodGet, srv.URL+"/predictions", nil)
+			require.NoError(t, err)
+
+			second, err := c.fetch(srv.Client(), req, departuresTTL)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.calls, calls.Load())
+			assert.Equal(t, http.StatusOK, second.Status)
+			assert.Equal(t, "predictions", string(second.Body))
+
+			if !tc.revalidate {
+				assert.True(t, second.Cached)
+				assert.Equal(t, fetched, second.Fetched)
+				return
+			}
+
+			assert.False(t, second.Cached, "the upstream just confirmed a revalidated body")
+
+			req, err = http.NewRequest(http.MethodGet, srv.URL+"/predictions", nil)
+			require.NoError(t, err)
+
+			third, err := c.fetch(srv.Client(), req, departuresTTL)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.calls, calls.Load())
+			assert.True(t, third.Cached)
+			assert.Equal(t, now, third.Fetched, "a 304 restarts the entry's clock")
+		})
+	}
+}
+
+func TestResponseCacheSkipsErrors(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := statusSequence(t, nil, http.StatusNotFound)
+	c := newResponseCache(t.TempDir(), time.Now, slog.New(slog.DiscardHandler))
+
+	for range 2 {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		_, err = c.fetch(srv.Client(), req, time.Hour)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(2), calls.Load(), "the 404 should not have been stored")
+}
+
+func TestNilResponseCache(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := statusSequence(t, nil)
+
+	var c *responseCache
+	for range 2 {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		res, err := c.fetch(srv.Client(), req, time.Hour)
+		require.NoError(t, err)
+		assert.False(t, res.Cached)
+	}
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestScrubURL(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse("https://api.511.org/transit/StopMonitoring?agency=BA&api_key=secret&stopcode=123")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://api.511.org/transit/StopMonitoring?agency=BA&stopcode=123", scrubURL(u))
+}
@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cassetteFile is the name a cassette is saved under inside its directory.
+const cassetteFile = "cassette.json"
+
+// Cassette is every exchange a run had with the upstream APIs, in the order they
+// happened, along with what the run thought the time was. Secrets are scrubbed
+// before anything is kept, so a cassette can be attached to a bug report.
+type Cassette struct {
+	Now          time.Time     `json:"now"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and what came back for it.
+type Interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`              // Without the api_key.
+	Status int         `json:"status,omitempty"` // Zero when the request failed before a response.
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Err    string      `json:"error,omitempty"`      // Why the request failed, if it did.
+	Kind   string      `json:"error_kind,omitempty"` // What sort of failure Err was, see errKind.
+}
+
+// What kind of failure an interaction recorded. Callers decide whether to give up
+// by the error's type, so a replay has to hand back the same type, not just the text.
+const (
+	errKindCanceled = "canceled"
+	errKindTimeout  = "timeout"
+	errKindDNS      = "dns"
+	errKindNetwork  = "network"
+)
+
+// errKind names the kind of failure err is, or "" when it's none we rebuild.
+func errKind(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return errKindCanceled
+	}
+
+	// Before timeout, a lookup that timed out is still a lookup that failed.
+	if _, ok := errors.AsType[*net.DNSError](err); ok {
+		return errKindDNS
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errKindTimeout
+	}
+
+	if netErr, ok := errors.AsType[net.Error](err); ok && netErr.Timeout() {
+		return errKindTimeout
+	}
+
+	if _, ok := errors.AsType[*net.OpError](err); ok {
+		return errKindNetwork
+	}
+
+	return ""
+}
+
+// replayedError is a recorded failure: it reads as the original message and
+// unwraps to an error of the kind that was recorded.
+type replayedError struct {
+	msg  string
+	kind error
+}
+
+func (e *replayedError) Error() string { return e.msg }
+func (e *replayedError) Unwrap() error { return e.kind }
+
+// replayErr rebuilds the failure an interaction recorded.
+func (i Interaction) replayErr() error {
+	var kind error
+	switch i.Kind {
+	case errKindCanceled:
+		kind = context.Canceled
+	case errKindTimeout:
+		kind = context.DeadlineExceeded
+	case errKindDNS:
+		kind = &net.DNSError{Err: i.Err}
+	case errKindNetwork:
+		kind = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New(i.Err)}
+	default:
+		return errors.New(i.Err)
+	}
+
+	return &replayedError{msg: i.Err, kind: kind}
+}
+
+// LoadCassette reads the cassette saved in dir.
+func LoadCassette(dir string) (*Cassette, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, cassetteFile))
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to dir, creating it if it doesn't exist.
+func (c *Cassette) Save(dir string) error {
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create cassette dir: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, cassetteFile), raw, 0o644)
+}
+
+// Recorder is a transport that sends requests through the shared transport and keeps
+// a scrubbed copy of every exchange. Retries happen below it, so only the final
+// answer to each request is kept.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder starts a cassette for a run that believes it's now.
+func NewRecorder(now time.Time) *Recorder {
+	return &Recorder{next: sharedTransport, cassette: Cassette{Now: now}}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+
+	i := Interaction{Method: req.Method, URL: scrubURL(req.URL)}
+
+	switch {
+	case err != nil:
+		i.Err = err.Error()
+		i.Kind = errKind(err)
+	default:
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		i.Status = resp.StatusCode
+		i.Header = scrubHeader(resp.Header)
+		i.Body = string(body)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.mu.Unlock()
+
+	return resp, err
+}
+
+// Cassette returns what has been recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := Cassette{Now: r.cassette.Now, Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+	return &c
+}
+
+// Replayer is a transport that answers from a cassette and never reaches the network.
+// Each request is answered by the first recorded interaction with the same method
+// and URL that hasn't been used yet, so repeated requests replay in order.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer answers requests from c.
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{cassette: c, used: make([]bool, len(c.Interactions))}
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := scrubURL(req.URL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for n, i := range r.cassette.Interactions {
+		if r.used[n] || i.Method != req.Method || i.URL != url {
+			continue
+		}
+
+		r.used[n] = true
+
+		if i.Err != "" {
+			return nil, i.replayErr()
+		}
+
+		return &http.Response{
+			Status:     http.StatusText(i.Status),
+			StatusCode: i.Status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     i.Header.Clone(),
+			Body:       io.NopCloser(bytes.NewBufferString(i.Body)),
+			Request:    req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("replay: the cassette has no response left for %s %s", req.Method, url)
+}
+
+// scrubHeader drops response headers that could identify the account that made the request.
+func scrubHeader(h http.Header) http.Header {
+	out := h.Clone()
+	out.Del("Set-Cookie")
+
+	return out
+}
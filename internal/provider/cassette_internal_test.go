@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassetteRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = io.WriteString(w, "stop "+r.URL.Query().Get("stopcode"))
+	}))
+	t.Cleanup(srv.Close)
+
+	now := time.Date(2026, 8, 9, 17, 30, 0, 0, time.UTC)
+	rec := NewRecorder(now)
+	rec.next = http.DefaultTransport
+
+	get := func(t *testing.T, client *http.Client, stop string) string {
+		t.Helper()
+
+		res, err := client.Get(srv.URL + "/StopMonitoring?api_key=secret&stopcode=" + stop)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	recording := &http.Client{Transport: rec}
+	assert.Equal(t, "stop 1", get(t, recording, "1"))
+	assert.Equal(t, "stop 2", get(t, recording, "2"))
+
+	dir := t.TempDir()
+	require.NoError(t, rec.Cassette().Save(dir))
+
+	cassette, err := LoadCassette(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, now, cassette.Now)
+	require.Len(t, cassette.Interactions, 2)
+	for _, i := range cassette.Interactions {
+		assert.NotContains(t, i.URL, "secret")
+		assert.Empty(t, i.Header.Get("Set-Cookie"))
+	}
+
+	replaying := &http.Client{Transport: NewReplayer(cassette)}
+	assert.Equal(t, "stop 2", get(t, replaying, "2"), "requests are matched by URL, not by position")
+	assert.Equal(t, "stop 1", get(t, replaying, "1"))
+	assert.Equal(t, 2, calls, "a replay should never reach the server")
+
+	_, err = replaying.Get(srv.URL + "/StopMonitoring?api_key=secret&stopcode=1")
+	assert.ErrorContains(t, err, "no response left", "each interaction is replayed once")
+}
+
+func TestCassetteReplaysErrorKinds(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err  error
+		kind string
+		is   func(error) bool
+	}{
+		"canceled": {
+			err:  context.Canceled,
+			kind: errKindCanceled,
+			is:   func(err error) bool { return errors.Is(err, context.Canceled) },
+		},
+		"timeout": {
+			err:  context.DeadlineExceeded,
+			kind: errKindTimeout,
+			is: func(err error) bool {
+				netErr, ok := errors.AsType[net.Error](err)
+				return errors.Is(err, context.DeadlineExceeded) && ok && netErr.Timeout()
+			},
+		},
+		"dns": {
+			err:  &net.DNSError{Err: "no such host", Name: "api.511.org", IsNotFound: true},
+			kind: errKindDNS,
+			is:   func(err error) bool { _, ok := errors.AsType[*net.DNSError](err); return ok },
+		},
+		"network": {
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			kind: errKindNetwork,
+			is:   func(err error) bool { _, ok := errors.AsType[*net.OpError](err); return ok },
+		},
+		"other": {
+			err:  errors.New("something else"),
+			kind: "",
+			is:   func(err error) bool { return err != nil },
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := NewRecorder(time.Now())
+			rec.next = roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, tc.err })
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.511.org/StopMonitoring?api_key=secret", nil)
+			require.NoError(t, err)
+
+			_, err = rec.RoundTrip(req)
+			require.ErrorIs(t, err, tc.err)
+
+			dir := t.TempDir()
+			require.NoError(t, rec.Cassette().Save(dir))
+
+			cassette, err := LoadCassette(dir)
+			require.NoError(t, err)
+			require.Len(t, cassette.Interactions, 1)
+			assert.Equal(t, tc.kind, cassette.Interactions[0].Kind)
+
+			_, err = NewReplayer(cassette).RoundTrip(req)
+			require.Error(t, err)
+			assert.Equal(t, tc.err.Error(), err.Error(), "the message is replayed as it was recorded")
+			assert.True(t, tc.is(err), "replayed %T", err)
+		})
+	}
+}
+
+// roundTripFunc lets a plain function stand in for the transport a recorder sends through.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
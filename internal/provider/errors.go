@@ -3,6 +3,7 @@ package provider
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ErrMissingAPIKey is returned when the configured location has no credentials.
@@ -17,3 +18,24 @@ type HTTPError struct {
 func (e *HTTPError) Error() string {
 	return fmt.Sprintf("%s: unexpected status %d", e.URL, e.StatusCode)
 }
+
+// RateLimitError is returned when the requests allowed for a host are spent, either by
+// the local budget or because the upstream kept answering 429.
+type RateLimitError struct {
+	Host       string
+	RetryAfter time.Duration // How long until a request would be allowed again. Zero when unknown.
+	Upstream   bool          // The upstream refused the request rather than the local budget.
+}
+
+func (e *RateLimitError) Error() string {
+	who := "request budget spent"
+	if e.Upstream {
+		who = "rate limited upstream"
+	}
+
+	if e.RetryAfter <= 0 {
+		return fmt.Sprintf("%s: %s", e.Host, who)
+	}
+
+	return fmt.Sprintf("%s: %s, retry in %s", e.Host, who, e.RetryAfter.Round(time.Second))
+}
@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultConcurrency is how many requests a client has in flight at once when
+// [WithConcurrency] isn't given.
+const defaultConcurrency = 4
+
+// fanOut calls fetch for every item with at most limit calls running at once. Results
+// and errors come back in the order of items, whichever call finished first, so the
+// caller sees the same output as a sequential loop would have produced.
+func fanOut[T, R any](ctx context.Context, items []T, limit int, fetch func(context.Context, T) (R, error)) ([]R, []error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+
+	sem := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+
+	for n, item := range items {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			results[n], errs[n] = fetch(ctx, item)
+		})
+	}
+
+	wg.Wait()
+
+	return results, errs
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/fixtures"
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOut(t *testing.T) {
+	t.Parallel()
+
+	const limit = 2
+
+	var running, peak atomic.Int32
+	items := []int{5, 1, 4, 2, 3}
+
+	results, errs := fanOut(t.Context(), items, limit, func(_ context.Context, n int) (string, error) {
+		now := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			p := peak.Load()
+			if now <= p || peak.CompareAndSwap(p, now) {
+				break
+			}
+		}
+
+		// The first items take the longest, so they finish last.
+		time.Sleep(time.Duration(n) * time.Millisecond)
+
+		if n == 4 {
+			return "", errors.New("four failed")
+		}
+
+		return strconv.Itoa(n), nil
+	})
+
+	assert.Equal(t, []string{"5", "1", "", "2", "3"}, results)
+	assert.Equal(t, []error{nil, nil, errors.New("four failed"), nil, nil}, errs)
+	assert.LessOrEqual(t, peak.Load(), int32(limit))
+}
+
+func TestSFDeparturesKeepsRefOrder(t *testing.T) {
+	t.Parallel()
+
+	body := fixtures.Read(t, "511-stop-monitoring.json")
+
+	// Earlier stops answer slower, and one doesn't answer at all.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stop, _ := strconv.Atoi(r.URL.Query().Get("stopcode"))
+		if stop == 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		time.Sleep(time.Duration(5-stop) * 5 * time.Millisecond)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	sf := &SFClient{apiKey: "key", baseURL: srv.URL, http: srv.Client(), concurrency: 3}
+
+	refs := []transit.StopRef{
+		{AgencyID: "BA", StopID: "1", Name: "One"},
+		{AgencyID: "BA", StopID: "2", Name: "Two"},
+		{AgencyID: "BA", StopID: "3", Name: "Three"},
+		{AgencyID: "BA", StopID: "4", Name: "Four"},
+	}
+
+	set, err := sf.Departures(t.Context(), refs)
+	require.NoError(t, err)
+
+	var stops []string
+	for _, d := range set.Departures {
+		if len(stops) == 0 || stops[len(stops)-1] != d.StopID {
+			stops = append(stops, d.StopID)
+		}
+	}
+
+	assert.Equal(t, []string{"1", "3", "4"}, stops)
+
+	require.Len(t, set.Sources, 1)
+	assert.ErrorContains(t, set.Sources[0].Err, "departures at Two")
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ismailshak/transit/internal/config"
+	"github.com/ismailshak/transit/internal/gtfs"
+)
+
+// downloadFeed fetches the GTFS Static archive req points at and unzips it into the
+// config dir. The caller parses what it needs out of the returned directory, then calls
+// cleanup to remove it. name keeps concurrent downloads from sharing an archive.
+func downloadFeed(ctx context.Context, client *http.Client, req *http.Request, name string, log *slog.Logger) (string, func(), error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", nil, &HTTPError{StatusCode: resp.StatusCode, URL: scrubURL(req.URL)}
+	}
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	zipPath := filepath.Join(configDir, name+"_gtfs_static.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer func() {
+		f.Close() //nolint:errcheck // only here for the copy below, we close it ourselves after that
+		if err := os.RemoveAll(zipPath); err != nil {
+			log.DebugContext(ctx, "leftover gtfs archive", "path", zipPath, "err", err)
+		}
+	}()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return "", nil, fmt.Errorf("download gtfs archive: %w", err)
+	}
+
+	// Close it before we read it back, a bad write only shows up here
+	if err := f.Close(); err != nil {
+		return "", nil, fmt.Errorf("write gtfs archive %s: %w", zipPath, err)
+	}
+
+	dirName := name + "_gtfs_static_" + strconv.FormatInt(time.Now().Unix(), 10)
+	feed := filepath.Join(configDir, dirName)
+	if err = os.MkdirAll(feed, 0o755); err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		_ = os.RemoveAll(feed)
+	}
+
+	if err = gtfs.UnzipStaticGTFS(zipPath, feed); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return feed, cleanup, nil
+}
@@ -7,6 +7,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -24,14 +25,118 @@ const (
 // Makes testing easier.
 type staticLookup interface {
 	Agencies(ctx context.Context, location transit.LocationSlug) ([]transit.Agency, error)
+	StopNames(ctx context.Context, location transit.LocationSlug, stopIDs []string) (map[string]string, error)
+	TripRoutes(ctx context.Context, location transit.LocationSlug, tripIDs []string) (map[string]string, error)
+	StopsByLocation(ctx context.Context, location transit.LocationSlug, parentsOnly bool) ([]transit.Stop, error)
+}
+
+// Option configures a client built by [NewDMV] or [NewSF].
+type Option func(*options)
+
+type options struct {
+	cacheDir    string
+	transport   http.RoundTripper
+	concurrency int
+	log         *slog.Logger
+	language    string
+	now         func() time.Time
+}
+
+// WithCache keeps responses under dir, so that asking again within a few seconds
+// (from this process or another) is answered without an upstream call.
+func WithCache(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
+// WithTransport sends requests through rt instead of the shared transport, e.g. a
+// [Recorder] or a [Replayer].
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithConcurrency caps how many requests a client has in flight at once, for
+// providers that need one request per stop or agency. Less than 1 means the default.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithLogger writes requests, their status and latency, and cache hits to log at debug level.
+func WithLogger(log *slog.Logger) Option {
+	return func(o *options) {
+		o.log = log
+	}
+}
+
+// WithLanguage picks which of an upstream's translations riders see, e.g. "es". Text
+// an upstream only has in other languages falls back to the agency's own language.
+func WithLanguage(lang string) Option {
+	return func(o *options) {
+		o.language = lang
+	}
+}
+
+// WithClock reads the time from now instead of the wall clock, for dating responses
+// and aging the cache, e.g. the App's clock frozen by a replay.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+func buildOptions(opts []Option) options {
+	o := options{concurrency: defaultConcurrency, log: slog.New(slog.DiscardHandler), now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = defaultConcurrency
+	}
+
+	if o.log == nil {
+		o.log = slog.New(slog.DiscardHandler)
+	}
+
+	if o.now == nil {
+		o.now = time.Now
+	}
+
+	return o
+}
+
+// client returns the HTTP client a provider sends its requests with.
+func (o options) client() *http.Client {
+	var next http.RoundTripper = sharedTransport
+	if o.transport != nil {
+		next = o.transport
+	}
+
+	return &http.Client{Timeout: httpTimeout, Transport: &logTransport{next: next, log: o.log}}
+}
+
+// cache returns the response cache the options asked for, or nil for none.
+func (o options) cache() *responseCache {
+	if o.cacheDir == "" {
+		return nil
+	}
+
+	return newResponseCache(o.cacheDir, o.now, o.log)
 }
 
 // NewDMV builds a client for the DMV Metro Area, backed by WMATA.
-func NewDMV(apiKey string, now func() time.Time) (*WMATAClient, error) {
+func NewDMV(apiKey string, s staticLookup, opts ...Option) (*WMATAClient, error) {
 	if apiKey == "" {
 		return nil, ErrMissingAPIKey
 	}
 
+	o := buildOptions(opts)
+
 	location, err := time.LoadLocation(wmataTimezone)
 	if err != nil {
 		return nil, fmt.Errorf("load %s: %w", wmataTimezone, err)
@@ -40,22 +145,32 @@ func NewDMV(apiKey string, now func() time.Time) (*WMATAClient, error) {
 	return &WMATAClient{
 		apiKey:   apiKey,
 		baseURL:  wmataBaseURL,
-		http:     &http.Client{Timeout: httpTimeout},
+		store:    s,
+		http:     o.client(),
 		location: location,
-		now:      now,
+		now:      o.now,
+		cache:    o.cache(),
+		log:      o.log,
 	}, nil
 }
 
 // NewSF builds a client for the San Francisco Bay Area, backed by 511.
-func NewSF(apiKey string, s staticLookup) (*SFClient, error) {
+func NewSF(apiKey string, s staticLookup, opts ...Option) (*SFClient, error) {
 	if apiKey == "" {
 		return nil, ErrMissingAPIKey
 	}
 
+	o := buildOptions(opts)
+
 	return &SFClient{
 		apiKey:  apiKey,
 		baseURL: sfBaseURL,
-		http:    &http.Client{Timeout: httpTimeout},
+		http:    o.client(),
 		store:   s,
+		cache:   o.cache(),
+		log:     o.log,
+
+		concurrency: o.concurrency,
+		language:    o.language,
 	}, nil
 }
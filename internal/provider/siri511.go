@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"slices"
 	"strings"
@@ -18,6 +19,9 @@ import (
 
 const (
 	source511 = "bayarea511"
+
+	// defaultLanguage is the last resort before whichever translation came first.
+	defaultLanguage = "en"
 )
 
 // SFClient is the API to interact with San Francisco's 511 API.
@@ -26,6 +30,14 @@ type SFClient struct {
 	baseURL string
 	http    *http.Client
 	store   staticLookup
+	cache   *responseCache
+	log     *slog.Logger
+
+	// The language alerts are shown in when 511 has them in it.
+	language string
+
+	// How many stops or agencies are asked about at once.
+	concurrency int
 }
 
 type sfStopPlace struct {
@@ -91,6 +103,15 @@ type sfTranslatedText struct {
 	Translations []sfTranslation `json:"Translations"`
 }
 
+type sfInformedEntity struct {
+	AgencyID string `json:"AgencyId"`
+	StopID   string `json:"StopId"`
+	RouteID  string `json:"RouteId"`
+	Trip     struct {
+		TripID string `json:"TripId"`
+	} `json:"Trip"`
+}
+
 type sfServiceAlertsResponse struct {
 	Header struct {
 		GtfsRealtimeVersion string `json:"GtfsRealtimeVersion"`
@@ -104,18 +125,11 @@ type sfServiceAlertsResponse struct {
 				Start int64 `json:"Start"`
 				End   int64 `json:"End"`
 			} `json:"ActivePeriods"`
-			InformedEntities []struct {
-				AgencyID string `json:"AgencyId"`
-				StopID   string `json:"StopId"`
-				RouteID  string `json:"RouteId"`
-				Trip     struct {
-					TripID string `json:"TripId"`
-				} `json:"Trip"`
-			} `json:"InformedEntities"`
-			Cause           int              `json:"cause"`
-			Effect          int              `json:"effect"`
-			HeaderText      sfTranslatedText `json:"HeaderText"`
-			DescriptionText sfTranslatedText `json:"DescriptionText"`
+			InformedEntities []sfInformedEntity `json:"InformedEntities"`
+			Cause            int                `json:"cause"`
+			Effect           int                `json:"effect"`
+			HeaderText       sfTranslatedText   `json:"HeaderText"`
+			DescriptionText  sfTranslatedText   `json:"DescriptionText"`
 		} `json:"Alert"`
 	} `json:"Entities"`
 }
@@ -129,6 +143,30 @@ func newSFHTTPError(req *http.Request, statusCode int) *HTTPError {
 
 }
 
+// Ping lists the operators 511 knows, its smallest authenticated response. It's never cached.
+func (sf *SFClient) Ping(ctx context.Context) error {
+	req, err := sf.BuildRequest(ctx, http.MethodGet, "transit", "operators")
+	if err != nil {
+		return err
+	}
+
+	q := req.URL.Query()
+	q.Add("api_key", sf.apiKey)
+	q.Add("format", "json")
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := send(sf.http, req)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != 200 {
+		return newSFHTTPError(req, resp.Status)
+	}
+
+	return nil
+}
+
 func (sf *SFClient) BuildRequest(ctx context.Context, method string, route ...string) (*http.Request, error) {
 	parts := make([]string, 0, len(route)+1)
 	parts = append(parts, sf.baseURL)
@@ -214,6 +252,44 @@ func (sf *SFClient) fetchStopsForAgency(ctx context.Context, agency transit.Agen
 	return stops, nil
 }
 
+// fetchScheduleForAgency reads the trips and the patterns they run out of the
+// agency's GTFS feed. Trips only put names on alerts and patterns only plan trips,
+// so a failed download is logged and seeding carries on without them.
+func (sf *SFClient) fetchScheduleForAgency(ctx context.Context, agency transit.Agency) ([]transit.Trip, []transit.Pattern) {
+	req, err := sf.BuildRequest(ctx, http.MethodGet, "transit", "datafeeds")
+	if err != nil {
+		sf.log.DebugContext(ctx, "fetch schedule", "agency", agency.AgencyID, "err", err)
+		return nil, nil
+	}
+
+	q := req.URL.Query()
+	q.Add("api_key", sf.apiKey)
+	q.Add("operator_id", agency.AgencyID)
+	req.URL.RawQuery = q.Encode()
+
+	feed, cleanup, err := downloadFeed(ctx, sf.http, req, "511_"+agency.AgencyID, sf.log)
+	if err != nil {
+		sf.log.DebugContext(ctx, "fetch schedule", "agency", agency.AgencyID, "err", err)
+		return nil, nil
+	}
+
+	defer cleanup()
+
+	trips, err := gtfs.ParseGTFSTrips(feed)
+	if err != nil {
+		sf.log.DebugContext(ctx, "fetch schedule", "agency", agency.AgencyID, "err", err)
+		return nil, nil
+	}
+
+	patterns, err := gtfs.ParseGTFSPatterns(feed, trips)
+	if err != nil {
+		sf.log.DebugContext(ctx, "fetch schedule", "agency", agency.AgencyID, "err", err)
+		return trips, nil
+	}
+
+	return trips, patterns
+}
+
 func (sf *SFClient) Seed(ctx context.Context) (*transit.Static, error) {
 	bart := transit.Agency{
 		AgencyID: "BA",
@@ -241,18 +317,23 @@ func (sf *SFClient) Seed(ctx context.Context) (*transit.Static, error) {
 		return nil, fmt.Errorf("fetch Caltrain stops: %w", err)
 	}
 
+	bartTrips, bartPatterns := sf.fetchScheduleForAgency(ctx, bart)
+	calTrips, calPatterns := sf.fetchScheduleForAgency(ctx, cal)
+
 	staticData := transit.Static{
 		Agencies: []transit.Agency{bart, cal},
 		Stops:    slices.Concat(bartStops, calStops),
+		Trips:    slices.Concat(bartTrips, calTrips),
+		Patterns: slices.Concat(bartPatterns, calPatterns),
 	}
 
 	return &staticData, nil
 }
 
-func (sf *SFClient) fetchDepartures(ctx context.Context, ref transit.StopRef) ([]transit.Departure, time.Time, error) {
+func (sf *SFClient) fetchDepartures(ctx context.Context, ref transit.StopRef) ([]transit.Departure, time.Time, bool, error) {
 	req, err := sf.BuildRequest(ctx, http.MethodGet, "transit", "StopMonitoring")
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
 	q := req.URL.Query()
@@ -262,30 +343,23 @@ func (sf *SFClient) fetchDepartures(ctx context.Context, ref transit.StopRef) ([
 	q.Add("format", "json")
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := sf.http.Do(req)
+	resp, err := sf.cache.fetch(sf.http, req, departuresTTL)
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, time.Time{}, newSFHTTPError(req, resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, time.Time{}, err
+	if resp.Status != 200 {
+		return nil, time.Time{}, false, newSFHTTPError(req, resp.Status)
 	}
 
 	// Remove BOM from response
-	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	body := bytes.TrimPrefix(resp.Body, []byte("\xef\xbb\xbf"))
 
 	var stopMonitoring sfStopMonitoringResponse
 
 	err = json.Unmarshal(body, &stopMonitoring)
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
 	// A missing or malformed timestamp should only affect the age and not the departure list.
@@ -310,7 +384,7 @@ func (sf *SFClient) fetchDepartures(ctx context.Context, ref transit.StopRef) ([
 
 		arrivalTime, err := time.Parse(time.RFC3339, arrivalString)
 		if err != nil {
-			return nil, time.Time{}, err
+			return nil, time.Time{}, false, err
 		}
 
 		bg, fg := sfLineColor(mvj.LineRef)
@@ -332,23 +406,36 @@ func (sf *SFClient) fetchDepartures(ctx context.Context, ref transit.StopRef) ([
 		departures = append(departures, d)
 	}
 
-	return departures, asOf, nil
+	return departures, asOf, resp.Cached, nil
+}
+
+// sfResult is what one request to 511 produced, so fanOut can carry it as one value.
+type sfResult[T any] struct {
+	items  []T
+	asOf   time.Time
+	cached bool
 }
 
 func (sf *SFClient) Departures(ctx context.Context, refs []transit.StopRef) (transit.DepartureSet, error) {
+	results, fetchErrs := fanOut(ctx, refs, sf.concurrency, func(ctx context.Context, r transit.StopRef) (sfResult[transit.Departure], error) {
+		d, t, hit, err := sf.fetchDepartures(ctx, r)
+		return sfResult[transit.Departure]{items: d, asOf: t, cached: hit}, err
+	})
+
 	var departures []transit.Departure
 	var errs []error
 	var asOf time.Time
+	var cached bool
 
-	for _, r := range refs {
-		d, t, err := sf.fetchDepartures(ctx, r)
-		if err != nil {
+	for n, r := range refs {
+		if err := fetchErrs[n]; err != nil {
 			errs = append(errs, fmt.Errorf("departures at %s: %w", r.Name, err))
 			continue
 		}
 
-		departures = append(departures, d...)
-		asOf = older(asOf, t)
+		departures = append(departures, results[n].items...)
+		asOf = older(asOf, results[n].asOf)
+		cached = cached || results[n].cached
 	}
 
 	// Every stop lost its request, so there's no set to hand back and nothing to date it with.
@@ -362,6 +449,7 @@ func (sf *SFClient) Departures(ctx context.Context, refs []transit.StopRef) (tra
 			Source: source511,
 			AsOf:   asOf,
 			Err:    errors.Join(errs...),
+			Cached: cached,
 		}},
 	}, nil
 }
@@ -372,20 +460,26 @@ func (sf *SFClient) Alerts(ctx context.Context) (transit.AlertSet, error) {
 		return transit.AlertSet{}, err
 	}
 
+	// 511 only answers for one agency at a time.
+	results, fetchErrs := fanOut(ctx, agencies, sf.concurrency, func(ctx context.Context, agency transit.Agency) (sfResult[transit.Alert], error) {
+		a, t, hit, err := sf.fetchAgencyAlerts(ctx, agency)
+		return sfResult[transit.Alert]{items: a, asOf: t, cached: hit}, err
+	})
+
 	var alerts []transit.Alert
 	var errs []error
 	var asOf time.Time
+	var cached bool
 
-	// TODO surely there's a way to fetch multiple agencies at once
-	for _, agency := range agencies {
-		a, t, err := sf.fetchAgencyAlerts(ctx, agency)
-		if err != nil {
+	for n, agency := range agencies {
+		if err := fetchErrs[n]; err != nil {
 			errs = append(errs, fmt.Errorf("alerts for %s: %w", agency.Name, err))
 			continue
 		}
 
-		alerts = append(alerts, a...)
-		asOf = older(asOf, t)
+		alerts = append(alerts, results[n].items...)
+		asOf = older(asOf, results[n].asOf)
+		cached = cached || results[n].cached
 	}
 
 	return transit.AlertSet{
@@ -394,14 +488,15 @@ func (sf *SFClient) Alerts(ctx context.Context) (transit.AlertSet, error) {
 			Source: source511,
 			AsOf:   asOf,
 			Err:    errors.Join(errs...),
+			Cached: cached,
 		}},
 	}, nil
 }
 
-func (sf *SFClient) fetchAgencyAlerts(ctx context.Context, agency transit.Agency) ([]transit.Alert, time.Time, error) {
+func (sf *SFClient) fetchAgencyAlerts(ctx context.Context, agency transit.Agency) ([]transit.Alert, time.Time, bool, error) {
 	req, err := sf.BuildRequest(ctx, http.MethodGet, "transit", "servicealerts")
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
 	q := req.URL.Query()
@@ -410,81 +505,138 @@ func (sf *SFClient) fetchAgencyAlerts(ctx context.Context, agency transit.Agency
 	q.Add("format", "json")
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := sf.http.Do(req)
+	resp, err := sf.cache.fetch(sf.http, req, alertsTTL)
 	if err != nil {
-		return nil, time.Time{}, err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, time.Time{}, newSFHTTPError(req, resp.StatusCode)
+		return nil, time.Time{}, false, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, time.Time{}, err
+	if resp.Status != 200 {
+		return nil, time.Time{}, false, newSFHTTPError(req, resp.Status)
 	}
 
 	// Remove BOM from response
-	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	body := bytes.TrimPrefix(resp.Body, []byte("\xef\xbb\xbf"))
 
 	var serviceAlerts sfServiceAlertsResponse
 	err = json.Unmarshal(body, &serviceAlerts)
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
 	asOf := sfTimestamp(serviceAlerts.Header.Timestamp)
 
+	var informed []sfInformedEntity
+	for _, entity := range serviceAlerts.Entities {
+		informed = append(informed, entity.Alert.InformedEntities...)
+	}
+
+	names := sf.refNames(ctx, informed)
+
 	var alerts []transit.Alert
 
 	for _, entity := range serviceAlerts.Entities {
-		var start, end time.Time
-		if len(entity.Alert.ActivePeriods) > 0 {
-			start = sfTimestamp(entity.Alert.ActivePeriods[0].Start)
-			end = sfTimestamp(entity.Alert.ActivePeriods[0].End)
+		periods := make([]transit.ActivePeriod, 0, len(entity.Alert.ActivePeriods))
+		for _, p := range entity.Alert.ActivePeriods {
+			periods = append(periods, transit.ActivePeriod{
+				Starts: sfTimestamp(p.Start),
+				Ends:   sfTimestamp(p.End),
+			})
 		}
 
-		var affected []transit.AlertRef
-		// TODO: StopID could be duplicated because they would have different RouteIDs * sigh *
-		// TODO: Trip.TripID needs the trips.txt join from P5b-1 before it can become a route ref
-		// Agency is on every informed entity. The alert already knows which agency is affected.
-		for _, e := range entity.Alert.InformedEntities {
-			if e.StopID != "" {
-				affected = append(affected, transit.AlertRef{
-					Kind: transit.RefStop,
-					ID:   e.StopID,
-				})
-			}
-
-			if e.RouteID != "" {
-				bg, fg := sfLineColor(e.RouteID)
-				affected = append(affected, transit.AlertRef{
-					Kind:      transit.RefRoute,
-					ID:        e.RouteID,
-					Color:     bg,
-					TextColor: fg,
-				})
-			}
-		}
+		translations := alertTranslations(entity.Alert.HeaderText, entity.Alert.DescriptionText)
 
 		alert := transit.Alert{
+			ID:          entity.ID,
 			Source:      source511,
-			Description: alertText(entity.Alert.HeaderText, entity.Alert.DescriptionText),
+			Description: transit.Translate(translations, sf.language, agency.Language, defaultLanguage),
 			Effect:      gtfs.ResolveGTFSAlertEffect(entity.Alert.Effect),
+			Cause:       gtfs.ResolveGTFSAlertCause(entity.Alert.Cause),
 			AgencyID:    agency.AgencyID,
-			Affected:    affected,
-			Starts:      start, // TODO: Figure out what scenario triggers multiple active periods
-			Ends:        end,   // TODO: ^ here too
+			Affected:    names.refs(entity.Alert.InformedEntities),
+			Periods:     periods,
 			Updated:     asOf,
+
+			Translations: translations,
 		}
 
 		alerts = append(alerts, alert)
 	}
 
-	return alerts, asOf, nil
+	return alerts, asOf, resp.Cached, nil
+}
+
+// sfRefNames is what the store knows about the stops and trips an agency's alerts mention.
+type sfRefNames struct {
+	stops map[string]string // Stop ID to its name.
+	trips map[string]string // Trip ID to the route it runs on.
+}
+
+// refNames looks up every stop and trip in informed at once. A failed lookup only
+// costs the names, so it's logged and the alerts go out with raw IDs.
+func (sf *SFClient) refNames(ctx context.Context, informed []sfInformedEntity) sfRefNames {
+	var stopIDs, tripIDs []string
+	for _, e := range informed {
+		if e.StopID != "" {
+			stopIDs = append(stopIDs, e.StopID)
+		}
+
+		if e.Trip.TripID != "" {
+			tripIDs = append(tripIDs, e.Trip.TripID)
+		}
+	}
+
+	var names sfRefNames
+	var err error
+
+	if names.stops, err = sf.store.StopNames(ctx, transit.SFSlug, stopIDs); err != nil {
+		sf.log.DebugContext(ctx, "resolve alert stops", "err", err)
+	}
+
+	if names.trips, err = sf.store.TripRoutes(ctx, transit.SFSlug, tripIDs); err != nil {
+		sf.log.DebugContext(ctx, "resolve alert trips", "err", err)
+	}
 
+	return names
+}
+
+// refs turns an alert's informed entities into the stops and routes it affects.
+// 511 repeats a stop once per route through it, and a trip names its route
+// indirectly, so each entity only appears once however many times it was sent.
+func (n sfRefNames) refs(informed []sfInformedEntity) []transit.AlertRef {
+	var affected []transit.AlertRef
+	seen := make(map[transit.AlertRef]struct{})
+
+	add := func(ref transit.AlertRef) {
+		key := transit.AlertRef{Kind: ref.Kind, ID: ref.ID}
+		if _, ok := seen[key]; ok {
+			return
+		}
+
+		seen[key] = struct{}{}
+		affected = append(affected, ref)
+	}
+
+	route := func(id string) transit.AlertRef {
+		bg, fg := sfLineColor(id)
+		return transit.AlertRef{Kind: transit.RefRoute, ID: id, Color: bg, TextColor: fg}
+	}
+
+	// Agency is on every informed entity. The alert already knows which agency is affected.
+	for _, e := range informed {
+		if e.StopID != "" {
+			add(transit.AlertRef{Kind: transit.RefStop, ID: e.StopID, Name: n.stops[e.StopID]})
+		}
+
+		if e.RouteID != "" {
+			add(route(e.RouteID))
+		}
+
+		if routeID, ok := n.trips[e.Trip.TripID]; ok && e.RouteID == "" {
+			add(route(routeID))
+		}
+	}
+
+	return affected
 }
 
 func older(a, b time.Time) time.Time {
@@ -508,11 +660,34 @@ func sfTimestamp(sec int64) time.Time {
 	return time.Unix(sec, 0)
 }
 
+// alertTranslations pairs up the header and description in each language 511 sent
+// either of them in. Languages keep the order they first appear in.
+func alertTranslations(header, description sfTranslatedText) []transit.Translation {
+	var languages []string
+	for _, tr := range slices.Concat(header.Translations, description.Translations) {
+		if !slices.Contains(languages, tr.Language) {
+			languages = append(languages, tr.Language)
+		}
+	}
+
+	translations := make([]transit.Translation, 0, len(languages))
+	for _, lang := range languages {
+		text := alertText(header, description, lang)
+		if text == "" {
+			continue
+		}
+
+		translations = append(translations, transit.Translation{Language: lang, Text: text})
+	}
+
+	return translations
+}
+
 // Caltrain sends its text in HeaderText and leaves DescriptionText empty.
 // BART sends a banner like "BART.gov Alert" as the header and the real text as the description.
-func alertText(header, description sfTranslatedText) string {
-	headerText := strings.TrimSpace(englishText(header))
-	descriptionText := strings.TrimSpace(englishText(description))
+func alertText(header, description sfTranslatedText, language string) string {
+	headerText := strings.TrimSpace(translatedText(header, language))
+	descriptionText := strings.TrimSpace(translatedText(description, language))
 
 	if headerText == "" {
 		return descriptionText
@@ -526,9 +701,9 @@ func alertText(header, description sfTranslatedText) string {
 }
 
 // 511 ships four languages and doesn't promise an order.
-func englishText(t sfTranslatedText) string {
+func translatedText(t sfTranslatedText, language string) string {
 	for _, tr := range t.Translations {
-		if tr.Language == "en" {
+		if tr.Language == language {
 			return tr.Text
 		}
 	}
@@ -1,8 +1,11 @@
 package provider
 
 import (
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
 )
 
 func TestSFTimestamp(t *testing.T) {
@@ -135,7 +138,7 @@ func TestAlertText(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			got := alertText(tc.header, tc.description)
+			got := alertText(tc.header, tc.description, "en")
 
 			if got != tc.want {
 				t.Errorf("expected %q but got %q", tc.want, got)
@@ -143,3 +146,89 @@ func TestAlertText(t *testing.T) {
 		})
 	}
 }
+
+func TestAlertTranslations(t *testing.T) {
+	t.Parallel()
+
+	header := sfTranslatedText{Translations: []sfTranslation{
+		{Language: "en", Text: "Platform change"},
+		{Language: "es", Text: "Cambio de andén"},
+		{Language: "vi", Text: ""},
+	}}
+	description := sfTranslatedText{Translations: []sfTranslation{
+		{Language: "es", Text: "El tren 519 sale del andén 5"},
+		{Language: "zh", Text: "519次列车从5号站台出发"},
+	}}
+
+	got := alertTranslations(header, description)
+
+	want := []transit.Translation{
+		{Language: "en", Text: "Platform change"},
+		{Language: "es", Text: "Cambio de andén: El tren 519 sale del andén 5"},
+		{Language: "zh", Text: "519次列车从5号站台出发"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+}
+
+func TestSFRefNames(t *testing.T) {
+	t.Parallel()
+
+	entity := func(stop, route, trip string) sfInformedEntity {
+		e := sfInformedEntity{AgencyID: "BA", StopID: stop, RouteID: route}
+		e.Trip.TripID = trip
+		return e
+	}
+
+	names := sfRefNames{
+		stops: map[string]string{"902101": "Embarcadero"},
+		trips: map[string]string{"1521057": "Red-N"},
+	}
+
+	red := transit.AlertRef{Kind: transit.RefRoute, ID: "Red-N", Color: "#ED1D24", TextColor: "#000000"}
+	yellow := transit.AlertRef{Kind: transit.RefRoute, ID: "Yellow-S", Color: "#FFE600", TextColor: "#000000"}
+
+	tests := map[string]struct {
+		informed []sfInformedEntity
+		want     []transit.AlertRef
+	}{
+		"a known stop gets its name": {
+			informed: []sfInformedEntity{entity("902101", "", "")},
+			want:     []transit.AlertRef{{Kind: transit.RefStop, ID: "902101", Name: "Embarcadero"}},
+		},
+		"an unknown stop keeps its ID": {
+			informed: []sfInformedEntity{entity("999999", "", "")},
+			want:     []transit.AlertRef{{Kind: transit.RefStop, ID: "999999"}},
+		},
+		"a stop repeated per route is listed once": {
+			informed: []sfInformedEntity{entity("902101", "Red-N", ""), entity("902101", "Yellow-S", "")},
+			want:     []transit.AlertRef{{Kind: transit.RefStop, ID: "902101", Name: "Embarcadero"}, red, yellow},
+		},
+		"a trip becomes its route": {
+			informed: []sfInformedEntity{entity("", "", "1521057")},
+			want:     []transit.AlertRef{red},
+		},
+		"a trip on a route already listed adds nothing": {
+			informed: []sfInformedEntity{entity("", "Red-N", ""), entity("", "", "1521057")},
+			want:     []transit.AlertRef{red},
+		},
+		"an unknown trip is dropped": {
+			informed: []sfInformedEntity{entity("", "", "0")},
+			want:     nil,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := names.refs(tc.informed)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v but got %+v", tc.want, got)
+			}
+		})
+	}
+}
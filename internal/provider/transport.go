@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxRetries    = 3
+	baseBackoff   = 250 * time.Millisecond
+	maxBackoff    = 4 * time.Second
+	maxRetryAfter = 30 * time.Second // A longer wait isn't worth holding a command open for.
+)
+
+// hostBudgets caps the requests sent to a host in a window. 511 allows 60 requests an
+// hour per key and answers 429 once they're spent. The count only lives as long as the
+// process, so it mostly protects watch mode.
+var hostBudgets = map[string]budgetLimit{
+	"api.511.org": {requests: 60, window: time.Hour},
+}
+
+// sharedTransport is the transport every client sends through, so that clients built
+// in the same process draw from the same budgets.
+var sharedTransport = newTransport(http.DefaultTransport, hostBudgets)
+
+// newHTTPClient builds the client a provider sends its requests with. The timeout covers
+// the whole exchange, retries included.
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: httpTimeout, Transport: sharedTransport}
+}
+
+type budgetLimit struct {
+	requests int
+	window   time.Duration
+}
+
+// budget is a sliding window of the requests sent to one host.
+type budget struct {
+	limit budgetLimit
+	sent  []time.Time // Oldest first, and only the ones still inside the window.
+}
+
+// take records a request at now. When the budget is spent it reports how long
+// until the oldest request leaves the window instead.
+func (b *budget) take(now time.Time) (time.Duration, bool) {
+	cutoff := now.Add(-b.limit.window)
+	kept := b.sent[:0]
+	for _, t := range b.sent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	b.sent = kept
+
+	if len(b.sent) >= b.limit.requests {
+		return b.sent[0].Sub(cutoff), false
+	}
+
+	b.sent = append(b.sent, now)
+	return 0, true
+}
+
+// transport retries idempotent requests that failed for a reason the upstream could
+// recover from, with jittered exponential backoff or the wait the upstream asked for.
+// A spent budget, local or upstream, is returned as a [RateLimitError].
+type transport struct {
+	next    http.RoundTripper
+	limits  map[string]budgetLimit
+	retries int
+
+	// Swappable so tests don't have to wait.
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+
+	mu      sync.Mutex
+	budgets map[string]*budget
+}
+
+func newTransport(next http.RoundTripper, limits map[string]budgetLimit) *transport {
+	return &transport{
+		next:    next,
+		limits:  limits,
+		retries: maxRetries,
+		now:     time.Now,
+		sleep:   sleepCtx,
+		jitter:  fullJitter,
+		budgets: make(map[string]*budget),
+	}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		if wait, ok := t.take(req.URL.Host); !ok {
+			return nil, &RateLimitError{Host: req.URL.Host, RetryAfter: wait}
+		}
+
+		resp, err := t.next.RoundTrip(req)
+
+		wait, retry := t.backoff(req, resp, err, attempt)
+		if !retryable || !retry || attempt >= t.retries || !fitsDeadline(req.Context(), wait) {
+			return t.finish(req, resp, err)
+		}
+
+		discard(resp)
+
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// take draws one request from the host's budget. Hosts without a limit always have room.
+func (t *transport) take(host string) (time.Duration, bool) {
+	limit, ok := t.limits[host]
+	if !ok {
+		return 0, true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.budgets[host]
+	if !ok {
+		b = &budget{limit: limit}
+		t.budgets[host] = b
+	}
+
+	return b.take(t.now())
+}
+
+// backoff reports whether an attempt is worth repeating and how long to wait first.
+func (t *transport) backoff(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		// Nothing to gain from retrying a request the caller gave up on.
+		if req.Context().Err() != nil {
+			return 0, false
+		}
+
+		return t.jitter(exponential(attempt)), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if after, ok := retryAfter(resp.Header.Get("Retry-After"), t.now()); ok {
+			return after, after <= maxRetryAfter
+		}
+
+		return t.jitter(exponential(attempt)), true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return t.jitter(exponential(attempt)), true
+	default:
+		return 0, false
+	}
+}
+
+// finish hands back the last attempt. A 429 that outlasted the retries becomes a RateLimitError.
+func (t *transport) finish(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	after, _ := retryAfter(resp.Header.Get("Retry-After"), t.now())
+	discard(resp)
+
+	return nil, &RateLimitError{Host: req.URL.Host, RetryAfter: after, Upstream: true}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header, which is either seconds or an HTTP date.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+func exponential(attempt int) time.Duration {
+	return min(baseBackoff<<attempt, maxBackoff)
+}
+
+// fullJitter picks a wait between zero and d, so that clients backing off together don't
+// come back together.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	return rand.N(d) //nolint:gosec // spreading retries out, nothing to protect
+}
+
+// fitsDeadline reports whether waiting d still leaves the request time to run.
+func fitsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// discard drains and closes a response that won't be handed back, so its connection
+// can be reused.
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// logTransport writes every request that reaches it to log: the URL without
+// secrets, the status, and how long it took. It sits above the retries, so the
+// latency is what the caller waited.
+type logTransport struct {
+	next http.RoundTripper
+	log  *slog.Logger
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	attrs := []any{"method", req.Method, "url", scrubURL(req.URL), "elapsed", time.Since(start)}
+	if err != nil {
+		t.log.DebugContext(req.Context(), "http request failed", append(attrs, "err", err)...)
+		return resp, err
+	}
+
+	t.log.DebugContext(req.Context(), "http request", append(attrs, "status", resp.StatusCode)...)
+
+	return resp, nil
+}
@@ -0,0 +1,261 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testTransport returns a transport that records its waits instead of sleeping.
+func testTransport(limits map[string]budgetLimit) (*transport, *[]time.Duration) {
+	var waits []time.Duration
+
+	t := newTransport(http.DefaultTransport, limits)
+	t.jitter = func(d time.Duration) time.Duration { return d }
+	t.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	return t, &waits
+}
+
+// statusSequence answers with each status in turn, then 200 once they run out.
+func statusSequence(t *testing.T, headers http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		for k, v := range headers {
+			w.Header()[k] = v
+		}
+
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestTransportRetries(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		method   string
+		statuses []int
+		headers  http.Header
+		calls    int32
+		status   int
+		waits    []time.Duration
+	}{
+		"a success is not retried": {
+			method: http.MethodGet,
+			calls:  1,
+			status: http.StatusOK,
+		},
+		"5xx backs off exponentially": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+			calls:    3,
+			status:   http.StatusOK,
+			waits:    []time.Duration{baseBackoff, 2 * baseBackoff},
+		},
+		"Retry-After is honored": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusTooManyRequests},
+			headers:  http.Header{"Retry-After": []string{"2"}},
+			calls:    2,
+			status:   http.StatusOK,
+			waits:    []time.Duration{2 * time.Second},
+		},
+		"a Retry-After too long to wait out is not retried": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusServiceUnavailable},
+			headers:  http.Header{"Retry-After": []string{"3600"}},
+			calls:    1,
+			status:   http.StatusServiceUnavailable,
+		},
+		"gives up after the last retry": {
+			method:   http.MethodGet,
+			statuses: []int{500, 500, 500, 500, 500},
+			calls:    maxRetries + 1,
+			status:   http.StatusInternalServerError,
+			waits:    []time.Duration{baseBackoff, 2 * baseBackoff, 4 * baseBackoff},
+		},
+		"a client error is not retried": {
+			method:   http.MethodGet,
+			statuses: []int{http.StatusUnauthorized},
+			calls:    1,
+			status:   http.StatusUnauthorized,
+		},
+		"a POST is not retried": {
+			method:   http.MethodPost,
+			statuses: []int{http.StatusServiceUnavailable},
+			calls:    1,
+			status:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, calls := statusSequence(t, tc.headers, tc.statuses...)
+			tr, waits := testTransport(nil)
+
+			req, err := http.NewRequestWithContext(t.Context(), tc.method, srv.URL, nil)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("expected no error but got %v", err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.status {
+				t.Errorf("expected status %d but got %d", tc.status, resp.StatusCode)
+			}
+
+			if got := calls.Load(); got != tc.calls {
+				t.Errorf("expected %d calls but got %d", tc.calls, got)
+			}
+
+			if len(*waits) != len(tc.waits) {
+				t.Fatalf("expected waits %v but got %v", tc.waits, *waits)
+			}
+
+			for i, w := range tc.waits {
+				if (*waits)[i] != w {
+					t.Errorf("expected waits %v but got %v", tc.waits, *waits)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestTransportUpstreamRateLimit(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := statusSequence(t, http.Header{"Retry-After": []string{"1"}}, 429, 429, 429, 429)
+	tr, _ := testTransport(nil)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"?api_key=secret", nil)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	resp, err := tr.RoundTrip(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	rateErr, ok := errors.AsType[*RateLimitError](err)
+	if !ok {
+		t.Fatalf("expected a RateLimitError but got %v", err)
+	}
+
+	if !rateErr.Upstream || rateErr.RetryAfter != time.Second {
+		t.Errorf("expected an upstream limit with a 1s wait but got %+v", rateErr)
+	}
+
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected the api key to stay out of the error but got %q", err)
+	}
+}
+
+func TestTransportBudget(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := statusSequence(t, nil)
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	now := time.Date(2026, time.August, 21, 9, 0, 0, 0, time.UTC)
+	tr, _ := testTransport(map[string]budgetLimit{host: {requests: 2, window: time.Hour}})
+	tr.now = func() time.Time { return now }
+
+	get := func() error {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+
+		resp, err := tr.RoundTrip(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		return err
+	}
+
+	for range 2 {
+		if err := get(); err != nil {
+			t.Fatalf("expected no error within the budget but got %v", err)
+		}
+
+		now = now.Add(10 * time.Minute)
+	}
+
+	err := get()
+	rateErr, ok := errors.AsType[*RateLimitError](err)
+	if !ok {
+		t.Fatalf("expected a RateLimitError but got %v", err)
+	}
+
+	if rateErr.Upstream || rateErr.RetryAfter != 40*time.Minute {
+		t.Errorf("expected a local limit freeing up in 40m but got %+v", rateErr)
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected the spent budget to keep the request local but the server saw %d calls", got)
+	}
+
+	now = now.Add(40 * time.Minute)
+	if err := get(); err != nil {
+		t.Errorf("expected the window to free a request but got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.August, 21, 9, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		"absent":        {header: "", ok: false},
+		"seconds":       {header: "120", want: 2 * time.Minute, ok: true},
+		"an http date":  {header: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second, ok: true},
+		"a date passed": {header: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, ok: true},
+		"garbage":       {header: "soon", ok: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := retryAfter(tc.header, now)
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("expected (%v, %v) but got (%v, %v)", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+}
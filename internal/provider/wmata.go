@@ -4,23 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/ismailshak/transit/internal/config"
 	"github.com/ismailshak/transit/internal/gtfs"
 	"github.com/ismailshak/transit/internal/transit"
 )
 
 const (
-	wmataDateTimeLayout = "2006-01-02T15:04:05"
-	sourceWMATARail     = "wmata-rail"
-	agencyWMATA         = "MET"
+	wmataDateTimeLayout  = "2006-01-02T15:04:05"
+	sourceWMATARail      = "wmata-rail"
+	sourceWMATAElevators = "wmata-elevators"
+	agencyWMATA          = "MET"
 )
 
 // WMATAClient is the API to interact with WMATA.
@@ -30,6 +30,9 @@ type WMATAClient struct {
 	location *time.Location
 	http     *http.Client
 	now      func() time.Time
+	cache    *responseCache
+	log      *slog.Logger
+	store    staticLookup // Points elevator outages at seeded stations. Nil leaves them on WMATA's codes.
 }
 
 type wmataTrain struct {
@@ -62,96 +65,137 @@ type wmataIncidentsResponse struct {
 	Incidents []wmataIncident `json:"Incidents"`
 }
 
-func (w *WMATAClient) BuildRequest(ctx context.Context, method string, route ...string) (*http.Request, error) {
-	parts := make([]string, 0, len(route)+1)
-	parts = append(parts, w.baseURL)
-	parts = append(parts, route...)
-	url := strings.Join(parts, "/")
+type wmataElevatorIncident struct {
+	UnitName                 string `json:"UnitName"`
+	UnitType                 string `json:"UnitType"`
+	StationCode              string `json:"StationCode"`
+	StationName              string `json:"StationName"`
+	LocationDescription      string `json:"LocationDescription"`
+	SymptomDescription       string `json:"SymptomDescription"`
+	DateOutOfServ            string `json:"DateOutOfServ"`
+	DateUpdated              string `json:"DateUpdated"`
+	EstimatedReturnToService string `json:"EstimatedReturnToService"`
+}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, nil)
-	if err != nil {
-		return nil, err
-	}
+type wmataElevatorIncidentsResponse struct {
+	ElevatorIncidents []wmataElevatorIncident `json:"ElevatorIncidents"`
+}
 
-	req.Header.Add("api_key", w.apiKey)
+type wmataTrainPosition struct {
+	TrainID                string `json:"TrainId"`
+	CarCount               int    `json:"CarCount"`
+	DirectionNum           int    `json:"DirectionNum"`
+	CircuitID              int    `json:"CircuitId"`
+	DestinationStationCode string `json:"DestinationStationCode"`
+	LineCode               string `json:"LineCode"`
+	ServiceType            string `json:"ServiceType"`
+}
 
-	return req, nil
+type wmataTrainPositionsResponse struct {
+	TrainPositions []wmataTrainPosition `json:"TrainPositions"`
 }
 
-func (w *WMATAClient) Seed(ctx context.Context) (*transit.Static, error) {
-	req, err := w.BuildRequest(ctx, http.MethodGet, "gtfs/rail-gtfs-static.zip")
+type wmataTrackCircuit struct {
+	SeqNum      int    `json:"SeqNum"`
+	CircuitID   int    `json:"CircuitId"`
+	StationCode string `json:"StationCode"`
+}
+
+// wmataStandardRoute is the circuits along one track of a line, in the order trains
+// on that track pass them. Track 1 and 2 run opposite ways, a train's DirectionNum
+// is the track it's on.
+type wmataStandardRoute struct {
+	LineCode      string              `json:"LineCode"`
+	TrackNum      int                 `json:"TrackNum"`
+	TrackCircuits []wmataTrackCircuit `json:"TrackCircuits"`
+}
+
+type wmataStandardRoutesResponse struct {
+	StandardRoutes []wmataStandardRoute `json:"StandardRoutes"`
+}
+
+type wmataRailFare struct {
+	OffPeakTime    float64 `json:"OffPeakTime"`
+	PeakTime       float64 `json:"PeakTime"`
+	SeniorDisabled float64 `json:"SeniorDisabled"`
+}
+
+type wmataStationToStation struct {
+	CompositeMiles     float64       `json:"CompositeMiles"`
+	DestinationStation string        `json:"DestinationStation"`
+	RailFare           wmataRailFare `json:"RailFare"`
+	RailTime           int           `json:"RailTime"` // Minutes.
+	SourceStation      string        `json:"SourceStation"`
+}
+
+type wmataStationToStationResponse struct {
+	StationToStationInfos []wmataStationToStation `json:"StationToStationInfos"`
+}
+
+// Ping lists the rail lines, WMATA's smallest authenticated response. It's never cached.
+func (w *WMATAClient) Ping(ctx context.Context) error {
+	req, err := w.BuildRequest(ctx, http.MethodGet, "Rail.svc/json/jLines")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	resp, err := w.http.Do(req)
+	resp, err := send(w.http, req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, &HTTPError{StatusCode: resp.StatusCode, URL: req.URL.String()}
+	if resp.Status != 200 {
+		return &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
 	}
 
-	configDir, err := config.GetConfigDir()
-	if err != nil {
-		return nil, err
-	}
+	return nil
+}
 
-	zipPath := filepath.Join(configDir, "wmata_rail_gtfs_static.zip")
-	f, err := os.Create(zipPath)
+func (w *WMATAClient) BuildRequest(ctx context.Context, method string, route ...string) (*http.Request, error) {
+	parts := make([]string, 0, len(route)+1)
+	parts = append(parts, w.baseURL)
+	parts = append(parts, route...)
+	url := strings.Join(parts, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: name the file we couldn't clean up, once there's a debug stream to name it on
-	defer func() {
-		f.Close() //nolint:errcheck // only here for the copy below, we close it ourselves after that
-		_ = os.RemoveAll(zipPath)
-	}()
-
-	if _, err := io.Copy(f, resp.Body); err != nil {
-		return nil, fmt.Errorf("download gtfs archive: %w", err)
-	}
+	req.Header.Add("api_key", w.apiKey)
 
-	// Close it before we read it back, a bad write only shows up here
-	if err := f.Close(); err != nil {
-		return nil, fmt.Errorf("write gtfs archive %s: %w", zipPath, err)
-	}
+	return req, nil
+}
 
-	dirName := "gtfs_static_" + strconv.FormatInt(time.Now().Unix(), 10)
-	feed := filepath.Join(configDir, dirName)
-	if err = os.MkdirAll(feed, 0o755); err != nil {
+func (w *WMATAClient) Seed(ctx context.Context) (*transit.Static, error) {
+	req, err := w.BuildRequest(ctx, http.MethodGet, "gtfs/rail-gtfs-static.zip")
+	if err != nil {
 		return nil, err
 	}
 
-	defer func() {
-		_ = os.RemoveAll(feed)
-	}()
-
-	err = gtfs.UnzipStaticGTFS(zipPath, feed)
+	feed, cleanup, err := downloadFeed(ctx, w.http, req, "wmata_rail", w.log)
 	if err != nil {
 		return nil, err
 	}
 
+	defer cleanup()
+
 	return gtfs.ParseGTFS(feed, transit.DMVSlug, transit.TrainStation, agencyWMATA)
 }
 
 func (w *WMATAClient) Departures(ctx context.Context, refs []transit.StopRef) (transit.DepartureSet, error) {
-	departures, asOf, err := w.fetchDepartures(ctx, refs)
+	departures, asOf, cached, err := w.fetchDepartures(ctx, refs)
 	if err != nil {
 		return transit.DepartureSet{}, err
 	}
 
 	return transit.DepartureSet{
 		Departures: departures,
-		Sources:    []transit.SourceStatus{{Source: sourceWMATARail, AsOf: asOf}},
+		Sources:    []transit.SourceStatus{{Source: sourceWMATARail, AsOf: asOf, Cached: cached}},
 	}, nil
 }
 
-func (w *WMATAClient) fetchDepartures(ctx context.Context, refs []transit.StopRef) ([]transit.Departure, time.Time, error) {
+func (w *WMATAClient) fetchDepartures(ctx context.Context, refs []transit.StopRef) ([]transit.Departure, time.Time, bool, error) {
 	codes := make([]string, 0, len(refs))
 	for _, i := range refs {
 		codes = append(codes, i.StopID)
@@ -159,33 +203,26 @@ func (w *WMATAClient) fetchDepartures(ctx context.Context, refs []transit.StopRe
 
 	req, err := w.BuildRequest(ctx, http.MethodGet, "StationPrediction.svc/json/GetPrediction", strings.Join(codes, ","))
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
-	resp, err := w.http.Do(req)
-
+	resp, err := w.cache.fetch(w.http, req, departuresTTL)
 	if err != nil {
-		return nil, time.Time{}, err
+		return nil, time.Time{}, false, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, time.Time{}, &HTTPError{StatusCode: resp.StatusCode, URL: req.URL.String()}
+	if resp.Status != 200 {
+		return nil, time.Time{}, false, &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, time.Time{}, err
-	}
-
-	asOf := w.now()
+	body := resp.Body
+	asOf := w.asOf(resp)
 
 	var predictionsResponse wmataPredictionsResponse
 	err = json.Unmarshal(body, &predictionsResponse)
 
 	if err != nil {
-		return nil, time.Time{}, fmt.Errorf("parse predictions response: %w", err)
+		return nil, time.Time{}, false, fmt.Errorf("parse predictions response: %w", err)
 	}
 
 	departures := make([]transit.Departure, 0, len(predictionsResponse.Trains))
@@ -216,52 +253,71 @@ func (w *WMATAClient) fetchDepartures(ctx context.Context, refs []transit.StopRe
 		})
 	}
 
-	return departures, asOf, nil
+	return departures, asOf, resp.Cached, nil
+}
+
+// asOf dates a response. WMATA doesn't send a timestamp, so it's when the body was fetched.
+func (w *WMATAClient) asOf(resp *response) time.Time {
+	if resp.Cached {
+		return resp.Fetched
+	}
+
+	return w.now()
 }
 
+// Alerts asks for rail incidents and elevator and escalator outages at the same
+// time. Each is its own source, one failing doesn't hide the other.
 func (w *WMATAClient) Alerts(ctx context.Context) (transit.AlertSet, error) {
-	alerts, err := w.fetchAlerts(ctx)
+	var incidents, outages []transit.Alert
+	var incidentsResp, outagesResp *response
+	var incidentsErr, outagesErr error
 
-	source := transit.SourceStatus{Source: sourceWMATARail, Err: err}
+	var wg sync.WaitGroup
+	wg.Go(func() { incidents, incidentsResp, incidentsErr = w.fetchAlerts(ctx) })
+	wg.Go(func() { outages, outagesResp, outagesErr = w.fetchElevatorAlerts(ctx) })
+	wg.Wait()
 
+	return transit.AlertSet{
+		Alerts: slices.Concat(incidents, outages),
+		Sources: []transit.SourceStatus{
+			w.alertSource(sourceWMATARail, incidentsResp, incidentsErr),
+			w.alertSource(sourceWMATAElevators, outagesResp, outagesErr),
+		},
+	}, nil
+}
+
+func (w *WMATAClient) alertSource(source string, resp *response, err error) transit.SourceStatus {
+	status := transit.SourceStatus{Source: source, Err: err}
 	if err == nil {
-		source.AsOf = w.now()
+		status.AsOf = w.asOf(resp)
+		status.Cached = resp.Cached
 	}
 
-	return transit.AlertSet{
-		Alerts:  alerts,
-		Sources: []transit.SourceStatus{source},
-	}, nil
+	return status
 }
 
-func (w *WMATAClient) fetchAlerts(ctx context.Context) ([]transit.Alert, error) {
+func (w *WMATAClient) fetchAlerts(ctx context.Context) ([]transit.Alert, *response, error) {
 	req, err := w.BuildRequest(ctx, http.MethodGet, "Incidents.svc/json/Incidents")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	resp, err := w.http.Do(req)
-
+	resp, err := w.cache.fetch(w.http, req, alertsTTL)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, &HTTPError{StatusCode: resp.StatusCode, URL: req.URL.String()}
+	if resp.Status != 200 {
+		return nil, nil, &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	body := resp.Body
 
 	var incidentsRes wmataIncidentsResponse
 	err = json.Unmarshal(body, &incidentsRes)
 
 	if err != nil {
-		return nil, fmt.Errorf("parse incidents response: %w", err)
+		return nil, nil, fmt.Errorf("parse incidents response: %w", err)
 	}
 
 	alerts := make([]transit.Alert, 0, len(incidentsRes.Incidents))
@@ -269,6 +325,7 @@ func (w *WMATAClient) fetchAlerts(ctx context.Context) ([]transit.Alert, error)
 		// A missing or malformed timestamp should only affect the age and not the alert.
 		date, _ := time.ParseInLocation(wmataDateTimeLayout, inc.DateUpdated, w.location)
 		alert := transit.Alert{
+			ID:          inc.IncidentID,
 			Source:      sourceWMATARail,
 			AgencyID:    agencyWMATA,
 			Affected:    parseAffected(inc.LinesAffected),
@@ -280,8 +337,336 @@ func (w *WMATAClient) fetchAlerts(ctx context.Context) ([]transit.Alert, error)
 		alerts = append(alerts, alert)
 	}
 
-	return alerts, nil
+	return alerts, resp, nil
+}
+
+func (w *WMATAClient) fetchElevatorAlerts(ctx context.Context) ([]transit.Alert, *response, error) {
+	req, err := w.BuildRequest(ctx, http.MethodGet, "Incidents.svc/json/ElevatorIncidents")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := w.cache.fetch(w.http, req, alertsTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.Status != 200 {
+		return nil, nil, &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
+	}
+
+	var incidentsRes wmataElevatorIncidentsResponse
+	if err := json.Unmarshal(resp.Body, &incidentsRes); err != nil {
+		return nil, nil, fmt.Errorf("parse elevator incidents response: %w", err)
+	}
+
+	stations := w.seededStations(ctx)
+
+	alerts := make([]transit.Alert, 0, len(incidentsRes.ElevatorIncidents))
+	for _, inc := range incidentsRes.ElevatorIncidents {
+		alerts = append(alerts, w.elevatorAlert(inc, stations))
+	}
+
+	return alerts, resp, nil
+}
+
+// elevatorAlert describes an outage as an accessibility alert on its station. A
+// station that wasn't seeded is referred to by its WMATA code.
+func (w *WMATAClient) elevatorAlert(inc wmataElevatorIncident, stations map[string]string) transit.Alert {
+	stationID, ok := stations[inc.StationCode]
+	if !ok {
+		stationID = inc.StationCode
+	}
+
+	unit := "Elevator"
+	if strings.EqualFold(inc.UnitType, "ESCALATOR") {
+		unit = "Escalator"
+	}
+
+	description := fmt.Sprintf("%s out of service at %s", unit, inc.StationName)
+	if inc.LocationDescription != "" {
+		description += ": " + inc.LocationDescription
+	}
+
+	if inc.SymptomDescription != "" {
+		description += " (" + inc.SymptomDescription + ")"
+	}
+
+	description += "."
+
+	// The estimate is often left in the past while the unit is still out, so it's only
+	// mentioned rather than ending the alert. The outage is over when WMATA drops it.
+	if back, err := time.ParseInLocation(wmataDateTimeLayout, inc.EstimatedReturnToService, w.location); err == nil {
+		description += " Expected back " + back.Format("2 Jan") + "."
+	}
+
+	// Missing or malformed timestamps leave the period open rather than dropping the outage.
+	starts, _ := time.ParseInLocation(wmataDateTimeLayout, inc.DateOutOfServ, w.location)
+	updated, _ := time.ParseInLocation(wmataDateTimeLayout, inc.DateUpdated, w.location)
+
+	alert := transit.Alert{
+		ID:          inc.UnitName,
+		Source:      sourceWMATAElevators,
+		AgencyID:    agencyWMATA,
+		Affected:    []transit.AlertRef{{Kind: transit.RefStop, ID: stationID, Name: inc.StationName}},
+		Description: description,
+		Effect:      transit.EffectAccessibility,
+		Updated:     updated,
+	}
+
+	if !starts.IsZero() {
+		alert.Periods = []transit.ActivePeriod{{Starts: starts}}
+	}
+
+	return alert
+}
+
+// seededStations maps WMATA station codes to the seeded stations they belong to. A
+// lookup that fails maps nothing, outages then keep WMATA's codes.
+func (w *WMATAClient) seededStations(ctx context.Context) map[string]string {
+	stations := make(map[string]string)
+	for code, stop := range w.stationsByCode(ctx) {
+		stations[code] = stop.StopID
+	}
+
+	return stations
+}
+
+// stationNames maps WMATA station codes to the names of their seeded stations.
+func (w *WMATAClient) stationNames(ctx context.Context) map[string]string {
+	names := make(map[string]string)
+	for code, stop := range w.stationsByCode(ctx) {
+		names[code] = stop.Name
+	}
+
+	return names
+}
+
+// stationsByCode reads the seeded stations, keyed by every code each one has. A
+// lookup that fails, or a client without a store, finds nothing.
+func (w *WMATAClient) stationsByCode(ctx context.Context) map[string]transit.Stop {
+	stations := make(map[string]transit.Stop)
+	if w.store == nil {
+		return stations
+	}
+
+	stops, err := w.store.StopsByLocation(ctx, transit.DMVSlug, true)
+	if err != nil {
+		w.log.DebugContext(ctx, "look up stations", "err", err)
+		return stations
+	}
+
+	for _, stop := range stops {
+		for _, code := range formatWMATAStopID(stop.StopID) {
+			stations[code] = stop
+		}
+	}
+
+	return stations
+}
+
+// Positions places the trains on a line between the stations of its standard route,
+// listed the way track 1 runs. Trains not on the route's circuits, e.g. in a yard,
+// and trains not in passenger service are left out.
+func (w *WMATAClient) Positions(ctx context.Context, line string) (transit.LinePositions, error) {
+	routes, err := w.fetchStandardRoutes(ctx)
+	if err != nil {
+		return transit.LinePositions{}, err
+	}
+
+	tracks := make(map[int][]wmataTrackCircuit, 2)
+	for _, route := range routes {
+		if strings.EqualFold(route.LineCode, line) {
+			circuits := slices.Clone(route.TrackCircuits)
+			slices.SortFunc(circuits, func(a, b wmataTrackCircuit) int { return a.SeqNum - b.SeqNum })
+			tracks[route.TrackNum] = circuits
+		}
+	}
+
+	if len(tracks[1]) == 0 {
+		return transit.LinePositions{}, fmt.Errorf("%w: %s", transit.ErrUnknownLine, line)
+	}
+
+	trains, resp, err := w.fetchTrainPositions(ctx)
+	if err != nil {
+		return transit.LinePositions{}, err
+	}
+
+	names := w.stationNames(ctx)
+	name := func(code string) string {
+		if n, ok := names[code]; ok {
+			return n
+		}
+
+		return code
+	}
+
+	code := strings.ToUpper(line)
+	bg, fg := wmataLineColor(code)
+	positions := transit.LinePositions{Line: code, Color: bg, TextColor: fg, AsOf: w.asOf(resp)}
+
+	for _, c := range tracks[1] {
+		if c.StationCode != "" {
+			positions.Stops = append(positions.Stops, transit.StopRef{StopID: c.StationCode, Name: name(c.StationCode), AgencyID: agencyWMATA, Source: sourceWMATARail})
+		}
+	}
+
+	for _, t := range trains {
+		if !strings.EqualFold(t.LineCode, code) || isWMATANonRevenue(t) {
+			continue
+		}
+
+		vehicle, ok := placeTrain(t, tracks[t.DirectionNum])
+		if !ok {
+			continue
+		}
+
+		if t.DestinationStationCode != "" {
+			vehicle.Headsign = name(t.DestinationStationCode)
+		}
+
+		positions.Vehicles = append(positions.Vehicles, vehicle)
+	}
+
+	return positions, nil
+}
+
+// placeTrain finds a train's circuit on its track. The station last passed is the
+// nearest one at or before the circuit, the next is the nearest one after it.
+func placeTrain(t wmataTrainPosition, track []wmataTrackCircuit) (transit.VehiclePosition, bool) {
+	at := slices.IndexFunc(track, func(c wmataTrackCircuit) bool { return c.CircuitID == t.CircuitID })
+	if at < 0 {
+		return transit.VehiclePosition{}, false
+	}
+
+	vehicle := transit.VehiclePosition{VehicleID: t.TrainID, Cars: t.CarCount, AtStop: track[at].StationCode != ""}
+
+	for i := at; i >= 0; i-- {
+		if track[i].StationCode != "" {
+			vehicle.StopID = track[i].StationCode
+			break
+		}
+	}
 
+	for _, c := range track[at+1:] {
+		if c.StationCode != "" {
+			vehicle.NextStopID = c.StationCode
+			break
+		}
+	}
+
+	// Still short of the first station of its run.
+	if vehicle.StopID == "" {
+		return transit.VehiclePosition{}, false
+	}
+
+	return vehicle, true
+}
+
+// isWMATANonRevenue reports trains that riders can't board, e.g. ones headed to a yard.
+func isWMATANonRevenue(t wmataTrainPosition) bool {
+	return t.ServiceType != "Normal" && t.ServiceType != "Special"
+}
+
+func (w *WMATAClient) fetchTrainPositions(ctx context.Context) ([]wmataTrainPosition, *response, error) {
+	req, err := w.BuildRequest(ctx, http.MethodGet, "TrainPositions/TrainPositions?contentType=json")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := w.cache.fetch(w.http, req, departuresTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.Status != 200 {
+		return nil, nil, &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
+	}
+
+	var positionsRes wmataTrainPositionsResponse
+	if err := json.Unmarshal(resp.Body, &positionsRes); err != nil {
+		return nil, nil, fmt.Errorf("parse train positions response: %w", err)
+	}
+
+	return positionsRes.TrainPositions, resp, nil
+}
+
+func (w *WMATAClient) fetchStandardRoutes(ctx context.Context) ([]wmataStandardRoute, error) {
+	req, err := w.BuildRequest(ctx, http.MethodGet, "TrainPositions/StandardRoutes?contentType=json")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := w.cache.fetch(w.http, req, routesTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status != 200 {
+		return nil, &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
+	}
+
+	var routesRes wmataStandardRoutesResponse
+	if err := json.Unmarshal(resp.Body, &routesRes); err != nil {
+		return nil, fmt.Errorf("parse standard routes response: %w", err)
+	}
+
+	return routesRes.StandardRoutes, nil
+}
+
+// Fare looks up the fare and rail time between two stations. A station with more
+// than one code is asked about by its first, the fare is the same whichever is used.
+func (w *WMATAClient) Fare(ctx context.Context, from, to transit.Stop) (transit.Fare, error) {
+	fromCodes, toCodes := formatWMATAStopID(from.StopID), formatWMATAStopID(to.StopID)
+	if len(fromCodes) == 0 || len(toCodes) == 0 {
+		return transit.Fare{}, fmt.Errorf("no station codes for %q to %q", from.StopID, to.StopID)
+	}
+
+	return w.StationToStation(ctx, fromCodes[0], toCodes[0])
+}
+
+// StationToStation asks Rail.svc for the fares, rail time and distance between two
+// station codes, e.g. "A01".
+func (w *WMATAClient) StationToStation(ctx context.Context, fromCode, toCode string) (transit.Fare, error) {
+	req, err := w.BuildRequest(ctx, http.MethodGet, "Rail.svc/json/jSrcStationToDstStationInfo")
+	if err != nil {
+		return transit.Fare{}, err
+	}
+
+	q := req.URL.Query()
+	q.Add("FromStationCode", fromCode)
+	q.Add("ToStationCode", toCode)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := w.cache.fetch(w.http, req, faresTTL)
+	if err != nil {
+		return transit.Fare{}, err
+	}
+
+	if resp.Status != 200 {
+		return transit.Fare{}, &HTTPError{StatusCode: resp.Status, URL: req.URL.String()}
+	}
+
+	var infoRes wmataStationToStationResponse
+	if err := json.Unmarshal(resp.Body, &infoRes); err != nil {
+		return transit.Fare{}, fmt.Errorf("parse station to station response: %w", err)
+	}
+
+	if len(infoRes.StationToStationInfos) == 0 {
+		return transit.Fare{}, fmt.Errorf("no fare from %s to %s", fromCode, toCode)
+	}
+
+	info := infoRes.StationToStationInfos[0]
+
+	return transit.Fare{
+		From:           info.SourceStation,
+		To:             info.DestinationStation,
+		Peak:           info.RailFare.PeakTime,
+		OffPeak:        info.RailFare.OffPeakTime,
+		SeniorDisabled: info.RailFare.SeniorDisabled,
+		RailTime:       time.Duration(info.RailTime) * time.Minute,
+		Miles:          info.CompositeMiles,
+	}, nil
 }
 
 // StopRefs splits a station into the platform codes WMATA understands.
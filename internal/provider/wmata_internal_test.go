@@ -1,11 +1,18 @@
 package provider
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"slices"
 	"testing"
 	"time"
 
+	"github.com/ismailshak/transit/internal/fixtures"
 	"github.com/ismailshak/transit/internal/transit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseLinesAffected(t *testing.T) {
@@ -183,3 +190,178 @@ func TestRailArrival(t *testing.T) {
 		})
 	}
 }
+
+func TestWMATAFare(t *testing.T) {
+	t.Parallel()
+
+	body := fixtures.Read(t, "wmata-station-to-station.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Rail.svc/json/jSrcStationToDstStationInfo" || r.Header.Get("api_key") != "key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("FromStationCode") != "A01" || r.URL.Query().Get("ToStationCode") != "N06" {
+			_, _ = w.Write([]byte(`{"StationToStationInfos":[]}`))
+			return
+		}
+
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	w := &WMATAClient{apiKey: "key", baseURL: srv.URL, http: srv.Client(), now: time.Now}
+
+	metroCenter := transit.Stop{StopID: "STN_A01_C01", Name: "Metro Center"}
+	wiehle := transit.Stop{StopID: "STN_N06", Name: "Wiehle-Reston East"}
+
+	fare, err := w.Fare(t.Context(), metroCenter, wiehle)
+	require.NoError(t, err)
+
+	assert.Equal(t, transit.Fare{
+		From:           "A01",
+		To:             "N06",
+		Peak:           6.00,
+		OffPeak:        3.85,
+		SeniorDisabled: 1.90,
+		RailTime:       47 * time.Minute,
+		Miles:          24.52,
+	}, fare)
+
+	_, err = w.Fare(t.Context(), wiehle, metroCenter)
+	assert.ErrorContains(t, err, "no fare from N06 to A01")
+
+	w.apiKey = "wrong"
+	_, err = w.Fare(t.Context(), metroCenter, wiehle)
+
+	httpErr, ok := errors.AsType[*HTTPError](err)
+	require.True(t, ok, "expected an HTTPError but got %v", err)
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
+
+// stationLookup answers StopsByLocation with fixed stations.
+type stationLookup struct {
+	stations []transit.Stop
+}
+
+func (l stationLookup) Agencies(context.Context, transit.LocationSlug) ([]transit.Agency, error) {
+	return nil, nil
+}
+
+func (l stationLookup) StopNames(context.Context, transit.LocationSlug, []string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (l stationLookup) TripRoutes(context.Context, transit.LocationSlug, []string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (l stationLookup) StopsByLocation(context.Context, transit.LocationSlug, bool) ([]transit.Stop, error) {
+	return l.stations, nil
+}
+
+func TestWMATAAlertsIncludesElevatorOutages(t *testing.T) {
+	t.Parallel()
+
+	incidents := fixtures.Read(t, "wmata-incidents.json")
+	outages := fixtures.Read(t, "wmata-elevator-incidents.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Incidents.svc/json/Incidents":
+			_, _ = w.Write(incidents)
+		case "/Incidents.svc/json/ElevatorIncidents":
+			_, _ = w.Write(outages)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	location, err := time.LoadLocation(wmataTimezone)
+	require.NoError(t, err)
+
+	w := &WMATAClient{
+		apiKey:   "key",
+		baseURL:  srv.URL,
+		http:     srv.Client(),
+		location: location,
+		now:      time.Now,
+		store:    stationLookup{stations: []transit.Stop{{StopID: "STN_A03", Name: "Dupont Circle"}}},
+	}
+
+	set, err := w.Alerts(t.Context())
+	require.NoError(t, err)
+
+	require.Len(t, set.Sources, 2)
+	assert.NoError(t, set.Sources[0].Err)
+	assert.NoError(t, set.Sources[1].Err)
+
+	accessibility := set.Filter(transit.AlertFilter{Effects: []string{transit.EffectAccessibility}}).Alerts
+	require.Len(t, accessibility, 2)
+	assert.Len(t, set.Alerts, 7, "the 5 incidents are still there")
+
+	dupont := accessibility[0]
+	assert.Equal(t, "A03N04", dupont.ID)
+	assert.Equal(t, sourceWMATAElevators, dupont.Source)
+	assert.Equal(t, []transit.AlertRef{{Kind: transit.RefStop, ID: "STN_A03", Name: "Dupont Circle, Q Street Entrance"}}, dupont.Affected)
+	assert.Equal(t, "Escalator out of service at Dupont Circle, Q Street Entrance: Escalator between street and mezzanine (Modernization). Expected back 30 Sep.", dupont.Description)
+	assert.Equal(t, []transit.ActivePeriod{{Starts: time.Date(2026, 7, 1, 6, 25, 0, 0, location)}}, dupont.Periods)
+
+	assert.Equal(t, "N06", accessibility[1].Affected[0].ID, "a station that wasn't seeded keeps its code")
+}
+
+func TestWMATAPositions(t *testing.T) {
+	t.Parallel()
+
+	routes := fixtures.Read(t, "wmata-standard-routes.json")
+	trains := fixtures.Read(t, "wmata-train-positions.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/TrainPositions/StandardRoutes":
+			_, _ = w.Write(routes)
+		case "/TrainPositions/TrainPositions":
+			_, _ = w.Write(trains)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	asOf := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	w := &WMATAClient{
+		apiKey:  "key",
+		baseURL: srv.URL,
+		http:    srv.Client(),
+		now:     func() time.Time { return asOf },
+		store: stationLookup{stations: []transit.Stop{
+			{StopID: "STN_A15", Name: "Shady Grove"},
+			{StopID: "STN_A14", Name: "Rockville"},
+			{StopID: "STN_A01_C01", Name: "Metro Center"},
+		}},
+	}
+
+	positions, err := w.Positions(t.Context(), "rd")
+	require.NoError(t, err)
+
+	assert.Equal(t, "RD", positions.Line)
+	assert.Equal(t, asOf, positions.AsOf)
+
+	var stops []string
+	for _, s := range positions.Stops {
+		stops = append(stops, s.StopID+" "+s.Name)
+	}
+
+	assert.Equal(t, []string{"A15 Shady Grove", "A14 Rockville", "A13 A13", "A01 Metro Center"}, stops, "a station that wasn't seeded keeps its code")
+
+	assert.Equal(t, []transit.VehiclePosition{
+		{VehicleID: "101", Headsign: "Metro Center", Cars: 8, StopID: "A15", NextStopID: "A14"},
+		{VehicleID: "102", Headsign: "Metro Center", Cars: 6, StopID: "A13", NextStopID: "A01", AtStop: true},
+		{VehicleID: "201", Headsign: "Shady Grove", Cars: 8, StopID: "A13", NextStopID: "A14"},
+	}, positions.Vehicles, "trains off the route, out of service or on other lines are left out")
+
+	_, err = w.Positions(t.Context(), "PK")
+	assert.ErrorIs(t, err, transit.ErrUnknownLine)
+}
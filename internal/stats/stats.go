@@ -0,0 +1,280 @@
+// Package stats works out how reliable departure predictions were, from the log
+// `at` keeps of them when stats.record_predictions is on.
+//
+// Predictions don't say which train they're for, so trains are followed from one
+// fetch to the next by their predicted arrival: a prediction within a few minutes
+// of where a train was last predicted is taken to be the same train.
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+const (
+	// sessionGap splits the log into watch sessions. Nothing can be said about a
+	// train across a gap this long, so trains still on the board at the end of a
+	// session are neither counted as arrived nor as ghosts.
+	sessionGap = 5 * time.Minute
+
+	// matchWindow is how far a train's predicted arrival can move between two
+	// fetches and still be the same train.
+	matchWindow = 3 * time.Minute
+
+	// arrivingWithin is how close to its fetch a prediction has to be for the train to
+	// count as arrived once it leaves the board.
+	arrivingWithin = time.Minute
+)
+
+// Buckets are the lead times drift is reported for. Predictions made less than two
+// minutes out are left out, a train that close is as good as there.
+var Buckets = []Bucket{
+	{Label: "10+ min out", From: 10 * time.Minute},
+	{Label: "5-10 min out", From: 5 * time.Minute, To: 10 * time.Minute},
+	{Label: "2-5 min out", From: 2 * time.Minute, To: 5 * time.Minute},
+}
+
+// Bucket is a range of lead times, how long before a train arrived a prediction was made.
+type Bucket struct {
+	Label string
+	From  time.Duration
+	To    time.Duration // Zero means no upper bound.
+}
+
+func (b Bucket) contains(lead time.Duration) bool {
+	return lead >= b.From && (b.To == 0 || lead < b.To)
+}
+
+// Report is the reliability of predictions at one station.
+type Report struct {
+	Fetches int       // How many times the station's departures were fetched.
+	From    time.Time // The first fetch.
+	To      time.Time // The last fetch.
+	Lines   []Line    // By line, then headsign.
+}
+
+// Line is how trains on one line towards one destination fared.
+type Line struct {
+	Line     string
+	Headsign string
+
+	Arrived int // Trains followed until they arrived.
+	// Trains that left the board while still minutes away, with the station still
+	// being watched. Often a train that was never going to come.
+	Ghosts int
+
+	MedianHeadway time.Duration // Between consecutive arrivals. Zero with fewer than two.
+	MaxHeadway    time.Duration
+
+	Drift []Drift // One per [Buckets] entry, in the same order.
+}
+
+// GhostRate is the share of trains that turned out to be ghosts.
+func (l Line) GhostRate() float64 {
+	if l.Arrived+l.Ghosts == 0 {
+		return 0
+	}
+
+	return float64(l.Ghosts) / float64(l.Arrived+l.Ghosts)
+}
+
+// Drift is how far off predictions made at some lead time were.
+type Drift struct {
+	Bucket
+	Samples int
+	MeanAbs time.Duration // Average size of the miss.
+	// Average miss with its sign. Positive means trains came later than predicted.
+	MeanBias time.Duration
+}
+
+// train is one vehicle followed across fetches.
+type train struct {
+	session   int
+	sightings []transit.Prediction
+}
+
+func (t *train) last() transit.Prediction { return t.sightings[len(t.sightings)-1] }
+
+// arriving reports whether the train was about to arrive when last seen.
+func (t *train) arriving() bool {
+	last := t.last()
+	return last.Arrives.Sub(last.Fetched) <= arrivingWithin
+}
+
+// Analyze follows the trains in a station's prediction log. predictions can be in
+// any order.
+func Analyze(predictions []transit.Prediction) Report {
+	fetches := fetchTimes(predictions)
+	if len(fetches) == 0 {
+		return Report{}
+	}
+
+	type key struct{ line, headsign string }
+	byKey := make(map[key][]transit.Prediction)
+	for _, p := range predictions {
+		k := key{p.Line, p.Headsign}
+		byKey[k] = append(byKey[k], p)
+	}
+
+	report := Report{Fetches: len(fetches), From: fetches[0], To: fetches[len(fetches)-1]}
+	for k, preds := range byKey {
+		arrived, ghosts := follow(fetches, preds)
+		report.Lines = append(report.Lines, summarize(k.line, k.headsign, arrived, ghosts))
+	}
+
+	slices.SortFunc(report.Lines, func(a, b Line) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Headsign, b.Headsign))
+	})
+
+	return report
+}
+
+// fetchTimes returns each distinct fetch, in order.
+func fetchTimes(predictions []transit.Prediction) []time.Time {
+	var fetches []time.Time
+	for _, p := range predictions {
+		fetches = append(fetches, p.Fetched)
+	}
+
+	slices.SortFunc(fetches, time.Time.Compare)
+
+	return slices.CompactFunc(fetches, time.Time.Equal)
+}
+
+// follow walks the fetches in order and matches each one's predictions to the
+// trains on the board. It returns the trains that arrived and the ones that vanished.
+func follow(fetches []time.Time, preds []transit.Prediction) ([]*train, []*train) {
+	byFetch := make(map[time.Time][]transit.Prediction)
+	for _, p := range preds {
+		byFetch[p.Fetched] = append(byFetch[p.Fetched], p)
+	}
+
+	var active, arrived, ghosts []*train
+	session := 0
+
+	for n, f := range fetches {
+		if n > 0 && f.Sub(fetches[n-1]) > sessionGap {
+			// Trains left on the board when watching stopped can't be judged.
+			for _, t := range active {
+				if t.arriving() {
+					arrived = append(arrived, t)
+				}
+			}
+
+			active = nil
+			session++
+		}
+
+		board := byFetch[f]
+		slices.SortFunc(board, func(a, b transit.Prediction) int { return a.Arrives.Compare(b.Arrives) })
+
+		matched := make([]bool, len(active))
+		var next []*train
+
+		for _, p := range board {
+			i := closest(active, matched, p)
+			if i < 0 {
+				next = append(next, &train{session: session, sightings: []transit.Prediction{p}})
+				continue
+			}
+
+			matched[i] = true
+			active[i].sightings = append(active[i].sightings, p)
+			next = append(next, active[i])
+		}
+
+		for i, t := range active {
+			if matched[i] {
+				continue
+			}
+
+			if t.arriving() {
+				arrived = append(arrived, t)
+			} else {
+				ghosts = append(ghosts, t)
+			}
+		}
+
+		active = next
+	}
+
+	for _, t := range active {
+		if t.arriving() {
+			arrived = append(arrived, t)
+		}
+	}
+
+	return arrived, ghosts
+}
+
+// closest finds the unmatched train whose last prediction is nearest to p, or -1
+// when none is within matchWindow.
+func closest(active []*train, matched []bool, p transit.Prediction) int {
+	best, bestGap := -1, matchWindow+1
+	for i, t := range active {
+		if matched[i] {
+			continue
+		}
+
+		gap := p.Arrives.Sub(t.last().Arrives).Abs()
+		if gap <= matchWindow && gap < bestGap {
+			best, bestGap = i, gap
+		}
+	}
+
+	return best
+}
+
+func summarize(line, headsign string, arrived, ghosts []*train) Line {
+	l := Line{Line: line, Headsign: headsign, Arrived: len(arrived), Ghosts: len(ghosts)}
+
+	// A train arrives when it was last predicted to, that prediction was at most a
+	// minute out.
+	arrival := func(t *train) time.Time { return t.last().Arrives }
+
+	slices.SortFunc(arrived, func(a, b *train) int { return arrival(a).Compare(arrival(b)) })
+
+	var headways []time.Duration
+	for i := 1; i < len(arrived); i++ {
+		if arrived[i].session == arrived[i-1].session {
+			headways = append(headways, arrival(arrived[i]).Sub(arrival(arrived[i-1])))
+		}
+	}
+
+	if len(headways) > 0 {
+		slices.Sort(headways)
+		l.MedianHeadway = headways[len(headways)/2]
+		l.MaxHeadway = headways[len(headways)-1]
+	}
+
+	for _, b := range Buckets {
+		d := Drift{Bucket: b}
+
+		var abs, bias time.Duration
+		for _, t := range arrived {
+			at := arrival(t)
+			for _, s := range t.sightings {
+				if !b.contains(at.Sub(s.Fetched)) {
+					continue
+				}
+
+				miss := at.Sub(s.Arrives)
+				d.Samples++
+				abs += miss.Abs()
+				bias += miss
+			}
+		}
+
+		if d.Samples > 0 {
+			d.MeanAbs = abs / time.Duration(d.Samples)
+			d.MeanBias = bias / time.Duration(d.Samples)
+		}
+
+		l.Drift = append(l.Drift, d)
+	}
+
+	return l
+}
@@ -0,0 +1,106 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ismailshak/transit/internal/stats"
+	"github.com/ismailshak/transit/internal/transit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var eight = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time { return eight.Add(time.Duration(minutes) * time.Minute) }
+
+// sighted logs a train on the board from fetch `from` to fetch `to`, every minute,
+// predicted by predict.
+func sighted(line, headsign string, from, to int, predict func(fetch int) int) []transit.Prediction {
+	var log []transit.Prediction
+	for f := from; f <= to; f++ {
+		log = append(log, transit.Prediction{
+			Source:   "wmata",
+			StopID:   "A01",
+			Line:     line,
+			Headsign: headsign,
+			Arrives:  at(predict(f)),
+			Fetched:  at(f),
+		})
+	}
+
+	return log
+}
+
+func exactly(arrival int) func(int) int { return func(int) int { return arrival } }
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	var log []transit.Prediction
+
+	// Predicted two minutes early until five minutes out, then right.
+	log = append(log, sighted("RD", "Glenmont", 0, 10, func(f int) int {
+		if f <= 5 {
+			return 8
+		}
+		return 10
+	})...)
+	log = append(log, sighted("RD", "Glenmont", 0, 16, exactly(16))...)
+	// Leaves the board eight minutes out.
+	log = append(log, sighted("RD", "Glenmont", 0, 4, exactly(13))...)
+	// Still coming when watching stopped.
+	log = append(log, sighted("BL", "Largo", 0, 20, exactly(25))...)
+
+	report := stats.Analyze(log)
+
+	assert.Equal(t, 21, report.Fetches)
+	assert.Equal(t, at(0), report.From)
+	assert.Equal(t, at(20), report.To)
+	require.Len(t, report.Lines, 2)
+
+	blue := report.Lines[0]
+	assert.Equal(t, "BL", blue.Line)
+	assert.Zero(t, blue.Arrived)
+	assert.Zero(t, blue.Ghosts, "a train still on the board isn't a ghost")
+
+	red := report.Lines[1]
+	assert.Equal(t, 2, red.Arrived)
+	assert.Equal(t, 1, red.Ghosts)
+	assert.InDelta(t, 1.0/3, red.GhostRate(), 0.001)
+	assert.Equal(t, 6*time.Minute, red.MedianHeadway)
+	assert.Equal(t, 6*time.Minute, red.MaxHeadway)
+
+	require.Len(t, red.Drift, len(stats.Buckets))
+
+	far, mid, near := red.Drift[0], red.Drift[1], red.Drift[2]
+	assert.Equal(t, 8, far.Samples)
+	assert.Equal(t, 15*time.Second, far.MeanAbs)
+	assert.Equal(t, 10, mid.Samples)
+	assert.Equal(t, time.Minute, mid.MeanAbs)
+	assert.Equal(t, time.Minute, mid.MeanBias, "trains came later than predicted")
+	assert.Equal(t, 6, near.Samples)
+	assert.Zero(t, near.MeanAbs)
+}
+
+func TestAnalyzeSessions(t *testing.T) {
+	t.Parallel()
+
+	var log []transit.Prediction
+	log = append(log, sighted("RD", "Glenmont", 0, 3, exactly(12))...)
+	// Watching again half an hour later, the train from before is long gone.
+	log = append(log, sighted("RD", "Glenmont", 30, 40, exactly(40))...)
+
+	report := stats.Analyze(log)
+
+	require.Len(t, report.Lines, 1)
+	assert.Zero(t, report.Lines[0].Ghosts, "a train can't vanish while nobody was watching")
+	assert.Equal(t, 1, report.Lines[0].Arrived)
+	assert.Zero(t, report.Lines[0].MedianHeadway)
+}
+
+func TestAnalyzeNothing(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, stats.Report{}, stats.Analyze(nil))
+}
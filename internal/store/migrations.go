@@ -38,6 +38,46 @@ var migrationChangesets = []changeset{
 		Up:   addSFToLocations,
 		Down: deleteSFFromLocations,
 	},
+	{
+		Name: "0004_Unique_Agencies_Stops",
+		Up:   addAgencyStopUniqueIndexes,
+		Down: dropAgencyStopUniqueIndexes,
+	},
+	{
+		Name: "0005_Trips",
+		Up:   createTripsTable,
+		Down: dropTripsTable,
+	},
+	{
+		Name: "0006_Notified",
+		Up:   createNotifiedTable,
+		Down: dropNotifiedTable,
+	},
+	{
+		Name: "0007_Alert_History",
+		Up:   createAlertHistoryTable,
+		Down: dropAlertHistoryTable,
+	},
+	{
+		Name: "0008_Predictions",
+		Up:   createPredictionsTable,
+		Down: dropPredictionsTable,
+	},
+	{
+		Name: "0009_Pattern_Stops",
+		Up:   createPatternStopsTable,
+		Down: dropPatternStopsTable,
+	},
+	{
+		Name: "0010_Seeded",
+		Up:   createSeededTable,
+		Down: dropSeededTable,
+	},
+	{
+		Name: "0011_Empty_Polls",
+		Up:   createEmptyPollsTable,
+		Down: dropEmptyPollsTable,
+	},
 }
 
 func failedMigration(message string, err error) error {
@@ -111,3 +151,249 @@ func addSFToLocations(ctx context.Context, trx *sql.Tx) error {
 func deleteSFFromLocations(ctx context.Context, trx *sql.Tx) error {
 	return nil
 }
+
+// addAgencyStopUniqueIndexes removes the duplicates a repeated seed left behind, then
+// stops it from happening again. The dedupe has to come first or the index can't be built.
+func addAgencyStopUniqueIndexes(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dedupeAgenciesSQL)
+	if err != nil {
+		return failedMigration("failed to deduplicate 'agencies': ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createAgencyLocationIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'agencies.location, agencies.agency_id' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dedupeStopsSQL)
+	if err != nil {
+		return failedMigration("failed to deduplicate 'stops': ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createStopLocationIDIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'stops.location, stops.stop_id' index: ", err)
+	}
+
+	return nil
+}
+
+func dropAgencyStopUniqueIndexes(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropAgencyLocationIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'agencies.location, agencies.agency_id' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropStopLocationIDIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'stops.location, stops.stop_id' index: ", err)
+	}
+
+	return nil
+}
+
+func createTripsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createTripsTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'trips' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createTripLocationIDIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'trips.location, trips.trip_id' index: ", err)
+	}
+
+	return nil
+}
+
+func dropTripsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropTripLocationIDIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'trips.location, trips.trip_id' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropTripsTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'trips' table: ", err)
+	}
+
+	return nil
+}
+
+func createNotifiedTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createNotifiedTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'notified' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createNotifiedLocationKeyIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'notified.location, notified.key' index: ", err)
+	}
+
+	return nil
+}
+
+func dropNotifiedTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropNotifiedLocationKeyIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'notified.location, notified.key' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropNotifiedTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'notified' table: ", err)
+	}
+
+	return nil
+}
+
+func createAlertHistoryTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createAlertHistoryTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'alert_history' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createAlertHistoryLocationKeyIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'alert_history.location, alert_history.key' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createAlertHistoryLastSeenIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'alert_history.location, alert_history.last_seen' index: ", err)
+	}
+
+	return nil
+}
+
+func dropAlertHistoryTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropAlertHistoryLastSeenIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'alert_history.location, alert_history.last_seen' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropAlertHistoryLocationKeyIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'alert_history.location, alert_history.key' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropAlertHistoryTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'alert_history' table: ", err)
+	}
+
+	return nil
+}
+
+func createPredictionsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createPredictionsTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'predictions' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createPredictionsStopIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'predictions.location, predictions.stop_id, predictions.fetched_at' index: ", err)
+	}
+
+	return nil
+}
+
+func dropPredictionsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropPredictionsStopIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'predictions.location, predictions.stop_id, predictions.fetched_at' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropPredictionsTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'predictions' table: ", err)
+	}
+
+	return nil
+}
+
+func createPatternStopsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createPatternStopsTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'pattern_stops' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createPatternStopsIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'pattern_stops.location, pattern_stops.pattern_id, pattern_stops.sequence' index: ", err)
+	}
+
+	return nil
+}
+
+func dropPatternStopsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropPatternStopsIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'pattern_stops.location, pattern_stops.pattern_id, pattern_stops.sequence' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropPatternStopsTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'pattern_stops' table: ", err)
+	}
+
+	return nil
+}
+
+func createSeededTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createSeededTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'seeded' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createSeededLocationIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'seeded.location' index: ", err)
+	}
+
+	return nil
+}
+
+func dropSeededTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropSeededLocationIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'seeded.location' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropSeededTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'seeded' table: ", err)
+	}
+
+	return nil
+}
+
+func createEmptyPollsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, createEmptyPollsTableSQL)
+	if err != nil {
+		return failedMigration("failed to create 'empty_polls' table: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, createEmptyPollsLocationKeyIndexSQL)
+	if err != nil {
+		return failedMigration("failed to create 'empty_polls.location, empty_polls.key' index: ", err)
+	}
+
+	return nil
+}
+
+func dropEmptyPollsTable(ctx context.Context, trx *sql.Tx) error {
+	_, err := trx.ExecContext(ctx, dropEmptyPollsLocationKeyIndexSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'empty_polls.location, empty_polls.key' index: ", err)
+	}
+
+	_, err = trx.ExecContext(ctx, dropEmptyPollsTableSQL)
+	if err != nil {
+		return failedMigration("failed to drop 'empty_polls' table: ", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestUniqueIndexMigrationDeduplicates(t *testing.T) {
+	t.Parallel()
+
+	db := openTestDB(t)
+	ctx := t.Context()
+
+	if err := createMigrationTable(ctx, db); err != nil {
+		t.Fatalf("Failed to create migrations table: %s", err)
+	}
+
+	// Everything before the unique indexes, which is the state a repeated seed duplicated rows in.
+	for i := range 3 {
+		if err := run(ctx, db, slog.New(slog.DiscardHandler), &migrationChangesets[i]); err != nil {
+			t.Fatalf("Failed to run %s: %s", migrationChangesets[i].Name, err)
+		}
+	}
+
+	for range 2 {
+		_, err := db.ExecContext(ctx, "INSERT INTO agencies (agency_id, name, location, timezone, language) VALUES ('BA', 'BART', 'sf', 'America/Los_Angeles', 'en')")
+		if err != nil {
+			t.Fatalf("Failed to insert agency: %s", err)
+		}
+
+		_, err = db.ExecContext(ctx, "INSERT INTO stops (stop_id, name, location, agency_id, type, parent_id) VALUES ('902101', 'Lake Merritt', 'sf', 'BA', 'train', '')")
+		if err != nil {
+			t.Fatalf("Failed to insert stop: %s", err)
+		}
+	}
+
+	if err := runMigrations(ctx, db, slog.New(slog.DiscardHandler), 3); err != nil {
+		t.Fatalf("Failed to run the remaining migrations: %s", err)
+	}
+
+	for table, want := range map[string]int{"agencies": 1, "stops": 1} {
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			t.Fatalf("Failed to count %s: %s", table, err)
+		}
+
+		if count != want {
+			t.Errorf("expected %d row in %s but got %d", want, table, count)
+		}
+	}
+}
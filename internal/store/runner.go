@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 )
 
 // migration is a record of a database migration that was executed.
@@ -40,7 +42,7 @@ func migrationCount(ctx context.Context, db *sql.DB) (int, error) {
 	return count, nil
 }
 
-func runMigrations(ctx context.Context, db *sql.DB, rowCount int) error {
+func runMigrations(ctx context.Context, db *sql.DB, log *slog.Logger, rowCount int) error {
 	migrationRows, err := currentMigrations(ctx, db, rowCount)
 	if err != nil {
 		return err
@@ -48,7 +50,7 @@ func runMigrations(ctx context.Context, db *sql.DB, rowCount int) error {
 
 	for i, cs := range migrationChangesets {
 		if i+1 > len(migrationRows) {
-			err = run(ctx, db, &cs)
+			err = run(ctx, db, log, &cs)
 			if err != nil {
 				return err
 			}
@@ -92,13 +94,15 @@ func currentMigrations(ctx context.Context, db *sql.DB, rowCount int) ([]migrati
 	return migrationRows, rows.Err()
 }
 
-func run(ctx context.Context, db *sql.DB, cs *changeset) error {
+func run(ctx context.Context, db *sql.DB, log *slog.Logger, cs *changeset) error {
+	start := time.Now()
+
 	trx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
 
-	defer rollback(trx)
+	defer rollback(ctx, log, trx)
 
 	err = cs.Up(ctx, trx)
 	if err != nil {
@@ -115,5 +119,7 @@ func run(ctx context.Context, db *sql.DB, cs *changeset) error {
 		return err
 	}
 
+	log.DebugContext(ctx, "migration applied", "name", cs.Name, "elapsed", time.Since(start))
+
 	return nil
 }
@@ -11,6 +11,8 @@ const createMigrationsTableSQL = `CREATE TABLE IF NOT EXISTS migrations (
 
 const countMigrationsSQL = "SELECT COUNT(*) FROM migrations"
 
+const countMigrationsTableSQL = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'migrations'"
+
 const selectMigrationsSQL = "SELECT rowid, name, DATETIME(migrated_at, 'localtime') FROM migrations"
 
 const insertMigrationSQL = "INSERT INTO migrations (name) VALUES (?)"
@@ -29,7 +31,24 @@ const createAgenciesTableSQL = `CREATE TABLE agencies (
 	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 )`
 
-const insertAgencySQL = "INSERT INTO agencies (agency_id, name, location, timezone, language) VALUES (?, ?, ?, ?, ?)"
+// createAgencyLocationIndexSQL makes (location, agency_id) the natural key that upsertAgencySQL conflicts on.
+const createAgencyLocationIndexSQL = "CREATE UNIQUE INDEX agency_location_id_index ON agencies(location, agency_id)"
+
+const dropAgencyLocationIndexSQL = "DROP INDEX IF EXISTS agency_location_id_index"
+
+// dedupeAgenciesSQL keeps the most recently inserted row of each (location, agency_id).
+const dedupeAgenciesSQL = "DELETE FROM agencies WHERE rowid NOT IN (SELECT MAX(rowid) FROM agencies GROUP BY location, agency_id)"
+
+// upsertAgencySQL only touches updated_at when a column actually changed.
+const upsertAgencySQL = `INSERT INTO agencies (agency_id, name, location, timezone, language) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (location, agency_id) DO UPDATE SET
+	name = excluded.name,
+	timezone = excluded.timezone,
+	language = excluded.language,
+	updated_at = CURRENT_TIMESTAMP
+WHERE name IS NOT excluded.name
+	OR timezone IS NOT excluded.timezone
+	OR language IS NOT excluded.language`
 
 const selectAgenciesByLocationSQL = "SELECT rowid, * FROM agencies WHERE location = ?"
 
@@ -77,4 +96,260 @@ const selectStopsByLocationSQL = "SELECT rowid, * FROM stops WHERE location = ?"
 
 const selectParentStopsByLocationSQL = `SELECT rowid, * FROM stops WHERE location = ? AND parent_id = ""`
 
-const insertStopSQL = "INSERT INTO stops (stop_id, name, location, agency_id, latitude, longitude, type, parent_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+// createStopLocationIDIndexSQL makes (location, stop_id) the natural key that upsertStopSQL conflicts on.
+const createStopLocationIDIndexSQL = "CREATE UNIQUE INDEX stop_location_id_index ON stops(location, stop_id)"
+
+const dropStopLocationIDIndexSQL = "DROP INDEX IF EXISTS stop_location_id_index"
+
+// dedupeStopsSQL keeps the most recently inserted row of each (location, stop_id).
+const dedupeStopsSQL = "DELETE FROM stops WHERE rowid NOT IN (SELECT MAX(rowid) FROM stops GROUP BY location, stop_id)"
+
+const selectSeededLocationsSQL = "SELECT DISTINCT location FROM stops ORDER BY location"
+
+const selectStopIDsByLocationSQL = "SELECT stop_id FROM stops WHERE location = ?"
+
+const deleteStopSQL = "DELETE FROM stops WHERE location = ? AND stop_id = ?"
+
+// upsertStopSQL only touches updated_at when a column actually changed.
+const upsertStopSQL = `INSERT INTO stops (stop_id, name, location, agency_id, latitude, longitude, type, parent_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (location, stop_id) DO UPDATE SET
+	name = excluded.name,
+	agency_id = excluded.agency_id,
+	latitude = excluded.latitude,
+	longitude = excluded.longitude,
+	type = excluded.type,
+	parent_id = excluded.parent_id,
+	updated_at = CURRENT_TIMESTAMP
+WHERE name IS NOT excluded.name
+	OR agency_id IS NOT excluded.agency_id
+	OR latitude IS NOT excluded.latitude
+	OR longitude IS NOT excluded.longitude
+	OR type IS NOT excluded.type
+	OR parent_id IS NOT excluded.parent_id`
+
+// selectStopNamesSQL is completed with one placeholder per stop ID by inPlaceholders.
+const selectStopNamesSQL = "SELECT stop_id, name FROM stops WHERE location = ? AND stop_id IN (%s)"
+
+/*
+	TRIPS TABLE
+*/
+
+const createTripsTableSQL = `CREATE TABLE trips (
+	trip_id TEXT NOT NULL,
+	location REFERENCES locations(slug),
+	route_id TEXT NOT NULL,
+	headsign TEXT,
+	shape_id TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+// createTripLocationIDIndexSQL makes (location, trip_id) the natural key that upsertTripSQL conflicts on.
+const createTripLocationIDIndexSQL = "CREATE UNIQUE INDEX trip_location_id_index ON trips(location, trip_id)"
+
+const dropTripLocationIDIndexSQL = "DROP INDEX IF EXISTS trip_location_id_index"
+
+const dropTripsTableSQL = "DROP TABLE IF EXISTS trips"
+
+// upsertTripSQL only touches updated_at when a column actually changed.
+const upsertTripSQL = `INSERT INTO trips (trip_id, location, route_id, headsign, shape_id) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (location, trip_id) DO UPDATE SET
+	route_id = excluded.route_id,
+	headsign = excluded.headsign,
+	shape_id = excluded.shape_id,
+	updated_at = CURRENT_TIMESTAMP
+WHERE route_id IS NOT excluded.route_id
+	OR headsign IS NOT excluded.headsign
+	OR shape_id IS NOT excluded.shape_id`
+
+const selectTripIDsByLocationSQL = "SELECT trip_id FROM trips WHERE location = ?"
+
+const deleteTripSQL = "DELETE FROM trips WHERE location = ? AND trip_id = ?"
+
+// selectTripRoutesSQL is completed with one placeholder per trip ID by inPlaceholders.
+const selectTripRoutesSQL = "SELECT trip_id, route_id FROM trips WHERE location = ? AND trip_id IN (%s)"
+
+/*
+	NOTIFIED TABLE
+*/
+
+// createNotifiedTableSQL remembers what transit notify already sent, so a restart
+// doesn't send it again.
+const createNotifiedTableSQL = `CREATE TABLE notified (
+	key TEXT NOT NULL,
+	location REFERENCES locations(slug),
+	notified_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+const createNotifiedLocationKeyIndexSQL = "CREATE UNIQUE INDEX notified_location_key_index ON notified(location, key)"
+
+const dropNotifiedLocationKeyIndexSQL = "DROP INDEX IF EXISTS notified_location_key_index"
+
+const dropNotifiedTableSQL = "DROP TABLE IF EXISTS notified"
+
+const insertNotifiedSQL = "INSERT INTO notified (location, key) VALUES (?, ?) ON CONFLICT (location, key) DO NOTHING"
+
+const deleteNotifiedSQL = "DELETE FROM notified WHERE location = ? AND key = ?"
+
+const deleteNotifiedBeforeSQL = "DELETE FROM notified WHERE notified_at < ?"
+
+/*
+	ALERT HISTORY TABLE
+*/
+
+// createAlertHistoryTableSQL keeps every alert seen, after the feeds drop it. affected
+// and periods are JSON.
+const createAlertHistoryTableSQL = `CREATE TABLE alert_history (
+	key TEXT NOT NULL,
+	location REFERENCES locations(slug),
+	alert_id TEXT,
+	source TEXT NOT NULL,
+	agency_id TEXT,
+	effect TEXT,
+	cause TEXT,
+	description TEXT,
+	affected TEXT,
+	periods TEXT,
+	first_seen DATETIME NOT NULL,
+	last_seen DATETIME NOT NULL
+)`
+
+const createAlertHistoryLocationKeyIndexSQL = "CREATE UNIQUE INDEX alert_history_location_key_index ON alert_history(location, key)"
+
+const createAlertHistoryLastSeenIndexSQL = "CREATE INDEX alert_history_last_seen_index ON alert_history(location, last_seen)"
+
+const dropAlertHistoryLocationKeyIndexSQL = "DROP INDEX IF EXISTS alert_history_location_key_index"
+
+const dropAlertHistoryLastSeenIndexSQL = "DROP INDEX IF EXISTS alert_history_last_seen_index"
+
+const dropAlertHistoryTableSQL = "DROP TABLE IF EXISTS alert_history"
+
+// upsertAlertHistorySQL keeps first_seen from the first sighting and takes everything
+// else from the latest one.
+const upsertAlertHistorySQL = `INSERT INTO alert_history (key, location, alert_id, source, agency_id, effect, cause, description, affected, periods, first_seen, last_seen)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (location, key) DO UPDATE SET
+	effect = excluded.effect,
+	cause = excluded.cause,
+	description = excluded.description,
+	affected = excluded.affected,
+	periods = excluded.periods,
+	last_seen = MAX(last_seen, excluded.last_seen)`
+
+const deleteAlertHistoryBeforeSQL = "DELETE FROM alert_history WHERE location = ? AND last_seen < ?"
+
+const selectAlertHistorySQL = `SELECT alert_id, source, agency_id, effect, cause, description, affected, periods, first_seen, last_seen
+FROM alert_history WHERE location = ? AND last_seen >= ? ORDER BY first_seen`
+
+/*
+	PREDICTIONS TABLE
+*/
+
+// createPredictionsTableSQL logs departures as they were predicted, for `transit stats`.
+const createPredictionsTableSQL = `CREATE TABLE predictions (
+	location REFERENCES locations(slug),
+	source TEXT NOT NULL,
+	stop_id TEXT NOT NULL,
+	stop_name TEXT,
+	line TEXT,
+	headsign TEXT,
+	arrives DATETIME NOT NULL,
+	fetched_at DATETIME NOT NULL
+)`
+
+const createPredictionsStopIndexSQL = "CREATE INDEX predictions_stop_index ON predictions(location, stop_id, fetched_at)"
+
+const dropPredictionsStopIndexSQL = "DROP INDEX IF EXISTS predictions_stop_index"
+
+const dropPredictionsTableSQL = "DROP TABLE IF EXISTS predictions"
+
+const insertPredictionSQL = `INSERT INTO predictions (location, source, stop_id, stop_name, line, headsign, arrives, fetched_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+const deletePredictionsBeforeSQL = "DELETE FROM predictions WHERE fetched_at < ?"
+
+// selectPredictionsSQL is completed with one placeholder per stop ID by inPlaceholders.
+const selectPredictionsSQL = `SELECT source, stop_id, stop_name, line, headsign, arrives, fetched_at
+FROM predictions WHERE location = ? AND fetched_at >= ? AND stop_id IN (%s) ORDER BY fetched_at, arrives`
+
+/*
+	PATTERN STOPS TABLE
+*/
+
+// createPatternStopsTableSQL keeps the stops each pattern calls at, a row per stop.
+// Times are seconds after the pattern leaves its first stop.
+const createPatternStopsTableSQL = `CREATE TABLE pattern_stops (
+	location REFERENCES locations(slug),
+	pattern_id TEXT NOT NULL,
+	route_id TEXT NOT NULL,
+	line TEXT,
+	headsign TEXT,
+	sequence INTEGER NOT NULL,
+	stop_id TEXT NOT NULL,
+	arrives INTEGER NOT NULL,
+	departs INTEGER NOT NULL
+)`
+
+const createPatternStopsIndexSQL = "CREATE UNIQUE INDEX pattern_stops_index ON pattern_stops(location, pattern_id, sequence)"
+
+const dropPatternStopsIndexSQL = "DROP INDEX IF EXISTS pattern_stops_index"
+
+const dropPatternStopsTableSQL = "DROP TABLE IF EXISTS pattern_stops"
+
+const deletePatternStopsSQL = "DELETE FROM pattern_stops WHERE location = ?"
+
+const insertPatternStopSQL = `INSERT INTO pattern_stops (location, pattern_id, route_id, line, headsign, sequence, stop_id, arrives, departs)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+const selectPatternStopsSQL = `SELECT pattern_id, route_id, line, headsign, stop_id, arrives, departs
+FROM pattern_stops WHERE location = ? ORDER BY pattern_id, sequence`
+
+/*
+	SEEDED TABLE
+*/
+
+// createSeededTableSQL records when each location's static data was last downloaded.
+// Upserts leave unchanged stops alone, so their updated_at can't say that.
+const createSeededTableSQL = `CREATE TABLE seeded (
+	location REFERENCES locations(slug),
+	seeded_at DATETIME NOT NULL
+)`
+
+const createSeededLocationIndexSQL = "CREATE UNIQUE INDEX seeded_location_index ON seeded(location)"
+
+const dropSeededLocationIndexSQL = "DROP INDEX IF EXISTS seeded_location_index"
+
+const dropSeededTableSQL = "DROP TABLE IF EXISTS seeded"
+
+const upsertSeededSQL = "INSERT INTO seeded (location, seeded_at) VALUES (?, ?) ON CONFLICT (location) DO UPDATE SET seeded_at = excluded.seeded_at"
+
+const selectSeededAtSQL = "SELECT seeded_at FROM seeded WHERE location = ?"
+
+/*
+	EMPTY POLLS TABLE
+*/
+
+// createEmptyPollsTableSQL counts the polls in a row that transit notify found a
+// watched station without departures, so one empty poll isn't taken for an outage.
+const createEmptyPollsTableSQL = `CREATE TABLE empty_polls (
+	location REFERENCES locations(slug),
+	key TEXT NOT NULL,
+	polls INTEGER NOT NULL DEFAULT 1,
+	polled_at DATETIME NOT NULL
+)`
+
+const createEmptyPollsLocationKeyIndexSQL = "CREATE UNIQUE INDEX empty_polls_location_key_index ON empty_polls(location, key)"
+
+const dropEmptyPollsLocationKeyIndexSQL = "DROP INDEX IF EXISTS empty_polls_location_key_index"
+
+const dropEmptyPollsTableSQL = "DROP TABLE IF EXISTS empty_polls"
+
+// upsertEmptyPollSQL starts the count over when the last poll was before the cutoff
+// in the third placeholder.
+const upsertEmptyPollSQL = `INSERT INTO empty_polls (location, key, polled_at) VALUES (?, ?, ?)
+ON CONFLICT (location, key) DO UPDATE SET
+	polls = CASE WHEN polled_at < ? THEN 1 ELSE polls + 1 END,
+	polled_at = excluded.polled_at
+RETURNING polls`
+
+const deleteEmptyPollsSQL = "DELETE FROM empty_polls WHERE location = ? AND key = ?"
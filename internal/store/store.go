@@ -5,8 +5,12 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/ismailshak/transit/internal/transit"
 	_ "modernc.org/sqlite"
@@ -15,18 +19,34 @@ import (
 // Store is a handle on the SQLite database. The zero value isn't usable
 // so build one with [New].
 type Store struct {
-	db *sql.DB
+	db  *sql.DB
+	log *slog.Logger
+}
+
+// Option configures a Store built by [New].
+type Option func(*Store)
+
+// WithLogger writes query timings and migration steps to log at debug level.
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Store) {
+		s.log = log
+	}
 }
 
 // New opens the SQLite database at path. The file is created if it doesn't
 // exist and no connection is made until the first query.
-func New(path string) (*Store, error) {
+func New(path string, opts ...Option) (*Store, error) {
 	conn, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Store{db: conn}, nil
+	s := &Store{db: conn, log: slog.New(slog.DiscardHandler)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
 
 // Ping verifies the connection to the database.
@@ -55,7 +75,7 @@ func (s *Store) SyncMigrations(ctx context.Context) error {
 		return nil
 	}
 
-	err = runMigrations(ctx, s.db, count)
+	err = runMigrations(ctx, s.db, s.log, count)
 	if err != nil {
 		return err
 	}
@@ -63,16 +83,42 @@ func (s *Store) SyncMigrations(ctx context.Context) error {
 	return nil
 }
 
+// MigrationState reports how many migrations have been applied to the database and
+// how many this build knows about. It only reads, so it describes the database as
+// it was found. A database that was never migrated has applied none.
+func (s *Store) MigrationState(ctx context.Context) (int, int, error) {
+	defer s.trace(ctx, "migration state", time.Now())
+
+	var tables int
+	if err := s.db.QueryRowContext(ctx, countMigrationsTableSQL).Scan(&tables); err != nil {
+		return 0, 0, fmt.Errorf("scan migrations table: %w", err)
+	}
+
+	if tables == 0 {
+		return 0, len(migrationChangesets), nil
+	}
+
+	applied, err := migrationCount(ctx, s.db)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return applied, len(migrationChangesets), nil
+}
+
 // InsertAgencies writes agencies in one transaction. Nothing is inserted if any row fails.
+// An agency already stored for its location is updated in place, so seeding twice is safe.
 func (s *Store) InsertAgencies(ctx context.Context, agencies []transit.Agency) error {
+	defer s.trace(ctx, "insert agencies", time.Now())
+
 	trx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	defer rollback(trx)
+	defer rollback(ctx, s.log, trx)
 
-	stmt, err := trx.PrepareContext(ctx, insertAgencySQL)
+	stmt, err := trx.PrepareContext(ctx, upsertAgencySQL)
 	if err != nil {
 		return err
 	}
@@ -94,6 +140,8 @@ func (s *Store) InsertAgencies(ctx context.Context, agencies []transit.Agency) e
 
 // Location returns one location by its slug. A slug with no row returns nil.
 func (s *Store) Location(ctx context.Context, location transit.LocationSlug) (*transit.Location, error) {
+	defer s.trace(ctx, "location", time.Now())
+
 	row := s.db.QueryRowContext(ctx, selectLocationSQL, location)
 
 	var l transit.Location
@@ -113,6 +161,8 @@ func (s *Store) Location(ctx context.Context, location transit.LocationSlug) (*t
 
 // AllLocations returns every location the migrations seeded.
 func (s *Store) AllLocations(ctx context.Context) ([]transit.Location, error) {
+	defer s.trace(ctx, "all locations", time.Now())
+
 	rows, err := s.db.QueryContext(ctx, selectAllLocationsSQL)
 	if err != nil {
 		return nil, fmt.Errorf("query locations: %w", err)
@@ -135,10 +185,37 @@ func (s *Store) AllLocations(ctx context.Context) ([]transit.Location, error) {
 	return locations, rows.Err()
 }
 
+// SeededLocations returns the slugs of every location that has stops stored, in
+// alphabetical order. A location that was never initialized isn't included.
+func (s *Store) SeededLocations(ctx context.Context) ([]transit.LocationSlug, error) {
+	defer s.trace(ctx, "seeded locations", time.Now())
+
+	rows, err := s.db.QueryContext(ctx, selectSeededLocationsSQL)
+	if err != nil {
+		return nil, fmt.Errorf("query seeded locations: %w", err)
+	}
+
+	defer rows.Close()
+
+	var slugs []transit.LocationSlug
+	for rows.Next() {
+		var slug transit.LocationSlug
+		if err := rows.Scan(&slug); err != nil {
+			return nil, fmt.Errorf("scan seeded location: %w", err)
+		}
+
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, rows.Err()
+}
+
 // StopsByLocation returns the stops seeded for a location. parentsOnly only returns
 // stops with no parent. Those are the stations and not the platforms
 // underneath them.
 func (s *Store) StopsByLocation(ctx context.Context, location transit.LocationSlug, parentsOnly bool) ([]transit.Stop, error) {
+	defer s.trace(ctx, "stops by location", time.Now())
+
 	var statement string
 	if parentsOnly {
 		statement = selectParentStopsByLocationSQL
@@ -180,15 +257,18 @@ func (s *Store) StopsByLocation(ctx context.Context, location transit.LocationSl
 }
 
 // InsertStops writes stops in one transaction. Nothing is inserted if any row fails.
+// A stop already stored for its location is updated in place, so seeding twice is safe.
 func (s *Store) InsertStops(ctx context.Context, stops []transit.Stop) error {
+	defer s.trace(ctx, "insert stops", time.Now())
+
 	trx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	defer rollback(trx)
+	defer rollback(ctx, s.log, trx)
 
-	stmt, err := trx.PrepareContext(ctx, insertStopSQL)
+	stmt, err := trx.PrepareContext(ctx, upsertStopSQL)
 	if err != nil {
 		return err
 	}
@@ -208,8 +288,615 @@ func (s *Store) InsertStops(ctx context.Context, stops []transit.Stop) error {
 	return nil
 }
 
+// DeleteStops removes the stops with the given IDs from a location in one transaction.
+// An ID with no row is ignored.
+func (s *Store) DeleteStops(ctx context.Context, location transit.LocationSlug, stopIDs []string) error {
+	defer s.trace(ctx, "delete stops", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	if err = deleteStops(ctx, trx, location, stopIDs); err != nil {
+		return err
+	}
+
+	return trx.Commit()
+}
+
+// PruneStops removes the stops of a location that aren't in current, which is usually
+// a freshly fetched feed. It returns the IDs it removed.
+func (s *Store) PruneStops(ctx context.Context, location transit.LocationSlug, current []transit.Stop) ([]string, error) {
+	defer s.trace(ctx, "prune stops", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	keep := make(map[string]struct{}, len(current))
+	for _, stop := range current {
+		keep[stop.StopID] = struct{}{}
+	}
+
+	rows, err := trx.QueryContext(ctx, selectStopIDsByLocationSQL, location)
+	if err != nil {
+		return nil, fmt.Errorf("query stop ids: %w", err)
+	}
+
+	var vanished []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan stop id: %w", err)
+		}
+
+		if _, ok := keep[id]; !ok {
+			vanished = append(vanished, id)
+		}
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = deleteStops(ctx, trx, location, vanished); err != nil {
+		return nil, err
+	}
+
+	if err = trx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return vanished, nil
+}
+
+func deleteStops(ctx context.Context, trx *sql.Tx, location transit.LocationSlug, stopIDs []string) error {
+	if len(stopIDs) == 0 {
+		return nil
+	}
+
+	stmt, err := trx.PrepareContext(ctx, deleteStopSQL)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range stopIDs {
+		if _, err = stmt.ExecContext(ctx, location, id); err != nil {
+			return fmt.Errorf("delete stop %q: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// MarkSeeded records that a location's static data was downloaded at t.
+func (s *Store) MarkSeeded(ctx context.Context, location transit.LocationSlug, t time.Time) error {
+	defer s.trace(ctx, "mark seeded", time.Now())
+
+	if _, err := s.db.ExecContext(ctx, upsertSeededSQL, location, t.UTC().Format(time.DateTime)); err != nil {
+		return fmt.Errorf("insert seeded %q: %w", location, err)
+	}
+
+	return nil
+}
+
+// SeededAt returns when a location's static data was last downloaded, in UTC. A
+// location never seeded, or seeded before this was recorded, returns the zero time.
+func (s *Store) SeededAt(ctx context.Context, location transit.LocationSlug) (time.Time, error) {
+	defer s.trace(ctx, "seeded at", time.Now())
+
+	var seeded string
+	err := s.db.QueryRowContext(ctx, selectSeededAtSQL, location).Scan(&seeded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("scan seeded at: %w", err)
+	}
+
+	return parseTimestamp(seeded)
+}
+
+// StopNames maps the given stop IDs of a location to their names. An ID with no
+// stop is left out of the map.
+func (s *Store) StopNames(ctx context.Context, location transit.LocationSlug, stopIDs []string) (map[string]string, error) {
+	defer s.trace(ctx, "stop names", time.Now())
+
+	return s.lookup(ctx, selectStopNamesSQL, location, stopIDs)
+}
+
+// InsertTrips writes trips in one transaction. Nothing is inserted if any row fails.
+// A trip already stored for the location is updated in place, so seeding twice is safe.
+func (s *Store) InsertTrips(ctx context.Context, location transit.LocationSlug, trips []transit.Trip) error {
+	defer s.trace(ctx, "insert trips", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	stmt, err := trx.PrepareContext(ctx, upsertTripSQL)
+	if err != nil {
+		return err
+	}
+
+	for _, trip := range trips {
+		_, err = stmt.ExecContext(ctx, trip.TripID, location, trip.RouteID, trip.Headsign, trip.ShapeID)
+		if err != nil {
+			return fmt.Errorf("insert trip %q: %w", trip.TripID, err)
+		}
+	}
+
+	return trx.Commit()
+}
+
+// PruneTrips removes the trips of a location that aren't in current, which is usually
+// a freshly fetched schedule. It returns the IDs it removed.
+func (s *Store) PruneTrips(ctx context.Context, location transit.LocationSlug, current []transit.Trip) ([]string, error) {
+	defer s.trace(ctx, "prune trips", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	keep := make(map[string]struct{}, len(current))
+	for _, trip := range current {
+		keep[trip.TripID] = struct{}{}
+	}
+
+	rows, err := trx.QueryContext(ctx, selectTripIDsByLocationSQL, location)
+	if err != nil {
+		return nil, fmt.Errorf("query trip ids: %w", err)
+	}
+
+	var vanished []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan trip id: %w", err)
+		}
+
+		if _, ok := keep[id]; !ok {
+			vanished = append(vanished, id)
+		}
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(vanished) > 0 {
+		stmt, err := trx.PrepareContext(ctx, deleteTripSQL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range vanished {
+			if _, err = stmt.ExecContext(ctx, location, id); err != nil {
+				return nil, fmt.Errorf("delete trip %q: %w", id, err)
+			}
+		}
+	}
+
+	if err = trx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return vanished, nil
+}
+
+// TripRoutes maps the given trip IDs of a location to the route each one runs on.
+// An ID with no trip is left out of the map.
+func (s *Store) TripRoutes(ctx context.Context, location transit.LocationSlug, tripIDs []string) (map[string]string, error) {
+	defer s.trace(ctx, "trip routes", time.Now())
+
+	return s.lookup(ctx, selectTripRoutesSQL, location, tripIDs)
+}
+
+// ReplacePatterns swaps the patterns stored for a location for patterns, in one
+// transaction. Patterns are timed from whichever trip the schedule lists first, so
+// they're replaced wholesale rather than merged with an older schedule's.
+func (s *Store) ReplacePatterns(ctx context.Context, location transit.LocationSlug, patterns []transit.Pattern) error {
+	defer s.trace(ctx, "replace patterns", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	if _, err := trx.ExecContext(ctx, deletePatternStopsSQL, location); err != nil {
+		return fmt.Errorf("delete patterns: %w", err)
+	}
+
+	stmt, err := trx.PrepareContext(ctx, insertPatternStopSQL)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range patterns {
+		for seq, stop := range p.Stops {
+			_, err = stmt.ExecContext(ctx, location, p.PatternID, p.RouteID, p.Line, p.Headsign, seq, stop.StopID,
+				int64(stop.Arrives/time.Second), int64(stop.Departs/time.Second))
+			if err != nil {
+				return fmt.Errorf("insert pattern %q: %w", p.PatternID, err)
+			}
+		}
+	}
+
+	return trx.Commit()
+}
+
+// Patterns reads every pattern stored for a location.
+func (s *Store) Patterns(ctx context.Context, location transit.LocationSlug) ([]transit.Pattern, error) {
+	defer s.trace(ctx, "patterns", time.Now())
+
+	rows, err := s.db.QueryContext(ctx, selectPatternStopsSQL, location)
+	if err != nil {
+		return nil, fmt.Errorf("query patterns: %w", err)
+	}
+
+	defer rows.Close()
+
+	var patterns []transit.Pattern
+	for rows.Next() {
+		var p transit.Pattern
+		var stop transit.PatternStop
+		var arrives, departs int64
+		if err := rows.Scan(&p.PatternID, &p.RouteID, &p.Line, &p.Headsign, &stop.StopID, &arrives, &departs); err != nil {
+			return nil, fmt.Errorf("scan pattern: %w", err)
+		}
+
+		stop.Arrives, stop.Departs = time.Duration(arrives)*time.Second, time.Duration(departs)*time.Second
+
+		if n := len(patterns); n == 0 || patterns[n-1].PatternID != p.PatternID {
+			patterns = append(patterns, p)
+		}
+
+		last := &patterns[len(patterns)-1]
+		last.Stops = append(last.Stops, stop)
+	}
+
+	return patterns, rows.Err()
+}
+
+// MarkNotified remembers that the notification identified by key was sent for a
+// location. It reports false when it already had been, so a caller can send only
+// when it gets true.
+func (s *Store) MarkNotified(ctx context.Context, location transit.LocationSlug, key string) (bool, error) {
+	defer s.trace(ctx, "mark notified", time.Now())
+
+	res, err := s.db.ExecContext(ctx, insertNotifiedSQL, location, key)
+	if err != nil {
+		return false, fmt.Errorf("insert notified %q: %w", key, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// ForgetNotified drops key, so the next MarkNotified for it reports true again. It
+// reports whether key had been marked.
+func (s *Store) ForgetNotified(ctx context.Context, location transit.LocationSlug, key string) (bool, error) {
+	defer s.trace(ctx, "forget notified", time.Now())
+
+	res, err := s.db.ExecContext(ctx, deleteNotifiedSQL, location, key)
+	if err != nil {
+		return false, fmt.Errorf("delete notified %q: %w", key, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// PruneNotified drops everything marked before t, in every location. It returns how
+// many were dropped.
+func (s *Store) PruneNotified(ctx context.Context, t time.Time) (int64, error) {
+	defer s.trace(ctx, "prune notified", time.Now())
+
+	res, err := s.db.ExecContext(ctx, deleteNotifiedBeforeSQL, t.UTC().Format(time.DateTime))
+	if err != nil {
+		return 0, fmt.Errorf("prune notified: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// CountEmptyPoll records a poll at t that found nothing for key, and returns how many
+// there have been in a row since [Store.ResetEmptyPolls]. A poll more than maxGap
+// after the last one starts the count over, the ones before it are too old to
+// say the last poll wasn't a fluke.
+func (s *Store) CountEmptyPoll(ctx context.Context, location transit.LocationSlug, key string, t time.Time, maxGap time.Duration) (int, error) {
+	defer s.trace(ctx, "count empty poll", time.Now())
+
+	polled := t.UTC().Format(time.DateTime)
+	cutoff := t.Add(-maxGap).UTC().Format(time.DateTime)
+
+	var polls int
+	if err := s.db.QueryRowContext(ctx, upsertEmptyPollSQL, location, key, polled, cutoff).Scan(&polls); err != nil {
+		return 0, fmt.Errorf("count empty poll %q: %w", key, err)
+	}
+
+	return polls, nil
+}
+
+// ResetEmptyPolls starts the count of empty polls for key over.
+func (s *Store) ResetEmptyPolls(ctx context.Context, location transit.LocationSlug, key string) error {
+	defer s.trace(ctx, "reset empty polls", time.Now())
+
+	if _, err := s.db.ExecContext(ctx, deleteEmptyPollsSQL, location, key); err != nil {
+		return fmt.Errorf("reset empty polls %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// RecordAlerts adds alerts to a location's history as seen at seen. An alert already
+// there is updated to its latest version, and keeps when it was first seen.
+func (s *Store) RecordAlerts(ctx context.Context, location transit.LocationSlug, alerts []transit.Alert, seen time.Time) error {
+	defer s.trace(ctx, "record alerts", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	stmt, err := trx.PrepareContext(ctx, upsertAlertHistorySQL)
+	if err != nil {
+		return err
+	}
+
+	at := seen.UTC().Format(time.DateTime)
+	for _, a := range alerts {
+		affected, err := json.Marshal(a.Affected)
+		if err != nil {
+			return fmt.Errorf("encode affected of %q: %w", a.Key(), err)
+		}
+
+		periods, err := json.Marshal(a.Periods)
+		if err != nil {
+			return fmt.Errorf("encode periods of %q: %w", a.Key(), err)
+		}
+
+		_, err = stmt.ExecContext(ctx, a.Key(), location, a.ID, a.Source, a.AgencyID, a.Effect, a.Cause, a.Description, string(affected), string(periods), at, at)
+		if err != nil {
+			return fmt.Errorf("record alert %q: %w", a.Key(), err)
+		}
+	}
+
+	return trx.Commit()
+}
+
+// PruneAlertHistory drops the alerts of a location last seen before t. It returns how
+// many were dropped.
+func (s *Store) PruneAlertHistory(ctx context.Context, location transit.LocationSlug, t time.Time) (int64, error) {
+	defer s.trace(ctx, "prune alert history", time.Now())
+
+	res, err := s.db.ExecContext(ctx, deleteAlertHistoryBeforeSQL, location, t.UTC().Format(time.DateTime))
+	if err != nil {
+		return 0, fmt.Errorf("prune alert history: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// AlertHistory reads the alerts of a location seen at or after since, oldest first.
+func (s *Store) AlertHistory(ctx context.Context, location transit.LocationSlug, since time.Time) ([]transit.AlertRecord, error) {
+	defer s.trace(ctx, "alert history", time.Now())
+
+	rows, err := s.db.QueryContext(ctx, selectAlertHistorySQL, location, since.UTC().Format(time.DateTime))
+	if err != nil {
+		return nil, fmt.Errorf("query alert history: %w", err)
+	}
+
+	defer rows.Close()
+
+	var records []transit.AlertRecord
+	for rows.Next() {
+		var r transit.AlertRecord
+		var affected, periods, firstSeen, lastSeen string
+		if err := rows.Scan(
+			&r.ID,
+			&r.Source,
+			&r.AgencyID,
+			&r.Effect,
+			&r.Cause,
+			&r.Description,
+			&affected,
+			&periods,
+			&firstSeen,
+			&lastSeen,
+		); err != nil {
+			return nil, fmt.Errorf("scan alert history: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(affected), &r.Affected); err != nil {
+			return nil, fmt.Errorf("decode affected: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(periods), &r.Periods); err != nil {
+			return nil, fmt.Errorf("decode periods: %w", err)
+		}
+
+		if r.FirstSeen, err = parseTimestamp(firstSeen); err != nil {
+			return nil, err
+		}
+
+		if r.LastSeen, err = parseTimestamp(lastSeen); err != nil {
+			return nil, err
+		}
+
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// InsertPredictions logs departures as they were predicted, in one transaction.
+func (s *Store) InsertPredictions(ctx context.Context, location transit.LocationSlug, predictions []transit.Prediction) error {
+	defer s.trace(ctx, "insert predictions", time.Now())
+
+	trx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer rollback(ctx, s.log, trx)
+
+	stmt, err := trx.PrepareContext(ctx, insertPredictionSQL)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range predictions {
+		_, err = stmt.ExecContext(ctx, location, p.Source, p.StopID, p.StopName, p.Line, p.Headsign,
+			p.Arrives.UTC().Format(time.DateTime), p.Fetched.UTC().Format(time.DateTime))
+		if err != nil {
+			return fmt.Errorf("insert prediction: %w", err)
+		}
+	}
+
+	return trx.Commit()
+}
+
+// PrunePredictions drops every prediction fetched before t, in every location. It
+// returns how many were dropped.
+func (s *Store) PrunePredictions(ctx context.Context, t time.Time) (int64, error) {
+	defer s.trace(ctx, "prune predictions", time.Now())
+
+	res, err := s.db.ExecContext(ctx, deletePredictionsBeforeSQL, t.UTC().Format(time.DateTime))
+	if err != nil {
+		return 0, fmt.Errorf("prune predictions: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Predictions reads what was logged for the given stops of a location since since,
+// in the order it was fetched.
+func (s *Store) Predictions(ctx context.Context, location transit.LocationSlug, stopIDs []string, since time.Time) ([]transit.Prediction, error) {
+	defer s.trace(ctx, "predictions", time.Now())
+
+	if len(stopIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(stopIDs)+2)
+	args = append(args, location, since.UTC().Format(time.DateTime))
+	for _, id := range stopIDs {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(selectPredictionsSQL, inPlaceholders(len(stopIDs))), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query predictions: %w", err)
+	}
+
+	defer rows.Close()
+
+	var predictions []transit.Prediction
+	for rows.Next() {
+		var p transit.Prediction
+		var arrives, fetched string
+		if err := rows.Scan(&p.Source, &p.StopID, &p.StopName, &p.Line, &p.Headsign, &arrives, &fetched); err != nil {
+			return nil, fmt.Errorf("scan prediction: %w", err)
+		}
+
+		if p.Arrives, err = parseTimestamp(arrives); err != nil {
+			return nil, err
+		}
+
+		if p.Fetched, err = parseTimestamp(fetched); err != nil {
+			return nil, err
+		}
+
+		predictions = append(predictions, p)
+	}
+
+	return predictions, rows.Err()
+}
+
+// lookup runs a two column query, filtered to location and ids, into a map of the
+// first column to the second. query has a %s where the id placeholders go.
+func (s *Store) lookup(ctx context.Context, query string, location transit.LocationSlug, ids []string) (map[string]string, error) {
+	found := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, location)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(query, inPlaceholders(len(ids))), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+
+		found[key] = value
+	}
+
+	return found, rows.Err()
+}
+
+// inPlaceholders returns n comma separated placeholders for an IN clause.
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// parseTimestamp reads a CURRENT_TIMESTAMP column, which SQLite stores as UTC text.
+// The driver can hand it back in either layout depending on the column's declared type.
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range []string{time.DateTime, time.RFC3339Nano} {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("parse timestamp %q", value)
+}
+
 // CountStopsByLocation returns the number of stops seeded for a location slug.
 func (s *Store) CountStopsByLocation(ctx context.Context, location transit.LocationSlug) (int, error) {
+	defer s.trace(ctx, "count stops", time.Now())
+
 	row := s.db.QueryRowContext(ctx, countStopsByLocationSQL, location)
 
 	var count int
@@ -224,6 +911,8 @@ func (s *Store) CountStopsByLocation(ctx context.Context, location transit.Locat
 // returns an empty slice and no error. Nothing tells that apart from a location
 // with no agencies.
 func (s *Store) Agencies(ctx context.Context, location transit.LocationSlug) ([]transit.Agency, error) {
+	defer s.trace(ctx, "agencies", time.Now())
+
 	rows, err := s.db.QueryContext(ctx, selectAgenciesByLocationSQL, location)
 	if err != nil {
 		return nil, fmt.Errorf("query agencies: %w", err)
@@ -257,6 +946,8 @@ func (s *Store) Agencies(ctx context.Context, location transit.LocationSlug) ([]
 // MatchStops fuzzy matches query against the names of the stations seeded for a
 // location. Matches come back best first. No match is an empty slice.
 func (s *Store) MatchStops(ctx context.Context, location transit.LocationSlug, query string) ([]transit.Stop, error) {
+	defer s.trace(ctx, "match stops", time.Now())
+
 	stops, err := s.StopsByLocation(ctx, location, true)
 	if err != nil {
 		return nil, err
@@ -273,7 +964,14 @@ func (s *Store) MatchStops(ctx context.Context, location transit.LocationSlug, q
 }
 
 // rollback undoes trx unless it already committed which reports sql.ErrTxDone.
-// TODO: once there's a debug stream to write to report this rollback error
-func rollback(trx *sql.Tx) {
-	_ = trx.Rollback()
+func rollback(ctx context.Context, log *slog.Logger, trx *sql.Tx) {
+	if err := trx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.DebugContext(ctx, "rollback failed", "err", err)
+	}
+}
+
+// trace logs how long the query named op took, counted from start. It's meant to
+// be deferred at the top of a method so every return is timed.
+func (s *Store) trace(ctx context.Context, op string, start time.Time) {
+	s.log.DebugContext(ctx, "sql", "op", op, "elapsed", time.Since(start))
 }
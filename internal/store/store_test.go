@@ -1,10 +1,14 @@
 package store_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log/slog"
+	"path/filepath"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/ismailshak/transit/internal/store"
 	"github.com/ismailshak/transit/internal/transit"
@@ -38,6 +42,30 @@ func TestMigrationCompletes(t *testing.T) {
 	}
 }
 
+func TestMigrationStepsAreLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	db, err := store.New(filepath.Join(t.TempDir(), "transit-test-logged.db"), store.WithLogger(log))
+	if err != nil {
+		t.Fatal("Failed to connect to test database", err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := db.SyncMigrations(t.Context()); err != nil {
+		t.Fatalf("Failed to sync migrations. %s", err)
+	}
+
+	if _, err := db.MatchStops(t.Context(), testLocation, "metro"); err != nil {
+		t.Fatalf("Failed to match stops. %s", err)
+	}
+
+	assert.Contains(t, buf.String(), "migration applied")
+	assert.Contains(t, buf.String(), "name=0001")
+	assert.Contains(t, buf.String(), `op="match stops"`)
+}
+
 func TestGetValidLocation(t *testing.T) {
 	t.Parallel()
 
@@ -295,3 +323,571 @@ func TestInsertManyStops(t *testing.T) {
 		assert.NotNil(t, stop.UpdatedAt)
 	}
 }
+
+func TestInsertStopsTwiceUpdatesInPlace(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	if err := db.InsertStops(t.Context(), matchFixture); err != nil {
+		t.Fatalf("InsertStops() returned an error: %s", err)
+	}
+
+	renamed := slices.Clone(matchFixture)
+	renamed[2].Name = "Metro Centre"
+
+	if err := db.InsertStops(t.Context(), renamed); err != nil {
+		t.Fatalf("InsertStops() returned an error on the second seed: %s", err)
+	}
+
+	count, err := db.CountStopsByLocation(t.Context(), testLocation)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if count != 5 {
+		t.Errorf("expected 5 stops but got %d, the second seed duplicated rows", count)
+	}
+
+	matched, err := db.MatchStops(t.Context(), testLocation, "metro")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(matched) != 1 || matched[0].Name != "Metro Centre" {
+		t.Errorf("expected one renamed station but got %+v", matched)
+	}
+}
+
+func TestInsertAgenciesTwiceUpdatesInPlace(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	agencies := []transit.Agency{
+		{AgencyID: "MET", Name: "Moon Metro", Location: testLocation, Timezone: "UTC", Language: "en"},
+		{AgencyID: "MET", Name: "Mars Metro", Location: "mars", Timezone: "UTC", Language: "en"},
+	}
+
+	if err := db.InsertAgencies(t.Context(), agencies); err != nil {
+		t.Fatalf("InsertAgencies() returned an error: %s", err)
+	}
+
+	agencies[0].Name = "Lunar Metro"
+	if err := db.InsertAgencies(t.Context(), agencies); err != nil {
+		t.Fatalf("InsertAgencies() returned an error on the second seed: %s", err)
+	}
+
+	got, err := db.Agencies(t.Context(), testLocation)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 agency but got %d, the second seed duplicated rows", len(got))
+	}
+
+	assert.Equal(t, "Lunar Metro", got[0].Name)
+}
+
+func TestPruneStops(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	if err := db.InsertStops(t.Context(), matchFixture); err != nil {
+		t.Fatalf("InsertStops() returned an error: %s", err)
+	}
+
+	// Farragut West and the platform dropped out of the new feed. Mars isn't part of it at all.
+	current := slices.Clone(matchFixture[:3])
+
+	removed, err := db.PruneStops(t.Context(), testLocation, current)
+	if err != nil {
+		t.Fatalf("PruneStops() returned an error: %s", err)
+	}
+
+	assert.ElementsMatch(t, []string{"STN_C03", "PF_A01_1"}, removed)
+
+	stops, err := db.StopsByLocation(t.Context(), testLocation, false)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(stops) != 3 {
+		t.Errorf("expected 3 stops left but got %d", len(stops))
+	}
+
+	mars, err := db.CountStopsByLocation(t.Context(), "mars")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if mars != 1 {
+		t.Errorf("expected the other location to keep its stop but got %d", mars)
+	}
+}
+
+func TestDeleteStops(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	if err := db.InsertStops(t.Context(), matchFixture); err != nil {
+		t.Fatalf("InsertStops() returned an error: %s", err)
+	}
+
+	if err := db.DeleteStops(t.Context(), testLocation, []string{"STN_A07", "STN_X01", "missing"}); err != nil {
+		t.Fatalf("DeleteStops() returned an error: %s", err)
+	}
+
+	count, err := db.CountStopsByLocation(t.Context(), testLocation)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if count != 4 {
+		t.Errorf("expected 4 stops but got %d", count)
+	}
+
+	mars, err := db.CountStopsByLocation(t.Context(), "mars")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if mars != 1 {
+		t.Errorf("expected a stop id from another location to be left alone but got %d", mars)
+	}
+}
+
+func TestSeededLocations(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	slugs, err := db.SeededLocations(t.Context())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if len(slugs) != 0 {
+		t.Errorf("expected no seeded locations before a seed but got %v", slugs)
+	}
+
+	if err := db.InsertStops(t.Context(), matchFixture); err != nil {
+		t.Fatalf("InsertStops() returned an error: %s", err)
+	}
+
+	slugs, err = db.SeededLocations(t.Context())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	expected := []transit.LocationSlug{"mars", testLocation}
+	if !slices.Equal(expected, slugs) {
+		t.Errorf("expected %v but got %v", expected, slugs)
+	}
+}
+
+func TestMigrationState(t *testing.T) {
+	t.Parallel()
+
+	blank := blankDB(t)
+	applied, known, err := blank.MigrationState(t.Context())
+	if err != nil {
+		t.Fatalf("MigrationState() returned an error: %s", err)
+	}
+
+	assert.Equal(t, 0, applied)
+	assert.Positive(t, known)
+
+	migrated := migratedDB(t)
+	applied, known, err = migrated.MigrationState(t.Context())
+	if err != nil {
+		t.Fatalf("MigrationState() returned an error: %s", err)
+	}
+
+	assert.Equal(t, known, applied)
+}
+
+func TestSeededAt(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	seeded, err := db.SeededAt(t.Context(), testLocation)
+	if err != nil {
+		t.Fatalf("SeededAt() returned an error: %s", err)
+	}
+
+	assert.True(t, seeded.IsZero(), "a location never seeded has no age")
+
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, at := range []time.Time{first, first.Add(time.Hour)} {
+		if err := db.MarkSeeded(t.Context(), testLocation, at); err != nil {
+			t.Fatalf("MarkSeeded() returned an error: %s", err)
+		}
+	}
+
+	seeded, err = db.SeededAt(t.Context(), testLocation)
+	if err != nil {
+		t.Fatalf("SeededAt() returned an error: %s", err)
+	}
+
+	assert.Equal(t, first.Add(time.Hour), seeded)
+}
+
+func TestStopNames(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	if err := db.InsertStops(t.Context(), matchFixture); err != nil {
+		t.Fatalf("InsertStops() returned an error: %s", err)
+	}
+
+	names, err := db.StopNames(t.Context(), testLocation, []string{"STN_A01", "STN_C03", "STN_X01", "NOPE"})
+	if err != nil {
+		t.Fatalf("StopNames() returned an error: %s", err)
+	}
+
+	assert.Equal(t, map[string]string{"STN_A01": "Metro Center", "STN_C03": "Farragut West"}, names)
+}
+
+func TestInsertTripsTwiceUpdatesInPlace(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	trips := []transit.Trip{
+		{TripID: "T1", RouteID: "RD", Headsign: "Glenmont"},
+		{TripID: "T2", RouteID: "BL", Headsign: "Largo"},
+	}
+
+	if err := db.InsertTrips(t.Context(), testLocation, trips); err != nil {
+		t.Fatalf("InsertTrips() returned an error: %s", err)
+	}
+
+	trips[1].RouteID = "SV"
+	if err := db.InsertTrips(t.Context(), testLocation, trips); err != nil {
+		t.Fatalf("InsertTrips() returned an error on the second seed: %s", err)
+	}
+
+	routes, err := db.TripRoutes(t.Context(), testLocation, []string{"T1", "T2", "T3"})
+	if err != nil {
+		t.Fatalf("TripRoutes() returned an error: %s", err)
+	}
+
+	assert.Equal(t, map[string]string{"T1": "RD", "T2": "SV"}, routes)
+
+	others, err := db.TripRoutes(t.Context(), "mars", []string{"T1"})
+	if err != nil {
+		t.Fatalf("TripRoutes() returned an error: %s", err)
+	}
+
+	assert.Empty(t, others, "trips belong to the location they were seeded for")
+}
+
+func TestPruneTrips(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	trips := []transit.Trip{
+		{TripID: "T1", RouteID: "RD", Headsign: "Glenmont"},
+		{TripID: "T2", RouteID: "BL", Headsign: "Largo"},
+	}
+
+	if err := db.InsertTrips(t.Context(), testLocation, trips); err != nil {
+		t.Fatalf("InsertTrips() returned an error: %s", err)
+	}
+
+	if err := db.InsertTrips(t.Context(), "mars", trips); err != nil {
+		t.Fatalf("InsertTrips() returned an error: %s", err)
+	}
+
+	// T2 dropped out of the new schedule. Mars isn't part of it at all.
+	removed, err := db.PruneTrips(t.Context(), testLocation, trips[:1])
+	if err != nil {
+		t.Fatalf("PruneTrips() returned an error: %s", err)
+	}
+
+	assert.Equal(t, []string{"T2"}, removed)
+
+	routes, err := db.TripRoutes(t.Context(), testLocation, []string{"T1", "T2"})
+	if err != nil {
+		t.Fatalf("TripRoutes() returned an error: %s", err)
+	}
+
+	assert.Equal(t, map[string]string{"T1": "RD"}, routes)
+
+	others, err := db.TripRoutes(t.Context(), "mars", []string{"T1", "T2"})
+	if err != nil {
+		t.Fatalf("TripRoutes() returned an error: %s", err)
+	}
+
+	assert.Len(t, others, 2, "pruning one location leaves the others alone")
+}
+
+func TestReplacePatterns(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	red := transit.Pattern{
+		PatternID: "T1",
+		RouteID:   "RED",
+		Line:      "RD",
+		Headsign:  "Glenmont",
+		Stops: []transit.PatternStop{
+			{StopID: "PF_A01_C"},
+			{StopID: "PF_B01_C", Arrives: 2 * time.Minute, Departs: 2*time.Minute + 30*time.Second},
+		},
+	}
+	blue := transit.Pattern{PatternID: "T2", RouteID: "BLUE", Line: "BL", Headsign: "Largo", Stops: red.Stops}
+
+	if err := db.ReplacePatterns(t.Context(), testLocation, []transit.Pattern{red, blue}); err != nil {
+		t.Fatalf("ReplacePatterns() returned an error: %s", err)
+	}
+
+	if err := db.ReplacePatterns(t.Context(), testLocation, []transit.Pattern{red}); err != nil {
+		t.Fatalf("ReplacePatterns() returned an error on the second seed: %s", err)
+	}
+
+	got, err := db.Patterns(t.Context(), testLocation)
+	if err != nil {
+		t.Fatalf("Patterns() returned an error: %s", err)
+	}
+
+	assert.Equal(t, []transit.Pattern{red}, got, "the older schedule's patterns are gone")
+}
+
+func TestMarkNotified(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	first, err := db.MarkNotified(t.Context(), testLocation, "alert:wmata/1")
+	if err != nil {
+		t.Fatalf("MarkNotified() returned an error: %s", err)
+	}
+
+	again, err := db.MarkNotified(t.Context(), testLocation, "alert:wmata/1")
+	if err != nil {
+		t.Fatalf("MarkNotified() returned an error the second time: %s", err)
+	}
+
+	assert.True(t, first)
+	assert.False(t, again, "a key is only new once")
+
+	elsewhere, err := db.MarkNotified(t.Context(), "mars", "alert:wmata/1")
+	if err != nil {
+		t.Fatalf("MarkNotified() returned an error: %s", err)
+	}
+
+	assert.True(t, elsewhere, "keys are remembered per location")
+
+	forgot, err := db.ForgetNotified(t.Context(), testLocation, "alert:wmata/1")
+	if err != nil {
+		t.Fatalf("ForgetNotified() returned an error: %s", err)
+	}
+
+	assert.True(t, forgot)
+
+	renewed, err := db.MarkNotified(t.Context(), testLocation, "alert:wmata/1")
+	if err != nil {
+		t.Fatalf("MarkNotified() returned an error: %s", err)
+	}
+
+	assert.True(t, renewed, "a forgotten key is new again")
+}
+
+func TestCountEmptyPoll(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+	start := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+	count := func(key string, at time.Time) int {
+		t.Helper()
+
+		polls, err := db.CountEmptyPoll(t.Context(), testLocation, key, at, time.Hour)
+		if err != nil {
+			t.Fatalf("CountEmptyPoll() returned an error: %s", err)
+		}
+
+		return polls
+	}
+
+	assert.Equal(t, 1, count("no_service:dmv:metro", start))
+	assert.Equal(t, 2, count("no_service:dmv:metro", start.Add(time.Minute)))
+	assert.Equal(t, 1, count("no_service:dmv:gallery", start.Add(time.Minute)), "keys are counted apart")
+	assert.Equal(t, 1, count("no_service:dmv:metro", start.Add(3*time.Hour)), "a poll long after the last starts over")
+
+	if err := db.ResetEmptyPolls(t.Context(), testLocation, "no_service:dmv:metro"); err != nil {
+		t.Fatalf("ResetEmptyPolls() returned an error: %s", err)
+	}
+
+	assert.Equal(t, 1, count("no_service:dmv:metro", start.Add(3*time.Hour+time.Minute)), "a reset starts over")
+}
+
+func TestPruneNotified(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	if _, err := db.MarkNotified(t.Context(), testLocation, "alert:wmata/1"); err != nil {
+		t.Fatalf("MarkNotified() returned an error: %s", err)
+	}
+
+	pruned, err := db.PruneNotified(t.Context(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PruneNotified() returned an error: %s", err)
+	}
+
+	assert.Zero(t, pruned, "a recent key is kept")
+
+	pruned, err = db.PruneNotified(t.Context(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PruneNotified() returned an error: %s", err)
+	}
+
+	assert.Equal(t, int64(1), pruned)
+}
+
+func TestAlertHistory(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	delay := transit.Alert{
+		ID:          "42",
+		Source:      "wmata",
+		AgencyID:    "MET",
+		Effect:      "Delay",
+		Description: "Red Line delays",
+		Affected:    []transit.AlertRef{{Kind: transit.RefRoute, ID: "RD", Color: "#BF0D3E"}},
+		Periods:     []transit.ActivePeriod{{Starts: start}},
+	}
+	worse := delay
+	worse.Description = "Red Line delays of 20 minutes"
+	old := transit.Alert{ID: "7", Source: "wmata", Effect: "Closure", Description: "Station closed"}
+
+	if err := db.RecordAlerts(t.Context(), testLocation, []transit.Alert{old}, start.Add(-48*time.Hour)); err != nil {
+		t.Fatalf("RecordAlerts() returned an error: %s", err)
+	}
+
+	if err := db.RecordAlerts(t.Context(), testLocation, []transit.Alert{delay}, start); err != nil {
+		t.Fatalf("RecordAlerts() returned an error: %s", err)
+	}
+
+	if err := db.RecordAlerts(t.Context(), testLocation, []transit.Alert{worse}, start.Add(30*time.Minute)); err != nil {
+		t.Fatalf("RecordAlerts() returned an error: %s", err)
+	}
+
+	records, err := db.AlertHistory(t.Context(), testLocation, start.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("AlertHistory() returned an error: %s", err)
+	}
+
+	if !assert.Len(t, records, 1, "the alert last seen before since is left out") {
+		return
+	}
+
+	got := records[0]
+	assert.Equal(t, worse.Description, got.Description, "the latest version is kept")
+	assert.Equal(t, worse.Affected, got.Affected)
+	assert.True(t, got.Periods[0].Starts.Equal(start))
+	assert.Equal(t, start, got.FirstSeen)
+	assert.Equal(t, 30*time.Minute, got.Observed())
+
+	elsewhere, err := db.AlertHistory(t.Context(), "mars", time.Time{})
+	if err != nil {
+		t.Fatalf("AlertHistory() returned an error: %s", err)
+	}
+
+	assert.Empty(t, elsewhere)
+}
+
+func TestPruneAlertHistory(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	seen := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	alerts := []transit.Alert{{ID: "42", Source: "wmata", Effect: "Delay"}}
+	for _, location := range []transit.LocationSlug{testLocation, "mars"} {
+		if err := db.RecordAlerts(t.Context(), location, alerts, seen); err != nil {
+			t.Fatalf("RecordAlerts() returned an error: %s", err)
+		}
+	}
+
+	pruned, err := db.PruneAlertHistory(t.Context(), testLocation, seen)
+	if err != nil {
+		t.Fatalf("PruneAlertHistory() returned an error: %s", err)
+	}
+
+	assert.Zero(t, pruned, "an alert seen at the cutoff is kept")
+
+	pruned, err = db.PruneAlertHistory(t.Context(), testLocation, seen.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PruneAlertHistory() returned an error: %s", err)
+	}
+
+	assert.Equal(t, int64(1), pruned)
+
+	elsewhere, err := db.AlertHistory(t.Context(), "mars", time.Time{})
+	if err != nil {
+		t.Fatalf("AlertHistory() returned an error: %s", err)
+	}
+
+	assert.Len(t, elsewhere, 1, "other locations are left alone")
+}
+
+func TestPredictions(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	fetched := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	logged := []transit.Prediction{
+		{Source: "wmata", StopID: "A01", StopName: "Metro Center", Line: "RD", Headsign: "Glenmont", Arrives: fetched.Add(4 * time.Minute), Fetched: fetched},
+		{Source: "wmata", StopID: "C01", StopName: "Metro Center", Line: "BL", Headsign: "Largo", Arrives: fetched.Add(2 * time.Minute), Fetched: fetched},
+		{Source: "wmata", StopID: "B01", StopName: "Gallery Place", Line: "RD", Headsign: "Shady Grove", Arrives: fetched.Add(time.Minute), Fetched: fetched},
+		{Source: "wmata", StopID: "A01", StopName: "Metro Center", Line: "RD", Headsign: "Glenmont", Arrives: fetched.Add(4 * time.Minute), Fetched: fetched.Add(-time.Hour)},
+	}
+
+	if err := db.InsertPredictions(t.Context(), testLocation, logged); err != nil {
+		t.Fatalf("InsertPredictions() returned an error: %s", err)
+	}
+
+	got, err := db.Predictions(t.Context(), testLocation, []string{"A01", "C01"}, fetched.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Predictions() returned an error: %s", err)
+	}
+
+	assert.Equal(t, []transit.Prediction{logged[1], logged[0]}, got, "ordered by fetch then arrival, other stops and older fetches left out")
+}
+
+func TestPrunePredictions(t *testing.T) {
+	t.Parallel()
+
+	db := migratedDB(t)
+
+	fetched := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	logged := []transit.Prediction{
+		{Source: "wmata", StopID: "A01", Line: "RD", Arrives: fetched.Add(4 * time.Minute), Fetched: fetched},
+		{Source: "wmata", StopID: "A01", Line: "RD", Arrives: fetched.Add(4 * time.Minute), Fetched: fetched.Add(-48 * time.Hour)},
+	}
+
+	if err := db.InsertPredictions(t.Context(), testLocation, logged); err != nil {
+		t.Fatalf("InsertPredictions() returned an error: %s", err)
+	}
+
+	pruned, err := db.PrunePredictions(t.Context(), fetched.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("PrunePredictions() returned an error: %s", err)
+	}
+
+	assert.Equal(t, int64(1), pruned)
+
+	got, err := db.Predictions(t.Context(), testLocation, []string{"A01"}, time.Time{})
+	if err != nil {
+		t.Fatalf("Predictions() returned an error: %s", err)
+	}
+
+	assert.Equal(t, logged[:1], got, "the recent fetch is kept")
+}
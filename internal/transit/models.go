@@ -1,6 +1,11 @@
 package transit
 
-import "time"
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
 
 // LocationSlug is the unique identifier for a location.
 type LocationSlug string
@@ -32,6 +37,10 @@ const (
 	RefStop   RefKind = "stop"
 )
 
+// EffectAccessibility is the Effect of an alert about an elevator, escalator or other
+// step-free access being out of service. It's the label GTFS Realtime gives the effect.
+const EffectAccessibility = "Accessibility Issue"
+
 const (
 	TrainStation StopType = "train" // Type used to represent a train station.
 	BusStop      StopType = "bus"   // Type used to represent a bus stop.
@@ -71,12 +80,59 @@ type Departure struct {
 	Arrives   time.Time // Always an absolute instant. Render it in the agency's zone.
 }
 
+// Prediction is a departure as it was predicted at one point in time. A train is
+// predicted many times on its way in, and comparing them shows how reliable the
+// predictions are.
+type Prediction struct {
+	Source   string
+	StopID   string
+	StopName string
+	Line     string
+	Headsign string
+	Arrives  time.Time // When the train was predicted to arrive.
+	Fetched  time.Time // When the prediction was fetched.
+}
+
+// LinePositions is a snapshot of where the vehicles on a line are.
+type LinePositions struct {
+	Line      string
+	Color     string    // The line's background color.
+	TextColor string    // The line's foreground color.
+	Stops     []StopRef // The line's stops, in the order its vehicles run in one direction.
+	Vehicles  []VehiclePosition
+	AsOf      time.Time // When the positions were reported.
+}
+
+// VehiclePosition is where one vehicle is along its line. IDs are those of the
+// line's Stops.
+type VehiclePosition struct {
+	VehicleID  string
+	Headsign   string // Rider-facing destination displayed.
+	Cars       int    // Zero if the source didn't say.
+	StopID     string // The stop it's at, or the last one it passed.
+	NextStopID string // The stop it reaches next. Empty after the last stop of its run.
+	AtStop     bool   // It's at StopID rather than between StopID and NextStopID.
+}
+
+// Fare is what a ride between two stations costs. Amounts are in the agency's
+// currency, e.g. dollars.
+type Fare struct {
+	From           string // ID the Source uses for the station the ride starts at.
+	To             string // ID the Source uses for the station the ride ends at.
+	Peak           float64
+	OffPeak        float64
+	SeniorDisabled float64       // The reduced fare for seniors and riders with disabilities.
+	RailTime       time.Duration // How long the ride is scheduled to take.
+	Miles          float64       // Distance along the tracks.
+}
+
 // SourceStatus is the outcome of asking one source for data. A source that fans out a request per
 // stop/agency still reports one status and the request that failed lives in the error.
 type SourceStatus struct {
 	Source string    // Provider source that was asked.
 	AsOf   time.Time // When the source produced the data, not when we asked for it.
 	Err    error     // Non-nil means this particular Source is degraded. The others may still be good.
+	Cached bool      // Served from the local response cache rather than asked just now.
 }
 
 // DepartureSet is the result of asking every source that serves the requested stops. The status
@@ -93,24 +149,158 @@ func (s DepartureSet) AsOf() time.Time { return oldest(s.Sources) }
 // Degraded returns the sources that failed. It's empty when all sources succeed.
 func (s DepartureSet) Degraded() []SourceStatus { return degraded(s.Sources) }
 
+// CachedAge returns how old the oldest data served from the local response cache is
+// at now. It's zero when every source was asked just now.
+func (s DepartureSet) CachedAge(now time.Time) time.Duration { return cachedAge(s.Sources, now) }
+
 // AlertRef is the entity an Alert applies to.
 type AlertRef struct {
 	Kind      RefKind
 	ID        string // ID used by the Source to identify the entity.
+	Name      string // Rider-facing name. Empty when the Source's ID is all there is.
 	Color     string // The entity's background color. Empty for entities without branding (e.g. stops).
 	TextColor string // The entity's foreground color. Empty for entities without branding (e.g. stops).
 }
 
+// Label is what a rider should see for the entity, its name when the Source's ID has one.
+func (r AlertRef) Label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return r.ID
+}
+
+// ActivePeriod is a window of time an alert is in effect for.
+type ActivePeriod struct {
+	Starts time.Time // Zero means the period has already started.
+	Ends   time.Time // Zero means no announced end.
+}
+
+// Contains reports whether t falls inside the period.
+func (p ActivePeriod) Contains(t time.Time) bool {
+	return (p.Starts.IsZero() || !t.Before(p.Starts)) && (p.Ends.IsZero() || t.Before(p.Ends))
+}
+
+// AlertStatus is where an alert is in its active periods at a point in time.
+type AlertStatus string
+
+const (
+	AlertActive   AlertStatus = "active"   // In effect now.
+	AlertUpcoming AlertStatus = "upcoming" // Not in effect yet, but one of its periods is still to come.
+	AlertEnded    AlertStatus = "ended"    // Every period is over.
+)
+
+// Translation is a piece of rider-facing text in one language.
+type Translation struct {
+	Language string // BCP 47 tag, e.g. "en" or "zh-Hant".
+	Text     string
+}
+
+// Translate picks the text a rider who prefers languages, in order, should see. Each
+// preference matches exactly first, then by its base language ("es-MX" takes "es"),
+// before the next one is tried. With no match the first translation is used, so
+// a rider always sees something.
+func Translate(translations []Translation, languages ...string) string {
+	for _, lang := range languages {
+		if lang == "" {
+			continue
+		}
+
+		for _, t := range translations {
+			if strings.EqualFold(t.Language, lang) {
+				return t.Text
+			}
+		}
+
+		for _, t := range translations {
+			if strings.EqualFold(baseLanguage(t.Language), baseLanguage(lang)) {
+				return t.Text
+			}
+		}
+	}
+
+	if len(translations) == 0 {
+		return ""
+	}
+
+	return translations[0].Text
+}
+
+// baseLanguage strips the region or script from a tag, "zh-Hant" is "zh".
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return base
+}
+
 // Alert is a disruption to service (planned or not) and the entities it applies to.
 type Alert struct {
+	// ID used by the Source to identify the alert. It stays the same when the alert is
+	// updated. Empty if the Source doesn't publish one.
+	ID          string
 	Source      string // Provider source that produced the data.
 	AgencyID    string // The agency whose service is disrupted.
 	Affected    []AlertRef
-	Description string    // Rider-facing text describing the disruption.
-	Effect      string    // The agency's label for the effect this alert has on riders.
-	Starts      time.Time // Zero means the alert is already in effect.
-	Ends        time.Time // Zero means no announced end.
-	Updated     time.Time // When the Source last updated the alert. Zero if it didn't publish one.
+	Description string // Rider-facing text describing the disruption, in the preferred language.
+	// Description in every language the Source sent it in, including the preferred one.
+	// Empty if the Source doesn't tag its text with a language.
+	Translations []Translation
+	Effect       string // The agency's label for the effect this alert has on riders.
+	Cause        string // What's behind the disruption. Empty if the source didn't say.
+	// When the alert is in effect, in order. Empty means it's in effect until it's withdrawn.
+	// More than one means a recurring disruption, e.g. weekend track work.
+	Periods []ActivePeriod
+	Updated time.Time // When the Source last updated the alert. Zero if it didn't publish one.
+}
+
+// Status reports whether the alert is in effect at now, still to come, or over.
+func (a Alert) Status(now time.Time) AlertStatus {
+	if len(a.Periods) == 0 {
+		return AlertActive
+	}
+
+	status := AlertEnded
+	for _, p := range a.Periods {
+		if p.Contains(now) {
+			return AlertActive
+		}
+
+		if p.Starts.After(now) {
+			status = AlertUpcoming
+		}
+	}
+
+	return status
+}
+
+// Recurring reports whether the alert is in effect over several separate periods.
+func (a Alert) Recurring() bool {
+	return len(a.Periods) > 1
+}
+
+// Period returns the period that matters at now: the one in effect, otherwise the
+// next one to start, otherwise the last one. False when the alert has no periods.
+func (a Alert) Period(now time.Time) (ActivePeriod, bool) {
+	if len(a.Periods) == 0 {
+		return ActivePeriod{}, false
+	}
+
+	var next *ActivePeriod
+	for i, p := range a.Periods {
+		if p.Contains(now) {
+			return p, true
+		}
+
+		if p.Starts.After(now) && (next == nil || p.Starts.Before(next.Starts)) {
+			next = &a.Periods[i]
+		}
+	}
+
+	if next != nil {
+		return *next, true
+	}
+
+	return a.Periods[len(a.Periods)-1], true
 }
 
 // AlertSet is the result of asking every source for the disruptions it knows about.
@@ -126,6 +316,158 @@ func (s AlertSet) AsOf() time.Time { return oldest(s.Sources) }
 // Degraded returns the sources that failed. It's empty for the happy path.
 func (s AlertSet) Degraded() []SourceStatus { return degraded(s.Sources) }
 
+// CachedAge returns how old the oldest data served from the local response cache is
+// at now. It's zero when every source was asked just now.
+func (s AlertSet) CachedAge(now time.Time) time.Duration { return cachedAge(s.Sources, now) }
+
+// Filter returns the set with only the alerts f matches. Sources are kept as they are,
+// so the result still reports its age and failures.
+func (s AlertSet) Filter(f AlertFilter) AlertSet {
+	kept := make([]Alert, 0, len(s.Alerts))
+	for _, a := range s.Alerts {
+		if f.Match(a) {
+			kept = append(kept, a)
+		}
+	}
+
+	return AlertSet{Alerts: kept, Sources: s.Sources}
+}
+
+// Key identifies the alert across polls of its Source. Without an ID, the text is the
+// best there is, so an edited description reads as one alert clearing and another posting.
+func (a Alert) Key() string {
+	if a.ID != "" {
+		return a.Source + "/" + a.ID
+	}
+
+	return a.Source + "/" + a.AgencyID + "/" + a.Description
+}
+
+// AlertDiff is what changed between two polls of the alerts.
+type AlertDiff struct {
+	Posted   []Alert // In the new poll only.
+	Updated  []Alert // In both polls, with different content. These are the new versions.
+	Resolved []Alert // In the old poll only. These are the last versions seen.
+}
+
+// Empty reports whether nothing changed.
+func (d AlertDiff) Empty() bool {
+	return len(d.Posted) == 0 && len(d.Updated) == 0 && len(d.Resolved) == 0
+}
+
+// DiffAlerts compares two polls by [Alert.Key]. An alert only counts as updated when
+// what a rider sees changed, not just when its Source restamped it. Posted and
+// updated alerts keep the order of next, resolved ones the order of prev.
+func DiffAlerts(prev, next []Alert) AlertDiff {
+	before := make(map[string]Alert, len(prev))
+	for _, a := range prev {
+		before[a.Key()] = a
+	}
+
+	var diff AlertDiff
+	seen := make(map[string]struct{}, len(next))
+	for _, a := range next {
+		key := a.Key()
+		seen[key] = struct{}{}
+
+		old, ok := before[key]
+		switch {
+		case !ok:
+			diff.Posted = append(diff.Posted, a)
+		case !sameContent(old, a):
+			diff.Updated = append(diff.Updated, a)
+		}
+	}
+
+	for _, a := range prev {
+		if _, ok := seen[a.Key()]; !ok {
+			diff.Resolved = append(diff.Resolved, a)
+		}
+	}
+
+	return diff
+}
+
+func sameContent(a, b Alert) bool {
+	a.Updated, b.Updated = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
+// AlertRecord is an alert as the local history remembers it: its latest version and
+// when it was first and last seen in a feed.
+type AlertRecord struct {
+	Alert
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Observed is how long the alert stayed in the feed. It's measured by whoever was
+// polling, so it's a lower bound on how long the disruption lasted.
+func (r AlertRecord) Observed() time.Duration {
+	return r.LastSeen.Sub(r.FirstSeen)
+}
+
+// AlertFilter narrows alerts down to the ones a rider cares about. The zero value
+// matches everything. IDs are compared case insensitively.
+type AlertFilter struct {
+	// An alert affecting any of these lines or any of these stops is kept. Leaving
+	// both empty doesn't filter on what's affected.
+	Lines []string // Route IDs.
+	Stops []string // Stop IDs.
+
+	Agencies       []string  // Agency IDs. An alert from any of them is kept.
+	Effects        []string  // An alert with any of these effects is kept.
+	ExcludeEffects []string  // An alert with any of these effects is dropped.
+	ActiveAt       time.Time // Non-zero keeps only alerts in effect at this time.
+	CurrentAt      time.Time // Non-zero drops alerts that have ended by this time.
+}
+
+// Match reports whether f keeps a.
+func (f AlertFilter) Match(a Alert) bool {
+	if len(f.Agencies) > 0 && !containsFold(f.Agencies, a.AgencyID) {
+		return false
+	}
+
+	if len(f.Effects) > 0 && !containsFold(f.Effects, a.Effect) {
+		return false
+	}
+
+	if containsFold(f.ExcludeEffects, a.Effect) {
+		return false
+	}
+
+	if !f.ActiveAt.IsZero() && a.Status(f.ActiveAt) != AlertActive {
+		return false
+	}
+
+	if !f.CurrentAt.IsZero() && a.Status(f.CurrentAt) == AlertEnded {
+		return false
+	}
+
+	if len(f.Lines) == 0 && len(f.Stops) == 0 {
+		return true
+	}
+
+	for _, ref := range a.Affected {
+		switch ref.Kind {
+		case RefRoute:
+			if containsFold(f.Lines, ref.ID) {
+				return true
+			}
+		case RefStop:
+			if containsFold(f.Stops, ref.ID) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
 // Route is a line that vehicles run along. It carries the line's display identity, which a
 // departure resolves through the reference the source gives for it.
 type Route struct {
@@ -146,6 +488,24 @@ type Trip struct {
 	ShapeID  string // The physical path the vehicle follows. Trips that run the same way share one.
 }
 
+// Pattern is the stops that trips along a route call at, in order, and how far into
+// the trip each one is reached. Trips that call at the same stops share one, timed
+// like the first of them in the schedule.
+type Pattern struct {
+	PatternID string // The trip the pattern was timed from.
+	RouteID   string
+	Line      string // Rider-facing name for the route.
+	Headsign  string // User-facing destination the trips display.
+	Stops     []PatternStop
+}
+
+// PatternStop is one stop along a Pattern.
+type PatternStop struct {
+	StopID  string
+	Arrives time.Duration // How long after the trip leaves its first stop it arrives here.
+	Departs time.Duration // How long after the trip leaves its first stop it leaves here.
+}
+
 // StopRoute records that a route serves a stop.
 type StopRoute struct {
 	StopID  string
@@ -195,6 +555,7 @@ type Static struct {
 	Stops      []Stop
 	Routes     []Route
 	Trips      []Trip
+	Patterns   []Pattern
 	StopRoutes []StopRoute
 	Version    string // Identifies this edition of the data.
 }
@@ -214,6 +575,17 @@ func oldest(sources []SourceStatus) time.Time {
 	return t
 }
 
+func cachedAge(sources []SourceStatus, now time.Time) time.Duration {
+	var age time.Duration
+	for _, src := range sources {
+		if src.Cached && !src.AsOf.IsZero() {
+			age = max(age, now.Sub(src.AsOf))
+		}
+	}
+
+	return age
+}
+
 func degraded(sources []SourceStatus) []SourceStatus {
 	var bad []SourceStatus
 	for _, src := range sources {
@@ -2,6 +2,7 @@ package transit_test
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -85,6 +86,46 @@ func TestAsOf(t *testing.T) {
 	}
 }
 
+func TestCachedAge(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sources  []transit.SourceStatus
+		expected time.Duration
+	}{
+		"asked just now": {
+			sources:  []transit.SourceStatus{{Source: "wmata-rail", AsOf: nineOhFive}},
+			expected: 0,
+		},
+		"the oldest cached source": {
+			sources: []transit.SourceStatus{
+				{Source: "wmata-rail", AsOf: nineOhFive, Cached: true},
+				{Source: "wmata-bus", AsOf: nine, Cached: true},
+				{Source: "art", AsOf: nine},
+			},
+			expected: 10 * time.Minute,
+		},
+		"a source that returned nothing is excluded": {
+			sources:  []transit.SourceStatus{{Source: "wmata-rail", Err: errSourceDown, Cached: true}},
+			expected: 0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (transit.DepartureSet{Sources: tc.sources}).CachedAge(nineTen); got != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, got)
+			}
+
+			if got := (transit.AlertSet{Sources: tc.sources}).CachedAge(nineTen); got != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
 func TestDegraded(t *testing.T) {
 	t.Parallel()
 
@@ -156,3 +197,251 @@ func assertDegraded(t *testing.T, got []transit.SourceStatus, expected []string)
 		}
 	}
 }
+
+func TestAlertStatus(t *testing.T) {
+	t.Parallel()
+
+	eight := time.Date(2026, 8, 20, 8, 0, 0, 0, time.UTC)
+	weekend := []transit.ActivePeriod{
+		{Starts: eight, Ends: nine},
+		{Starts: nineOhFive, Ends: nineTen},
+	}
+
+	tests := map[string]struct {
+		periods []transit.ActivePeriod
+		now     time.Time
+		status  transit.AlertStatus
+		period  transit.ActivePeriod
+	}{
+		"no periods is in effect until withdrawn": {
+			now:    nine,
+			status: transit.AlertActive,
+		},
+		"inside an open ended period": {
+			periods: []transit.ActivePeriod{{Starts: eight}},
+			now:     nine,
+			status:  transit.AlertActive,
+			period:  transit.ActivePeriod{Starts: eight},
+		},
+		"between two periods waits for the next": {
+			periods: weekend,
+			now:     nine,
+			status:  transit.AlertUpcoming,
+			period:  weekend[1],
+		},
+		"inside the second period": {
+			periods: weekend,
+			now:     nineOhFive,
+			status:  transit.AlertActive,
+			period:  weekend[1],
+		},
+		"after every period": {
+			periods: weekend,
+			now:     nineTen,
+			status:  transit.AlertEnded,
+			period:  weekend[1],
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			alert := transit.Alert{Periods: tc.periods}
+
+			if got := alert.Status(tc.now); got != tc.status {
+				t.Errorf("expected %q but got %q", tc.status, got)
+			}
+
+			if got, _ := alert.Period(tc.now); got != tc.period {
+				t.Errorf("expected period %v but got %v", tc.period, got)
+			}
+		})
+	}
+}
+
+func TestAlertFilter(t *testing.T) {
+	t.Parallel()
+
+	redLine := transit.Alert{
+		AgencyID: "MET",
+		Affected: []transit.AlertRef{{Kind: transit.RefRoute, ID: "RD"}},
+	}
+	metroCenter := transit.Alert{
+		AgencyID: "MET",
+		Affected: []transit.AlertRef{{Kind: transit.RefStop, ID: "A01"}},
+		Effect:   transit.EffectAccessibility,
+		Periods:  []transit.ActivePeriod{{Starts: nineTen}},
+	}
+	bart := transit.Alert{AgencyID: "BA", Periods: []transit.ActivePeriod{{Ends: nineOhFive}}}
+
+	tests := map[string]struct {
+		filter   transit.AlertFilter
+		expected []transit.Alert
+	}{
+		"the zero filter keeps everything": {
+			expected: []transit.Alert{redLine, metroCenter, bart},
+		},
+		"lines are matched case insensitively": {
+			filter:   transit.AlertFilter{Lines: []string{"rd"}},
+			expected: []transit.Alert{redLine},
+		},
+		"a line or a stop": {
+			filter:   transit.AlertFilter{Lines: []string{"RD"}, Stops: []string{"A01"}},
+			expected: []transit.Alert{redLine, metroCenter},
+		},
+		"agency": {
+			filter:   transit.AlertFilter{Agencies: []string{"BA"}},
+			expected: []transit.Alert{bart},
+		},
+		"effect": {
+			filter:   transit.AlertFilter{Effects: []string{"accessibility issue"}},
+			expected: []transit.Alert{metroCenter},
+		},
+		"excluded effect": {
+			filter:   transit.AlertFilter{ExcludeEffects: []string{"accessibility issue"}},
+			expected: []transit.Alert{redLine, bart},
+		},
+		"active drops what hasn't started": {
+			filter:   transit.AlertFilter{Agencies: []string{"MET"}, ActiveAt: nine},
+			expected: []transit.Alert{redLine},
+		},
+		"current drops what has ended": {
+			filter:   transit.AlertFilter{CurrentAt: nineTen},
+			expected: []transit.Alert{redLine, metroCenter},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			set := transit.AlertSet{Alerts: []transit.Alert{redLine, metroCenter, bart}}
+			got := set.Filter(tc.filter).Alerts
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v but got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+
+	translations := []transit.Translation{
+		{Language: "vi", Text: "Thay đổi sân ga"},
+		{Language: "en", Text: "Platform change"},
+		{Language: "es", Text: "Cambio de andén"},
+		{Language: "zh-Hant", Text: "月台變更"},
+	}
+
+	tests := map[string]struct {
+		translations []transit.Translation
+		languages    []string
+		want         string
+	}{
+		"an exact match": {
+			translations: translations,
+			languages:    []string{"es", "en"},
+			want:         "Cambio de andén",
+		},
+		"a region falls back to its base language": {
+			translations: translations,
+			languages:    []string{"es-MX", "en"},
+			want:         "Cambio de andén",
+		},
+		"a base language takes a regional translation": {
+			translations: translations,
+			languages:    []string{"zh", "en"},
+			want:         "月台變更",
+		},
+		"tags are case insensitive": {
+			translations: translations,
+			languages:    []string{"ZH-hant"},
+			want:         "月台變更",
+		},
+		"a missing language falls through the chain": {
+			translations: translations,
+			languages:    []string{"fr", "", "en"},
+			want:         "Platform change",
+		},
+		"nothing matches so the first is used": {
+			translations: translations,
+			languages:    []string{"fr"},
+			want:         "Thay đổi sân ga",
+		},
+		"no translations": {
+			languages: []string{"en"},
+			want:      "",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := transit.Translate(tc.translations, tc.languages...); got != tc.want {
+				t.Errorf("expected %q but got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDiffAlerts(t *testing.T) {
+	t.Parallel()
+
+	delay := transit.Alert{ID: "1", Source: "wmata", Description: "Red Line delays", Updated: time.Unix(100, 0)}
+	restamped := delay
+	restamped.Updated = time.Unix(200, 0)
+	worse := delay
+	worse.Description = "Red Line delays of 20 minutes"
+	closure := transit.Alert{ID: "2", Source: "wmata", Description: "Station closed"}
+	sameIDOtherSource := transit.Alert{ID: "1", Source: "bayarea511", Description: "Elevator out"}
+	unkeyed := transit.Alert{Source: "bayarea511", AgencyID: "CT", Description: "Single tracking"}
+
+	tests := map[string]struct {
+		prev, next []transit.Alert
+		want       transit.AlertDiff
+	}{
+		"nothing changed": {
+			prev: []transit.Alert{delay, unkeyed},
+			next: []transit.Alert{delay, unkeyed},
+			want: transit.AlertDiff{},
+		},
+		"a new timestamp alone is not an update": {
+			prev: []transit.Alert{delay},
+			next: []transit.Alert{restamped},
+			want: transit.AlertDiff{},
+		},
+		"posted, updated and resolved": {
+			prev: []transit.Alert{delay, closure},
+			next: []transit.Alert{worse, sameIDOtherSource},
+			want: transit.AlertDiff{
+				Posted:   []transit.Alert{sameIDOtherSource},
+				Updated:  []transit.Alert{worse},
+				Resolved: []transit.Alert{closure},
+			},
+		},
+		"the first poll posts everything": {
+			next: []transit.Alert{delay, unkeyed},
+			want: transit.AlertDiff{Posted: []transit.Alert{delay, unkeyed}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := transit.DiffAlerts(tc.prev, tc.next)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %+v but got %+v", tc.want, got)
+			}
+
+			if got.Empty() != (len(tc.want.Posted)+len(tc.want.Updated)+len(tc.want.Resolved) == 0) {
+				t.Errorf("Empty() disagrees with %+v", got)
+			}
+		})
+	}
+}
@@ -8,6 +8,9 @@ import (
 // ErrNoDepartures is returned when a stop has no upcoming departures.
 var ErrNoDepartures = errors.New("no departures")
 
+// ErrUnknownLine is returned when a source doesn't run the line it was asked about.
+var ErrUnknownLine = errors.New("unknown line")
+
 // Provider is the set of calls a command makes to a transit source.
 type Provider interface {
 	// Departures returns what's arriving at the given refs.
@@ -26,3 +29,25 @@ type Seeder interface {
 	// Seed returns the source's whole stop list so that init scan write it to the store.
 	Seed(context.Context) (*Static, error)
 }
+
+// VehicleLocator is implemented by sources that can say where the vehicles on a
+// line are right now.
+type VehicleLocator interface {
+	// Positions returns a line's stops in order and where its vehicles are along them.
+	// A line the source doesn't run is ErrUnknownLine.
+	Positions(ctx context.Context, line string) (LinePositions, error)
+}
+
+// Pinger is implemented by sources that can check their credentials cheaply.
+type Pinger interface {
+	// Ping makes the cheapest authenticated request the source has. An error means
+	// the source couldn't be reached or didn't accept the key.
+	Ping(context.Context) error
+}
+
+// FareQuoter is implemented by sources that can quote the fare between two stations.
+type FareQuoter interface {
+	// Fare returns what riding from one seeded station to another costs and how long
+	// the ride is scheduled to take.
+	Fare(ctx context.Context, from, to Stop) (Fare, error)
+}
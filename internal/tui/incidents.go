@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -11,27 +12,63 @@ import (
 	"golang.org/x/term"
 )
 
-const (
-	dateFormat = "2 Jan 06 3:04pm"
-)
+// DateFormat is how dates are written wherever alerts are shown.
+const DateFormat = "2 Jan 06 3:04pm"
 
-func PrintIncidents(alertSet transit.AlertSet, showAgency bool) {
+// PrintIncidents renders every alert in the set. Each says whether it's in effect
+// at now, still to come, or recurring.
+func PrintIncidents(w io.Writer, alertSet transit.AlertSet, showAgency bool, now time.Time) {
 	if len(alertSet.Alerts) == 0 {
-		fmt.Println("No incidents reported")
+		_, _ = fmt.Fprintln(w, "No incidents reported")
 		return
 	}
 
+	PrintAlerts(w, alertSet.Alerts, showAgency, now)
+
+	// TODO: Print once
+	if updated := formatUpdatedAt(alertSet.AsOf()); updated != "" {
+		if age := alertSet.CachedAge(now); age > 0 {
+			updated += " (" + formatCached(age) + ")"
+		}
+
+		_, _ = fmt.Fprintln(w, lipgloss.NewStyle().Margin(1, 1).Faint(true).Render(updated))
+	}
+}
+
+// PrintAlerts renders alerts one after another, without anything around them. No
+// alerts prints nothing, which suits showing them under another screen.
+func PrintAlerts(w io.Writer, alerts []transit.Alert, showAgency bool, now time.Time) {
 	maxWidth := 80
 	termWidth, _, _ := term.GetSize(int(os.Stdin.Fd()))
 	width := min(max(termWidth-5, 0), maxWidth) // -5 for some padding
 
-	for _, a := range alertSet.Alerts {
-		render(a, width, showAgency)
+	for _, a := range alerts {
+		render(w, a, width, showAgency, now)
 	}
+}
 
-	// TODO: Print once
-	if updated := formatUpdatedAt(alertSet.AsOf()); updated != "" {
-		fmt.Println(lipgloss.NewStyle().Margin(1, 1).Faint(true).Render(updated))
+// PrintAlertDiff renders what changed since the last poll, under a line saying when
+// it was noticed. Each alert is labelled with how it changed.
+func PrintAlertDiff(w io.Writer, diff transit.AlertDiff, showAgency bool, now time.Time) {
+	_, _ = fmt.Fprintln(w, lipgloss.NewStyle().Margin(1, 1, 0).Faint(true).Render(now.Format(DateFormat)))
+
+	sections := []struct {
+		label  string
+		style  lipgloss.Style
+		alerts []transit.Alert
+	}{
+		{"New", lipgloss.NewStyle().Foreground(Green), diff.Posted},
+		{"Updated", lipgloss.NewStyle().Foreground(Orange), diff.Updated},
+		{"Resolved", lipgloss.NewStyle().Faint(true), diff.Resolved},
+	}
+
+	for _, s := range sections {
+		if len(s.alerts) == 0 {
+			continue
+		}
+
+		_, _ = fmt.Fprintln(w, s.style.Bold(true).Margin(0, 1).Render(fmt.Sprintf("%s (%d)", s.label, len(s.alerts))))
+		PrintAlerts(w, s.alerts, showAgency, now)
 	}
 }
 
@@ -40,7 +77,12 @@ func formatUpdatedAt(date time.Time) string {
 		return ""
 	}
 
-	return "as of " + date.Format(dateFormat)
+	return "as of " + date.Format(DateFormat)
+}
+
+// formatCached says data came from the local response cache and how old it was.
+func formatCached(age time.Duration) string {
+	return fmt.Sprintf("cached, %ds old", int(age.Round(time.Second)/time.Second))
 }
 
 func formatStartEnd(start, end time.Time) string {
@@ -49,49 +91,58 @@ func formatStartEnd(start, end time.Time) string {
 	}
 
 	if start.IsZero() {
-		return fmt.Sprintf("Ends: %s", end.Format(dateFormat))
+		return fmt.Sprintf("Ends: %s", end.Format(DateFormat))
 	}
 
 	if end.IsZero() {
-		return fmt.Sprintf("Starts: %s", start.Format(dateFormat))
+		return fmt.Sprintf("Starts: %s", start.Format(DateFormat))
 	}
 
-	return fmt.Sprintf("%s - %s", start.Format(dateFormat), end.Format(dateFormat))
+	return fmt.Sprintf("%s - %s", start.Format(DateFormat), end.Format(DateFormat))
 }
 
-func genFooter(alert *transit.Alert, showAgency bool) string {
-	duration := formatStartEnd(alert.Starts, alert.Ends)
-
-	var agencyID string
-	if showAgency {
-		agencyID = alert.AgencyID
+// formatStatus describes where an alert is in its periods at now, along with the
+// period that matters: the current one, otherwise the next one.
+func formatStatus(alert *transit.Alert, now time.Time) string {
+	var status string
+	switch alert.Status(now) {
+	case transit.AlertActive:
+		status = "Active now"
+	case transit.AlertUpcoming:
+		status = "Upcoming"
+	default:
+		status = "Ended"
 	}
 
-	if agencyID == "" && duration == "" {
-		return ""
+	if alert.Recurring() {
+		status += fmt.Sprintf(" (recurring, %d periods)", len(alert.Periods))
 	}
 
-	var activePeriod string
-	if duration != "" {
-		activePeriod = lipgloss.NewStyle().Margin(1, 1, 0).Render(duration)
+	period, ok := alert.Period(now)
+	if !ok {
+		return status
 	}
 
-	var agencyHorMargin int
-	if duration == "" {
-		agencyHorMargin = 1
-	} else {
-		agencyHorMargin = 2
+	if duration := formatStartEnd(period.Starts, period.Ends); duration != "" {
+		status += " · " + duration
 	}
 
-	var agency string
-	if agencyID != "" {
-		agency = lipgloss.NewStyle().Margin(1, agencyHorMargin, 0).Foreground(lipgloss.Color("30")).Render(agencyID)
+	return status
+}
+
+func genFooter(alert *transit.Alert, showAgency bool, now time.Time) string {
+	status := lipgloss.NewStyle().Margin(1, 1, 0).Render(formatStatus(alert, now))
+
+	if !showAgency || alert.AgencyID == "" {
+		return status
 	}
 
-	return lipgloss.JoinHorizontal(lipgloss.Left, activePeriod, agency)
+	agency := lipgloss.NewStyle().Margin(1, 2, 0).Foreground(lipgloss.Color("30")).Render(alert.AgencyID)
+
+	return lipgloss.JoinHorizontal(lipgloss.Left, status, agency)
 }
 
-func render(alert transit.Alert, width int, showAgency bool) {
+func render(w io.Writer, alert transit.Alert, width int, showAgency bool, now time.Time) {
 	list := lipgloss.NewStyle().
 		Border(lipgloss.NormalBorder(), true, true, true, true).
 		Padding(1, 1).
@@ -99,22 +150,20 @@ func render(alert transit.Alert, width int, showAgency bool) {
 
 	effect := lipgloss.NewStyle().Padding(0, 1).Bold(true).Render(alert.Effect)
 
+	var cause string
+	if alert.Cause != "" {
+		cause = lipgloss.NewStyle().Padding(0, 1, 0, 0).Faint(true).Render(alert.Cause)
+	}
+
 	affected := genAffected(alert.Affected)
 
-	header := lipgloss.JoinHorizontal(lipgloss.Left, effect, affected)
+	header := lipgloss.JoinHorizontal(lipgloss.Left, effect, cause, affected)
 
 	description := lipgloss.NewStyle().Width(width).Margin(1, 1, 0).Render(alert.Description)
 
-	footer := genFooter(&alert, showAgency)
+	footer := genFooter(&alert, showAgency, now)
 
-	// TODO Clean up UI
-	if footer == "" {
-		out := list.Render(lipgloss.JoinVertical(lipgloss.Left, header, description))
-		fmt.Println(out)
-	} else {
-		out := list.Render(lipgloss.JoinVertical(lipgloss.Left, header, description, footer))
-		fmt.Println(out)
-	}
+	_, _ = fmt.Fprintln(w, list.Render(lipgloss.JoinVertical(lipgloss.Left, header, description, footer)))
 }
 
 func genAffected(affected []transit.AlertRef) string {
@@ -129,7 +178,7 @@ func genAffected(affected []transit.AlertRef) string {
 			style = style.Border(lipgloss.NormalBorder(), true, true).BorderForeground(Subtle).Foreground(Subtle)
 		}
 
-		builder.WriteString(style.Render(a.ID))
+		builder.WriteString(style.Render(a.Label()))
 	}
 
 	return builder.String()
@@ -3,6 +3,8 @@ package tui
 import (
 	"testing"
 	"time"
+
+	"github.com/ismailshak/transit/internal/transit"
 )
 
 func TestFormatUpdatedAt(t *testing.T) {
@@ -38,3 +40,54 @@ func TestFormatUpdatedAt(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatCached(t *testing.T) {
+	t.Parallel()
+
+	if got, want := formatCached(8400*time.Millisecond), "cached, 8s old"; got != want {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+}
+
+func TestFormatStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.August, 22, 12, 0, 0, 0, time.UTC)
+	saturday := transit.ActivePeriod{
+		Starts: time.Date(2026, time.August, 22, 6, 0, 0, 0, time.UTC),
+		Ends:   time.Date(2026, time.August, 22, 22, 0, 0, 0, time.UTC),
+	}
+	nextSaturday := transit.ActivePeriod{
+		Starts: time.Date(2026, time.August, 29, 6, 0, 0, 0, time.UTC),
+		Ends:   time.Date(2026, time.August, 29, 22, 0, 0, 0, time.UTC),
+	}
+
+	tests := map[string]struct {
+		periods []transit.ActivePeriod
+		want    string
+	}{
+		"in effect until withdrawn": {
+			want: "Active now",
+		},
+		"upcoming": {
+			periods: []transit.ActivePeriod{nextSaturday},
+			want:    "Upcoming · 29 Aug 26 6:00am - 29 Aug 26 10:00pm",
+		},
+		"recurring, in its first period": {
+			periods: []transit.ActivePeriod{saturday, nextSaturday},
+			want:    "Active now (recurring, 2 periods) · 22 Aug 26 6:00am - 22 Aug 26 10:00pm",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatStatus(&transit.Alert{Periods: tt.periods}, now)
+
+			if got != tt.want {
+				t.Errorf("expected %q but got %q", tt.want, got)
+			}
+		})
+	}
+}
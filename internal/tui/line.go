@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+// stripRow is one row of a line's strip map, a stop or the gap between two.
+type stripRow struct {
+	stop    string // Empty for a gap.
+	markers []string
+}
+
+// PrintLineMap prints a line as a vertical strip of its stops, in the order of
+// positions.Stops, with each vehicle beside the stop it's at or in the gap it's
+// travelling through. Arrows point the way it's headed.
+func PrintLineMap(w io.Writer, positions transit.LinePositions) {
+	badge := lipgloss.NewStyle().
+		Bold(true).
+		Background(lipgloss.Color(positions.Color)).
+		Foreground(lipgloss.Color(positions.TextColor)).
+		Padding(0, 1).
+		Render(positions.Line)
+
+	summary := fmt.Sprintf("%d trains", len(positions.Vehicles))
+	if len(positions.Vehicles) == 1 {
+		summary = "1 train"
+	}
+
+	if !positions.AsOf.IsZero() {
+		summary += ", " + formatUpdatedAt(positions.AsOf)
+	}
+
+	_, _ = fmt.Fprintln(w, lipgloss.NewStyle().PaddingTop(1).Render(badge+" "+lipgloss.NewStyle().Faint(true).Render(summary)))
+
+	track := lipgloss.NewStyle().Foreground(lipgloss.Color(positions.Color))
+	marker := lipgloss.NewStyle().Foreground(Orange)
+
+	for _, row := range stripRows(positions) {
+		markers := marker.Render(strings.Join(row.markers, "  "))
+		if row.stop == "" {
+			_, _ = fmt.Fprintf(w, "  %s   %s\n", track.Render("│"), markers)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(w, "  %s %s  %s\n", track.Render("●"), lipgloss.NewStyle().Width(24).Render(row.stop), markers)
+	}
+
+	_, _ = fmt.Fprintln(w)
+}
+
+// stripRows lays out the strip map: stop i is row 2i and the gap after it row 2i+1.
+func stripRows(positions transit.LinePositions) []stripRow {
+	if len(positions.Stops) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(positions.Stops))
+	rows := make([]stripRow, 2*len(positions.Stops)-1)
+	for i, s := range positions.Stops {
+		index[s.StopID] = i
+		rows[2*i].stop = s.Name
+	}
+
+	for _, v := range positions.Vehicles {
+		at, ok := index[v.StopID]
+		if !ok {
+			continue
+		}
+
+		next, hasNext := index[v.NextStopID]
+
+		arrow := "■" // At the end of its run.
+		if hasNext && next > at {
+			arrow = "▼"
+		} else if hasNext && next < at {
+			arrow = "▲"
+		}
+
+		label := arrow + " " + v.Headsign
+		if v.Cars > 0 {
+			label += fmt.Sprintf(" (%d cars)", v.Cars)
+		}
+
+		row := 2 * at
+		if !v.AtStop && hasNext {
+			row = 2*min(at, next) + 1
+		}
+
+		rows[row].markers = append(rows[row].markers, label)
+	}
+
+	return rows
+}
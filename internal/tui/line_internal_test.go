@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ismailshak/transit/internal/transit"
+)
+
+func TestStripRows(t *testing.T) {
+	t.Parallel()
+
+	positions := transit.LinePositions{
+		Line: "RD",
+		Stops: []transit.StopRef{
+			{StopID: "A15", Name: "Shady Grove"},
+			{StopID: "A14", Name: "Rockville"},
+			{StopID: "A13", Name: "Twinbrook"},
+		},
+		Vehicles: []transit.VehiclePosition{
+			{Headsign: "Glenmont", Cars: 8, StopID: "A15", NextStopID: "A14"},
+			{Headsign: "Glenmont", StopID: "A13", NextStopID: "A12", AtStop: true},
+			{Headsign: "Shady Grove", Cars: 6, StopID: "A13", NextStopID: "A14"},
+			{Headsign: "Shady Grove", Cars: 6, StopID: "A15", AtStop: true},
+			{Headsign: "Nowhere", StopID: "Z99"},
+		},
+	}
+
+	want := []stripRow{
+		{stop: "Shady Grove", markers: []string{"■ Shady Grove (6 cars)"}},
+		{markers: []string{"▼ Glenmont (8 cars)"}},
+		{stop: "Rockville"},
+		{markers: []string{"▲ Shady Grove (6 cars)"}},
+		{stop: "Twinbrook", markers: []string{"■ Glenmont"}},
+	}
+
+	if got := stripRows(positions); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v but got %+v", want, got)
+	}
+}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
@@ -12,26 +13,40 @@ import (
 
 // PrintArrivalScreen creates and prints a screen that resembles a station's. Will display
 // an arriving train's line, destination and arriving trains (in "minutes-away").
-func PrintArrivalScreen(destinationLookup *map[string][]transit.Departure, sortedDestinations []string, now time.Time) {
+// A station with elevators or escalators out is marked in the header, and departures
+// served from the local cache say how old they are, cachedAge being zero otherwise.
+func PrintArrivalScreen(w io.Writer, destinationLookup *map[string][]transit.Departure, sortedDestinations []string, outages int, cachedAge time.Duration, now time.Time) {
 	list := getScreen()
 
 	// since this is the same for all items, fishing it out from the first one
 	header := (*destinationLookup)[sortedDestinations[0]][0].StopName
 
 	items := []string{}
-	items = append(items, genHeader(header))
+	items = append(items, genHeader(header, outages))
 
 	for _, d := range sortedDestinations {
 		items = append(items, genRow((*destinationLookup)[d], now))
 	}
 
+	if cachedAge > 0 {
+		items = append(items, lipgloss.NewStyle().Faint(true).Render(formatCached(cachedAge)))
+	}
+
 	out := list.Render(
 		lipgloss.JoinVertical(lipgloss.Left,
 			items...,
 		),
 	)
 
-	fmt.Println(out)
+	_, _ = fmt.Fprintln(w, out)
+}
+
+// PrintFailedScreen prints the screen a station would have had, with why its
+// departures couldn't be fetched in place of them.
+func PrintFailedScreen(w io.Writer, header string, err error) {
+	row := lipgloss.NewStyle().Foreground(Red).Render(ErrorIcon + " " + err.Error())
+
+	_, _ = fmt.Fprintln(w, getScreen().Render(lipgloss.JoinVertical(lipgloss.Left, genHeader(header, 0), row)))
 }
 
 // Create and return a terminal layout that will contain the screen-like display.
@@ -42,7 +57,11 @@ func getScreen() lipgloss.Style {
 }
 
 // Generate the header that will be printed at the top of the screen.
-func genHeader(header string) string {
+func genHeader(header string, outages int) string {
+	if outages > 0 {
+		header += "  " + genOutageMarker(outages)
+	}
+
 	return lipgloss.NewStyle().
 		Bold(true).
 		BorderStyle(lipgloss.NormalBorder()).
@@ -52,6 +71,18 @@ func genHeader(header string) string {
 		Render(header)
 }
 
+// Generate the marker for a station with elevators or escalators out of service.
+func genOutageMarker(outages int) string {
+	label := "outage"
+	if outages > 1 {
+		label = "outages"
+	}
+
+	return lipgloss.NewStyle().
+		Foreground(Orange).
+		Render(fmt.Sprintf("♿ %d elevator/escalator %s", outages, label))
+}
+
 // Generates a row printed on the screen.
 func genRow(destination []transit.Departure, now time.Time) string {
 	formattedLine := genLine(destination[0])
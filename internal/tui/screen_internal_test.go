@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,3 +59,39 @@ func TestMinutesAway(t *testing.T) {
 		})
 	}
 }
+
+func TestGenHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		outages int
+		want    string
+	}{
+		"no outages":  {outages: 0, want: ""},
+		"one outage":  {outages: 1, want: "1 elevator/escalator outage"},
+		"two outages": {outages: 2, want: "2 elevator/escalator outages"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			header := genHeader("Dupont Circle", tc.outages)
+			if !strings.Contains(header, "Dupont Circle") {
+				t.Errorf("expected the station name in %q", header)
+			}
+
+			if tc.want == "" {
+				if strings.Contains(header, "elevator") {
+					t.Errorf("expected no marker in %q", header)
+				}
+
+				return
+			}
+
+			if !strings.Contains(header, tc.want) {
+				t.Errorf("expected %q in %q", tc.want, header)
+			}
+		})
+	}
+}